@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -56,9 +58,20 @@ type Server struct {
 	router        *chi.Mux
 }
 
-func NewServer(databaseURL string) (*Server, error) {
+// NewServer connects to the database and loads every tenant's engine
+// Engines are kept in sync with changes made through other replicas until
+// ctx is done.
+func NewServer(ctx context.Context, databaseURL string) (*Server, error) {
+	// Changes made through this replica's connections are applied as they are
+	// made, so the change listener can skip their notifications
+	replicaID := uuid.NewString()
+	connStr, err := multitenantengine.ReplicaConnString(databaseURL, replicaID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Connect to database
-	db, err := sql.Open("postgres", databaseURL)
+	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -86,13 +99,26 @@ func NewServer(databaseURL string) (*Server, error) {
 	// Start background goroutine to monitor connection pool health
 	go monitorConnectionPool(db)
 
-	return NewServerWithDB(db)
+	// Keep tenant engines in sync with rules, schemas and settings changed
+	// through other replicas. Listening starts before tenants load so no
+	// change made while they load is missed.
+	engineManager := multitenantengine.NewMultiTenantEngineManager(db)
+	err = engineManager.ListenForChanges(ctx, databaseURL, replicaID, func(err error) {
+		logger.Warn("Tenant change listener error", "error", err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newServer(db, engineManager)
 }
 
 func NewServerWithDB(db *sql.DB) (*Server, error) {
-	// Create engine manager
-	engineManager := multitenantengine.NewMultiTenantEngineManager(db)
+	return newServer(db, multitenantengine.NewMultiTenantEngineManager(db))
+}
 
+// newServer loads every tenant into engineManager and sets up the routes
+func newServer(db *sql.DB, engineManager *multitenantengine.MultiTenantEngineManager) (*Server, error) {
 	// Load all tenants
 	logger.Debug("Loading tenants from database")
 	if err := engineManager.LoadAllTenants(); err != nil {
@@ -178,6 +204,8 @@ func (s *Server) setupRoutes() {
 
 	// Evaluation
 	r.Post("/api/v1/evaluate", s.handleEvaluate)
+	r.Post("/api/v1/evaluate/batch", s.handleEvaluateBatch)
+	r.Post("/api/v1/evaluate/partial", s.handleEvaluatePartial)
 
 	// Tenant management
 	r.Route("/api/v1/tenants", func(r chi.Router) {
@@ -185,17 +213,54 @@ func (s *Server) setupRoutes() {
 		r.Post("/", s.handleCreateTenant)
 
 		r.Route("/{tenantId}", func(r chi.Router) {
+			// Tenant settings
+			r.Get("/settings", s.handleGetTenantSettings)
+			r.Put("/settings", s.handleUpdateTenantSettings)
+			r.Get("/engine-options", s.handleGetEngineOptions)
+			r.Put("/engine-options", s.handleUpdateEngineOptions)
+
 			// Schema management
 			r.Post("/schema", s.handleCreateSchema)
 			r.Put("/schema", s.handleUpdateSchema)
+			r.Post("/schema/dry-run", s.handleSchemaDryRun)
+			r.Get("/schema/changelog", s.handleSchemaChangelog)
 			r.Get("/schema", s.handleGetSchema)
+			r.Get("/schema/fields", s.handleSchemaFieldUsage)
 
 			// Rule management
 			r.Post("/rules", s.handleCreateRule)
 			r.Get("/rules", s.handleListRules)
+			r.Get("/rules/stats", s.handleRuleStats)
 			r.Get("/rules/{ruleId}", s.handleGetRule)
 			r.Put("/rules/{ruleId}", s.handleUpdateRule)
 			r.Delete("/rules/{ruleId}", s.handleDeleteRule)
+
+			// Rule version history
+			r.Get("/rules/{ruleId}/versions", s.handleListRuleVersions)
+			r.Get("/rules/{ruleId}/versions/diff", s.handleDiffRuleVersions)
+			r.Get("/rules/{ruleId}/versions/{version}", s.handleGetRuleVersion)
+			r.Post("/rules/{ruleId}/versions/{version}/rollback", s.handleRollbackRule)
+
+			// Rule test cases
+			r.Get("/rules/{ruleId}/tests", s.handleListRuleTests)
+			r.Post("/rules/{ruleId}/tests", s.handleCreateRuleTest)
+			r.Put("/rules/{ruleId}/tests/{testId}", s.handleUpdateRuleTest)
+			r.Delete("/rules/{ruleId}/tests/{testId}", s.handleDeleteRuleTest)
+			r.Post("/tests/run", s.handleRunTests)
+
+			// Derived fields
+			r.Post("/derived", s.handleCreateDerivedField)
+			r.Get("/derived", s.handleListDerivedFields)
+			r.Get("/derived/{name}", s.handleGetDerivedField)
+			r.Put("/derived/{name}", s.handleUpdateDerivedField)
+			r.Delete("/derived/{name}", s.handleDeleteDerivedField)
+
+			// Reference lists
+			r.Post("/lists", s.handleCreateReferenceList)
+			r.Get("/lists", s.handleListReferenceLists)
+			r.Get("/lists/{name}", s.handleGetReferenceList)
+			r.Put("/lists/{name}", s.handleUpdateReferenceList)
+			r.Delete("/lists/{name}", s.handleDeleteReferenceList)
 		})
 	})
 
@@ -281,9 +346,14 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 // @Router /api/v1/evaluate [post]
 func (s *Server) handleEvaluate(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		TenantID string         `json:"tenantId"`
-		Facts    map[string]any `json:"facts"`
-		RuleIDs  []string       `json:"rules,omitempty"` // optional
+		TenantID   string               `json:"tenantId"`
+		Facts      map[string]any       `json:"facts"`
+		RuleIDs    []string             `json:"rules,omitempty"`      // optional
+		Mode       rules.EvaluationMode `json:"mode,omitempty"`       // optional, defaults to the tenant's evaluationMode, else "all"
+		MaxMatches int                  `json:"maxMatches,omitempty"` // required for "stop-after-N-matches"
+		Tags       []string             `json:"tags,omitempty"`       // optional, rules carrying any of these tags
+		RuleSet    string               `json:"ruleSet,omitempty"`    // optional, rules in this rule set
+		Explain    bool                 `json:"explain,omitempty"`    // optional, break results down by sub-expression
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -301,6 +371,18 @@ func (s *Server) handleEvaluate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	opts := rules.EvaluateOptions{
+		Mode:       req.Mode,
+		MaxMatches: req.MaxMatches,
+		Tags:       req.Tags,
+		RuleSet:    req.RuleSet,
+		Explain:    req.Explain,
+	}
+	if err := opts.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid evaluation mode", err)
+		return
+	}
+
 	// Get tenant's engine
 	engine, err := s.engineManager.GetEngine(req.TenantID)
 	if err != nil {
@@ -311,26 +393,27 @@ func (s *Server) handleEvaluate(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
 	// Evaluate rules
+	// The request context stops evaluation if the client disconnects or the
+	// server timeout fires; the tenant's time budgets apply on top of it
 	var results []*rules.EvaluationResult
 	if len(req.RuleIDs) > 0 {
-		// Evaluate specific rules
-		results = make([]*rules.EvaluationResult, 0, len(req.RuleIDs))
-		for _, ruleID := range req.RuleIDs {
-			result, err := engine.Evaluate(ruleID, req.Facts)
-			if err != nil {
-				// Continue on error (might be rule not found)
-				logger.Warn("Rule evaluation error", "ruleID", ruleID, "error", err)
-				continue
-			}
-			results = append(results, result)
-		}
+		// Evaluate specific rules in the order given, skipping unknown, inactive and out-of-window rules
+		results, err = engine.EvaluateRulesContext(r.Context(), req.RuleIDs, req.Facts, opts)
 	} else {
-		// Evaluate all active rules
-		results, err = engine.EvaluateAll(req.Facts)
-		if err != nil {
-			respondError(w, http.StatusInternalServerError, "evaluation failed", err)
-			return
-		}
+		// Evaluate active rules in priority order
+		results, err = engine.EvaluateAllContext(r.Context(), req.Facts, opts)
+	}
+	if errors.Is(err, rules.ErrInvalidFacts) {
+		respondError(w, http.StatusBadRequest, "invalid facts", err)
+		return
+	}
+	if errors.Is(err, rules.ErrExplainUnavailable) {
+		respondError(w, http.StatusBadRequest, "explanations are unavailable", err)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "evaluation failed", err)
+		return
 	}
 
 	evaluationTime := time.Since(startTime)
@@ -344,6 +427,203 @@ func (s *Server) handleEvaluate(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, response)
 }
 
+// handleEvaluateBatch godoc
+// @Summary Evaluate rules against many fact sets
+// @Description Evaluate a tenant's active rules against each fact set in a batch. Results are returned per item, in the order given; a failure in one item does not affect the others.
+// @Tags evaluation
+// @Accept json
+// @Produce json
+// @Param request body BatchEvaluateRequest true "Batch evaluation request with tenant ID and fact sets"
+// @Success 200 {object} BatchEvaluateResponse
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "Tenant not found"
+// @Failure 413 {object} ErrorResponse "Batch exceeds the tenant's batch size limit"
+// @Failure 500 {object} ErrorResponse "Evaluation error"
+// @Router /api/v1/evaluate/batch [post]
+func (s *Server) handleEvaluateBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TenantID string `json:"tenantId"`
+		Items    []struct {
+			ID    string         `json:"id,omitempty"` // optional, echoed in the response
+			Facts map[string]any `json:"facts"`
+		} `json:"items"`
+		Mode       rules.EvaluationMode `json:"mode,omitempty"`       // optional, defaults to the tenant's evaluationMode, else "all"
+		MaxMatches int                  `json:"maxMatches,omitempty"` // required for "stop-after-N-matches"
+		Tags       []string             `json:"tags,omitempty"`       // optional, rules carrying any of these tags
+		RuleSet    string               `json:"ruleSet,omitempty"`    // optional, rules in this rule set
+		Explain    bool                 `json:"explain,omitempty"`    // optional, break results down by sub-expression
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	if req.TenantID == "" {
+		respondError(w, http.StatusBadRequest, "tenantId is required", nil)
+		return
+	}
+
+	if len(req.Items) == 0 {
+		respondError(w, http.StatusBadRequest, "items are required", nil)
+		return
+	}
+
+	opts := rules.EvaluateOptions{
+		Mode:       req.Mode,
+		MaxMatches: req.MaxMatches,
+		Tags:       req.Tags,
+		RuleSet:    req.RuleSet,
+		Explain:    req.Explain,
+	}
+	if err := opts.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid evaluation mode", err)
+		return
+	}
+
+	engine, err := s.engineManager.GetEngine(req.TenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	items := make([]rules.BatchItem, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = rules.BatchItem{ID: item.ID, Facts: item.Facts}
+	}
+
+	startTime := time.Now()
+
+	batchResults, err := engine.EvaluateBatchContext(r.Context(), items, opts)
+	if errors.Is(err, rules.ErrBatchTooLarge) {
+		respondError(w, http.StatusRequestEntityTooLarge, "batch too large", err)
+		return
+	}
+	if errors.Is(err, rules.ErrExplainUnavailable) {
+		respondError(w, http.StatusBadRequest, "explanations are unavailable", err)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "evaluation failed", err)
+		return
+	}
+
+	evaluationTime := time.Since(startTime)
+
+	type batchItemResponse struct {
+		ID      string                    `json:"id,omitempty"`
+		Index   int                       `json:"index"`
+		Results []*rules.EvaluationResult `json:"results"`
+		Error   string                    `json:"error,omitempty"`
+	}
+
+	response := make([]batchItemResponse, len(batchResults))
+	for i, result := range batchResults {
+		response[i] = batchItemResponse{
+			ID:      result.ID,
+			Index:   result.Index,
+			Results: result.Results,
+		}
+		if result.Error != nil {
+			response[i].Error = result.Error.Error()
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"items":          response,
+		"evaluationTime": evaluationTime.String(),
+	})
+}
+
+// handleEvaluatePartial godoc
+// @Summary Evaluate rules against incomplete facts
+// @Description Evaluate a tenant's active rules against facts that may be missing fields. Each rule is reported as decided, with its outcome, or undecided, with the residual expression and the facts it still needs.
+// @Tags evaluation
+// @Accept json
+// @Produce json
+// @Param request body PartialEvaluateRequest true "Partial evaluation request with tenant ID and known facts"
+// @Success 200 {object} PartialEvaluateResponse
+// @Failure 400 {object} ErrorResponse "Invalid request or facts don't match schema"
+// @Failure 404 {object} ErrorResponse "Tenant not found"
+// @Failure 500 {object} ErrorResponse "Evaluation error"
+// @Router /api/v1/evaluate/partial [post]
+func (s *Server) handleEvaluatePartial(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TenantID string         `json:"tenantId"`
+		Facts    map[string]any `json:"facts"`
+		Tags     []string       `json:"tags,omitempty"`    // optional, rules carrying any of these tags
+		RuleSet  string         `json:"ruleSet,omitempty"` // optional, rules in this rule set
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	if req.TenantID == "" {
+		respondError(w, http.StatusBadRequest, "tenantId is required", nil)
+		return
+	}
+
+	if req.Facts == nil {
+		// Nothing known yet; every rule reports the facts it needs
+		req.Facts = map[string]any{}
+	}
+
+	engine, err := s.engineManager.GetEngine(req.TenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	startTime := time.Now()
+
+	opts := rules.EvaluateOptions{Tags: req.Tags, RuleSet: req.RuleSet}
+	results, err := engine.EvaluatePartialContext(r.Context(), req.Facts, opts)
+	if errors.Is(err, rules.ErrInvalidFacts) {
+		respondError(w, http.StatusBadRequest, "invalid facts", err)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "evaluation failed", err)
+		return
+	}
+
+	evaluationTime := time.Since(startTime)
+
+	type partialResultResponse struct {
+		RuleID            string   `json:"ruleId"`
+		RuleName          string   `json:"ruleName"`
+		Decided           bool     `json:"decided"`
+		Matched           bool     `json:"matched"`
+		Output            any      `json:"output,omitempty"`
+		Residual          string   `json:"residual,omitempty"`
+		MissingAttributes []string `json:"missingAttributes,omitempty"`
+		Error             string   `json:"error,omitempty"`
+	}
+
+	response := make([]partialResultResponse, len(results))
+	for i, result := range results {
+		response[i] = partialResultResponse{
+			RuleID:            result.RuleID,
+			RuleName:          result.RuleName,
+			Decided:           result.Decided,
+			Matched:           result.Matched,
+			Output:            result.Output,
+			Residual:          result.Residual,
+			MissingAttributes: result.MissingAttributes,
+		}
+		if result.Error != nil {
+			response[i].Error = result.Error.Error()
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"results":        response,
+		"evaluationTime": evaluationTime.String(),
+	})
+}
+
 // List tenants handler
 func (s *Server) handleListTenants(w http.ResponseWriter, r *http.Request) {
 	rows, err := s.db.Query("SELECT id, name, created_at, updated_at FROM tenants ORDER BY created_at DESC")
@@ -420,6 +700,162 @@ func (s *Server) handleCreateTenant(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// tenantSettingsBody is the JSON form of a tenant's settings
+type tenantSettingsBody struct {
+	RuleTimeoutMs    int64 `json:"ruleTimeoutMs"`
+	RequestTimeoutMs int64 `json:"requestTimeoutMs"`
+	Concurrency      int   `json:"concurrency"`
+	MaxBatchSize     int   `json:"maxBatchSize"`
+	MaxRuleCost      int64 `json:"maxRuleCost"`
+}
+
+// handleGetTenantSettings godoc
+// @Summary Get tenant settings
+// @Description Get a tenant's evaluation settings. Timeouts of 0 mean no limit; concurrency of 0 or 1 evaluates rules sequentially.
+// @Tags tenants
+// @Produce json
+// @Param tenantId path string true "Tenant ID"
+// @Success 200 {object} TenantSettingsRequest
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/tenants/{tenantId}/settings [get]
+func (s *Server) handleGetTenantSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	settings, err := s.engineManager.GetTenantSettings(tenantID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get tenant settings", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, tenantSettingsBody{
+		RuleTimeoutMs:    settings.RuleTimeout.Milliseconds(),
+		RequestTimeoutMs: settings.RequestTimeout.Milliseconds(),
+		Concurrency:      settings.Concurrency,
+		MaxBatchSize:     settings.MaxBatchSize,
+		MaxRuleCost:      settings.MaxRuleCost,
+	})
+}
+
+// handleUpdateTenantSettings godoc
+// @Summary Update tenant settings
+// @Description Update a tenant's evaluation settings. Changes apply to evaluations started afterwards.
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param tenantId path string true "Tenant ID"
+// @Param settings body TenantSettingsRequest true "Tenant settings"
+// @Success 200 {object} TenantSettingsRequest
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/tenants/{tenantId}/settings [put]
+func (s *Server) handleUpdateTenantSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	var req tenantSettingsBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	settings := multitenantengine.TenantSettings{
+		RuleTimeout:    time.Duration(req.RuleTimeoutMs) * time.Millisecond,
+		RequestTimeout: time.Duration(req.RequestTimeoutMs) * time.Millisecond,
+		Concurrency:    req.Concurrency,
+		MaxBatchSize:   req.MaxBatchSize,
+		MaxRuleCost:    req.MaxRuleCost,
+	}
+
+	if err := s.engineManager.UpdateTenantSettings(tenantID, settings); err != nil {
+		respondError(w, http.StatusBadRequest, "failed to update tenant settings", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, req)
+}
+
+// engineOptionsBody is the JSON form of a tenant's engine options
+type engineOptionsBody struct {
+	CostLimit            uint64   `json:"costLimit"`
+	DisableStateTracking bool     `json:"disableStateTracking"`
+	CacheTTLMs           int64    `json:"cacheTtlMs"`
+	Extensions           []string `json:"extensions"`
+	EvaluationMode       string   `json:"evaluationMode"`
+	MaxMatches           int      `json:"maxMatches"`
+}
+
+// newEngineOptionsBody converts engine options into their JSON form
+func newEngineOptionsBody(options rules.EngineOptions) engineOptionsBody {
+	extensions := options.Extensions
+	if extensions == nil {
+		extensions = []string{}
+	}
+	return engineOptionsBody{
+		CostLimit:            options.CostLimit,
+		DisableStateTracking: options.DisableStateTracking,
+		CacheTTLMs:           options.CacheTTL.Milliseconds(),
+		Extensions:           extensions,
+		EvaluationMode:       string(options.EvaluationMode),
+		MaxMatches:           options.MaxMatches,
+	}
+}
+
+// handleGetEngineOptions godoc
+// @Summary Get engine options
+// @Description Get the options a tenant's engine is built with. Zero values use the engine defaults.
+// @Tags tenants
+// @Produce json
+// @Param tenantId path string true "Tenant ID"
+// @Success 200 {object} EngineOptionsRequest
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/tenants/{tenantId}/engine-options [get]
+func (s *Server) handleGetEngineOptions(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	options, err := s.engineManager.GetEngineOptions(tenantID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get engine options", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newEngineOptionsBody(options))
+}
+
+// handleUpdateEngineOptions godoc
+// @Summary Update engine options
+// @Description Replace a tenant's engine options. The engine is rebuilt with the new options and swapped in without interrupting evaluations.
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param tenantId path string true "Tenant ID"
+// @Param options body EngineOptionsRequest true "Engine options"
+// @Success 200 {object} EngineOptionsRequest
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/tenants/{tenantId}/engine-options [put]
+func (s *Server) handleUpdateEngineOptions(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	var req engineOptionsBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	options := rules.EngineOptions{
+		CostLimit:            req.CostLimit,
+		DisableStateTracking: req.DisableStateTracking,
+		CacheTTL:             time.Duration(req.CacheTTLMs) * time.Millisecond,
+		Extensions:           req.Extensions,
+		EvaluationMode:       rules.EvaluationMode(req.EvaluationMode),
+		MaxMatches:           req.MaxMatches,
+	}
+
+	if err := s.engineManager.UpdateEngineOptions(tenantID, options); err != nil {
+		respondError(w, http.StatusBadRequest, "failed to update engine options", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newEngineOptionsBody(options))
+}
+
 // handleCreateSchema godoc
 // @Summary Create a schema for a tenant
 // @Description Create a new schema definition for a tenant. Can only be called once per tenant. See validation rules in documentation.
@@ -514,6 +950,8 @@ func (s *Server) handleCreateSchema(w http.ResponseWriter, r *http.Request) {
 }
 
 // Update schema handler
+// A schema that would break rules is refused with 409 and the impact on each
+// rule, unless force=true, which deactivates the active rules it breaks
 func (s *Server) handleUpdateSchema(w http.ResponseWriter, r *http.Request) {
 	tenantID := chi.URLParam(r, "tenantId")
 
@@ -534,7 +972,24 @@ func (s *Server) handleUpdateSchema(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update schema (zero downtime!)
-	err := s.engineManager.UpdateTenantSchema(tenantID, req.Definition)
+	var report *multitenantengine.SchemaChangeReport
+	var err error
+	if r.URL.Query().Get("force") == "true" {
+		report, err = s.engineManager.ForceUpdateTenantSchema(tenantID, req.Definition)
+	} else {
+		err = s.engineManager.UpdateTenantSchema(tenantID, req.Definition)
+	}
+	var changeErr *multitenantengine.SchemaChangeError
+	if errors.As(err, &changeErr) {
+		response := newSchemaChangeResponse(changeErr.Report)
+		respondJSON(w, http.StatusConflict, map[string]any{
+			"error":    "schema change breaks rules",
+			"details":  err.Error(),
+			"breaking": response.Breaking,
+			"rules":    response.Rules,
+		})
+		return
+	}
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to update schema", err)
 		return
@@ -552,54 +1007,942 @@ func (s *Server) handleUpdateSchema(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Rules deactivated by a forced update
+	deactivated := []string{}
+	if report != nil {
+		for _, impact := range report.Breaking() {
+			if impact.Active {
+				deactivated = append(deactivated, impact.RuleID)
+			}
+		}
+	}
+
 	respondJSON(w, http.StatusOK, map[string]any{
-		"version":    version,
-		"status":     "active",
-		"definition": req.Definition,
+		"version":          version,
+		"status":           "active",
+		"definition":       req.Definition,
+		"deactivatedRules": deactivated,
 	})
 }
 
-// Get schema handler
-func (s *Server) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+// Schema dry-run handler
+// Compiles every rule against a candidate schema without storing anything and
+// reports which rules it would break
+func (s *Server) handleSchemaDryRun(w http.ResponseWriter, r *http.Request) {
 	tenantID := chi.URLParam(r, "tenantId")
 
-	var schemaJSON []byte
-	var version int
-	err := s.db.QueryRow(`
-		SELECT version, definition
-		FROM schemas
-		WHERE tenant_id = $1 AND active = true
-	`, tenantID).Scan(&version, &schemaJSON)
+	var req struct {
+		Definition multitenantengine.Schema `json:"definition"`
+	}
 
-	if err == sql.ErrNoRows {
-		respondError(w, http.StatusNotFound, "schema not found", nil)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to get schema", err)
+
+	if err := multitenantengine.ValidateSchema(req.Definition); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("schema validation failed: %v", err), nil)
 		return
 	}
 
-	var schema multitenantengine.Schema
-	if err := json.Unmarshal(schemaJSON, &schema); err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to parse schema", err)
+	if _, err := s.engineManager.GetEngine(tenantID); err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]any{
-		"version":    version,
-		"definition": schema,
-	})
+	report, err := s.engineManager.CheckSchemaChange(tenantID, req.Definition)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "failed to check schema change", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newSchemaChangeResponse(report))
 }
 
-// Create rule handler
-func (s *Server) handleCreateRule(w http.ResponseWriter, r *http.Request) {
+// Schema changelog handler
+// Lists the outcome of every schema update of the tenant, newest first
+func (s *Server) handleSchemaChangelog(w http.ResponseWriter, r *http.Request) {
 	tenantID := chi.URLParam(r, "tenantId")
 
-	var req struct {
-		Name       string `json:"name"`
+	changes, err := s.engineManager.SchemaChangelog(tenantID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get schema changelog", err)
+		return
+	}
+
+	type schemaChangeEntry struct {
+		ID              string                                 `json:"id"`
+		SchemaVersion   int                                    `json:"schemaVersion"`
+		ChangeType      string                                 `json:"changeType"`
+		RulesRecompiled int                                    `json:"rulesRecompiled"`
+		RulesFailed     int                                    `json:"rulesFailed"`
+		ErrorDetails    *multitenantengine.SchemaChangeDetails `json:"errorDetails,omitempty"`
+		CreatedAt       time.Time                              `json:"createdAt"`
+	}
+
+	response := make([]schemaChangeEntry, len(changes))
+	for i, change := range changes {
+		response[i] = schemaChangeEntry{
+			ID:              change.ID,
+			SchemaVersion:   change.SchemaVersion,
+			ChangeType:      string(change.ChangeType),
+			RulesRecompiled: change.RulesRecompiled,
+			RulesFailed:     change.RulesFailed,
+			ErrorDetails:    change.Details,
+			CreatedAt:       change.CreatedAt,
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"changes": response,
+	})
+}
+
+// ruleImpactResponse is the JSON form of a multitenantengine.RuleImpact
+type ruleImpactResponse struct {
+	RuleID      string               `json:"ruleId"`
+	RuleName    string               `json:"ruleName"`
+	Active      bool                 `json:"active"`
+	Before      string               `json:"before"`
+	After       string               `json:"after"`
+	Breaks      bool                 `json:"breaks"`
+	Error       string               `json:"error,omitempty"`
+	Diagnostics []diagnosticResponse `json:"diagnostics,omitempty"`
+}
+
+// schemaChangeResponse is the JSON form of a multitenantengine.SchemaChangeReport
+type schemaChangeResponse struct {
+	Compatible bool                 `json:"compatible"`
+	Breaking   int                  `json:"breaking"`
+	Rules      []ruleImpactResponse `json:"rules"`
+}
+
+func newSchemaChangeResponse(report *multitenantengine.SchemaChangeReport) schemaChangeResponse {
+	response := schemaChangeResponse{Rules: make([]ruleImpactResponse, len(report.Rules))}
+	for i, impact := range report.Rules {
+		entry := ruleImpactResponse{
+			RuleID:   impact.RuleID,
+			RuleName: impact.RuleName,
+			Active:   impact.Active,
+			Before:   string(impact.Before),
+			After:    string(impact.After),
+			Breaks:   impact.Breaks(),
+		}
+		if impact.Err != nil {
+			entry.Error = impact.Err.Error()
+			var compileErr *rules.CompileError
+			if errors.As(impact.Err, &compileErr) {
+				entry.Diagnostics = diagnosticsResponse(compileErr.Diagnostics)
+			}
+		}
+		if entry.Breaks {
+			response.Breaking++
+		}
+		response.Rules[i] = entry
+	}
+	response.Compatible = response.Breaking == 0
+	return response
+}
+
+// Get schema handler
+func (s *Server) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	var schemaJSON []byte
+	var version int
+	err := s.db.QueryRow(`
+		SELECT version, definition
+		FROM schemas
+		WHERE tenant_id = $1 AND active = true
+	`, tenantID).Scan(&version, &schemaJSON)
+
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "schema not found", nil)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get schema", err)
+		return
+	}
+
+	var schema multitenantengine.Schema
+	if err := json.Unmarshal(schemaJSON, &schema); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to parse schema", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"version":    version,
+		"definition": schema,
+	})
+}
+
+// Schema field usage handler
+// Lists every schema field with the rules and derived fields that read it. The
+// optional field query parameter restricts the list to one field, e.g.
+// field=User.Age, and unused=true to fields nothing reads.
+func (s *Server) handleSchemaFieldUsage(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	if _, err := s.engineManager.GetEngine(tenantID); err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	usage, err := s.engineManager.FieldUsage(tenantID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get field usage", err)
+		return
+	}
+
+	field := r.URL.Query().Get("field")
+	unusedOnly := r.URL.Query().Get("unused") == "true"
+
+	type fieldUsageResponse struct {
+		Path          string   `json:"path"`
+		Object        string   `json:"object"`
+		Field         string   `json:"field"`
+		Type          string   `json:"type"`
+		Rules         []string `json:"rules"`
+		DerivedFields []string `json:"derivedFields"`
+		Unused        bool     `json:"unused"`
+	}
+
+	response := []fieldUsageResponse{}
+	for _, u := range usage {
+		unused := len(u.Rules) == 0 && len(u.DerivedFields) == 0
+		if (field != "" && u.Path() != field) || (unusedOnly && !unused) {
+			continue
+		}
+		response = append(response, fieldUsageResponse{
+			Path:          u.Path(),
+			Object:        u.Object,
+			Field:         u.Field,
+			Type:          u.Type,
+			Rules:         u.Rules,
+			DerivedFields: u.DerivedFields,
+			Unused:        unused,
+		})
+	}
+
+	if field != "" && len(response) == 0 && !unusedOnly {
+		respondError(w, http.StatusNotFound, "field not found", fmt.Errorf("%s is not a schema field", field))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"fields": response,
+	})
+}
+
+// Create rule handler
+func (s *Server) handleCreateRule(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	var req struct {
+		Name       string   `json:"name"`
+		Expression string   `json:"expression"`
+		Active     bool     `json:"active"`
+		Shadow     bool     `json:"shadow"`
+		Priority   int      `json:"priority"`
+		OutputType string   `json:"outputType"`
+		Tags       []string `json:"tags"`
+		RuleSets   []string `json:"ruleSets"`
+
+		EffectiveFrom  *time.Time `json:"effectiveFrom"`
+		EffectiveUntil *time.Time `json:"effectiveUntil"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	if req.Name == "" || req.Expression == "" {
+		respondError(w, http.StatusBadRequest, "name and expression are required", nil)
+		return
+	}
+
+	// Get engine to add and compile the rule
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	// Create rule
+	rule := &rules.Rule{
+		Name:           req.Name,
+		Expression:     req.Expression,
+		Active:         req.Active,
+		Shadow:         req.Shadow,
+		Priority:       req.Priority,
+		OutputType:     req.OutputType,
+		Tags:           req.Tags,
+		RuleSets:       req.RuleSets,
+		EffectiveFrom:  req.EffectiveFrom,
+		EffectiveUntil: req.EffectiveUntil,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	// Generate UUID for rule ID
+	rule.ID = generateUUID()
+
+	// Add rule (this validates and compiles it)
+	if err := engine.AddRule(rule); err != nil {
+		if respondTestFailure(w, "rule failed its test cases", err) {
+			return
+		}
+		if respondCompileError(w, "failed to add rule", err, rule.Expression) {
+			return
+		}
+		respondError(w, http.StatusBadRequest, "failed to add rule", err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"id":               rule.ID,
+		"name":             rule.Name,
+		"expression":       rule.Expression,
+		"active":           rule.Active,
+		"shadow":           rule.Shadow,
+		"priority":         rule.Priority,
+		"outputType":       rule.OutputType,
+		"tags":             rule.Tags,
+		"ruleSets":         rule.RuleSets,
+		"effectiveFrom":    rule.EffectiveFrom,
+		"effectiveUntil":   rule.EffectiveUntil,
+		"status":           rule.StatusAt(time.Now()),
+		"estimatedCost":    estimatedCost(engine, rule),
+		"referencedFields": referencedFields(engine, rule),
+		"version":          rule.Version,
+	})
+}
+
+// ruleWithStatus is a rule as returned by the API, with whether it is live,
+// scheduled, expired or inactive right now, its estimated cost and the fields
+// it reads
+type ruleWithStatus struct {
+	*rules.Rule
+	Status           rules.RuleStatus
+	EstimatedCost    *rules.CostEstimate `json:",omitempty"`
+	ReferencedFields []string
+}
+
+// newRuleWithStatus describes a rule as of now
+// The cost is left out when the tenant's engine is not loaded or the rule no
+// longer compiles
+func newRuleWithStatus(engine *rules.Engine, rule *rules.Rule, now time.Time) ruleWithStatus {
+	return ruleWithStatus{
+		Rule:             rule,
+		Status:           rule.StatusAt(now),
+		EstimatedCost:    estimatedCost(engine, rule),
+		ReferencedFields: referencedFields(engine, rule),
+	}
+}
+
+// referencedFields returns the field paths a rule reads as the engine compiles
+// it, falling back to those stored with the rule, which are empty for rules
+// stored before they were recorded, when the engine is not loaded or the rule
+// no longer compiles
+func referencedFields(engine *rules.Engine, rule *rules.Rule) []string {
+	if engine == nil {
+		return rule.ReferencedFields
+	}
+	fields, err := engine.ReferencedFields(rule)
+	if err != nil {
+		return rule.ReferencedFields
+	}
+	return fields
+}
+
+// estimatedCost returns the static cost estimate of a rule, or nil when it
+// cannot be estimated
+func estimatedCost(engine *rules.Engine, rule *rules.Rule) *rules.CostEstimate {
+	if engine == nil {
+		return nil
+	}
+	cost, err := engine.EstimateRuleCost(rule)
+	if err != nil {
+		return nil
+	}
+	return &cost
+}
+
+// List rules handler
+// The optional tag query parameter restricts the list to rules carrying that
+// tag, and status to rules with that status, e.g. status=scheduled
+func (s *Server) handleListRules(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	status := rules.RuleStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", rules.RuleStatusLive, rules.RuleStatusScheduled, rules.RuleStatusExpired, rules.RuleStatusInactive:
+	default:
+		respondError(w, http.StatusBadRequest, "invalid status", fmt.Errorf("status must be one of: %s, %s, %s, %s",
+			rules.RuleStatusLive, rules.RuleStatusScheduled, rules.RuleStatusExpired, rules.RuleStatusInactive))
+		return
+	}
+
+	store := rules.NewPostgresRuleStore(s.db, tenantID)
+	var rulesList []*rules.Rule
+	var err error
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		rulesList, err = store.ListByTag(tag)
+	} else {
+		rulesList, err = store.List()
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list rules", err)
+		return
+	}
+
+	engine, _ := s.engineManager.GetEngine(tenantID)
+	now := time.Now()
+	response := make([]ruleWithStatus, 0, len(rulesList))
+	for _, rule := range rulesList {
+		if status != "" && rule.StatusAt(now) != status {
+			continue
+		}
+		response = append(response, newRuleWithStatus(engine, rule, now))
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"rules": response,
+	})
+}
+
+// Rule stats handler
+// Reports match, error and latency stats of the rules every replica has
+// evaluated; shadow=true restricts the report to shadow rules
+func (s *Server) handleRuleStats(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	shadowOnly := r.URL.Query().Get("shadow") == "true"
+
+	type ruleStatsResponse struct {
+		RuleID         string    `json:"ruleId"`
+		RuleName       string    `json:"ruleName"`
+		Shadow         bool      `json:"shadow"`
+		Evaluations    int64     `json:"evaluations"`
+		Matches        int64     `json:"matches"`
+		Errors         int64     `json:"errors"`
+		TimedOut       int64     `json:"timedOut"`
+		Skipped        int64     `json:"skipped"`
+		MatchRate      float64   `json:"matchRate"`
+		AverageLatency string    `json:"averageLatency"`
+		MaxLatency     string    `json:"maxLatency"`
+		LastEvaluated  time.Time `json:"lastEvaluated"`
+	}
+
+	allStats, err := engine.RuleStats()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to load rule stats", err)
+		return
+	}
+
+	response := []ruleStatsResponse{}
+	for _, stats := range allStats {
+		if shadowOnly && !stats.Shadow {
+			continue
+		}
+		entry := ruleStatsResponse{
+			RuleID:         stats.RuleID,
+			RuleName:       stats.RuleName,
+			Shadow:         stats.Shadow,
+			Evaluations:    stats.Evaluations,
+			Matches:        stats.Matches,
+			Errors:         stats.Errors,
+			TimedOut:       stats.TimedOut,
+			Skipped:        stats.Skipped,
+			AverageLatency: stats.AverageLatency.String(),
+			MaxLatency:     stats.MaxLatency.String(),
+			LastEvaluated:  stats.LastEvaluated,
+		}
+		if stats.Evaluations > 0 {
+			entry.MatchRate = float64(stats.Matches) / float64(stats.Evaluations)
+		}
+		response = append(response, entry)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"stats": response,
+	})
+}
+
+// Get rule handler
+func (s *Server) handleGetRule(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ruleID := chi.URLParam(r, "ruleId")
+
+	store := rules.NewPostgresRuleStore(s.db, tenantID)
+	rule, err := store.Get(ruleID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "rule not found", err)
+		return
+	}
+
+	engine, _ := s.engineManager.GetEngine(tenantID)
+	respondJSON(w, http.StatusOK, newRuleWithStatus(engine, rule, time.Now()))
+}
+
+// Update rule handler
+func (s *Server) handleUpdateRule(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ruleID := chi.URLParam(r, "ruleId")
+
+	var req struct {
+		Name       string   `json:"name"`
+		Expression string   `json:"expression"`
+		Active     bool     `json:"active"`
+		Shadow     bool     `json:"shadow"`
+		Priority   int      `json:"priority"`
+		OutputType string   `json:"outputType"`
+		Tags       []string `json:"tags"`
+		RuleSets   []string `json:"ruleSets"`
+
+		EffectiveFrom  *time.Time `json:"effectiveFrom"`
+		EffectiveUntil *time.Time `json:"effectiveUntil"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	// Get engine
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	// Update rule
+	rule := &rules.Rule{
+		ID:             ruleID,
+		Name:           req.Name,
+		Expression:     req.Expression,
+		Active:         req.Active,
+		Shadow:         req.Shadow,
+		Priority:       req.Priority,
+		OutputType:     req.OutputType,
+		Tags:           req.Tags,
+		RuleSets:       req.RuleSets,
+		EffectiveFrom:  req.EffectiveFrom,
+		EffectiveUntil: req.EffectiveUntil,
+		UpdatedAt:      time.Now(),
+	}
+
+	if err := engine.UpdateRule(rule); err != nil {
+		if respondTestFailure(w, "rule failed its test cases", err) {
+			return
+		}
+		if respondCompileError(w, "failed to update rule", err, rule.Expression) {
+			return
+		}
+		respondError(w, http.StatusBadRequest, "failed to update rule", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newRuleWithStatus(engine, rule, time.Now()))
+}
+
+// Delete rule handler
+func (s *Server) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ruleID := chi.URLParam(r, "ruleId")
+
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	if err := engine.DeleteRule(ruleID); err != nil {
+		if errors.Is(err, rules.ErrRuleInUse) {
+			respondError(w, http.StatusConflict, "rule is referenced by other rules", err)
+			return
+		}
+		respondError(w, http.StatusNotFound, "rule not found", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List rule versions handler
+func (s *Server) handleListRuleVersions(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ruleID := chi.URLParam(r, "ruleId")
+
+	store := rules.NewPostgresRuleStore(s.db, tenantID)
+	versions, err := store.ListVersions(ruleID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "rule not found", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"versions": versions,
+	})
+}
+
+// Get rule version handler
+func (s *Server) handleGetRuleVersion(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ruleID := chi.URLParam(r, "ruleId")
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "version must be an integer", err)
+		return
+	}
+
+	store := rules.NewPostgresRuleStore(s.db, tenantID)
+	ruleVersion, err := store.GetVersion(ruleID, version)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "rule version not found", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ruleVersion)
+}
+
+// Diff rule versions handler
+// Compares two revisions given by the from and to query parameters
+func (s *Server) handleDiffRuleVersions(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ruleID := chi.URLParam(r, "ruleId")
+
+	fromVersion, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "from must be an integer version", err)
+		return
+	}
+	toVersion, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "to must be an integer version", err)
+		return
+	}
+
+	store := rules.NewPostgresRuleStore(s.db, tenantID)
+	from, err := store.GetVersion(ruleID, fromVersion)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "rule version not found", err)
+		return
+	}
+	to, err := store.GetVersion(ruleID, toVersion)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "rule version not found", err)
+		return
+	}
+
+	diff, err := rules.DiffRuleVersions(from, to)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "failed to diff rule versions", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, diff)
+}
+
+// Rollback rule handler
+// Restores an earlier revision; the rollback itself is recorded as a new revision
+func (s *Server) handleRollbackRule(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ruleID := chi.URLParam(r, "ruleId")
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "version must be an integer", err)
+		return
+	}
+
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	rule, err := engine.RollbackRule(ruleID, version)
+	if err != nil {
+		if respondTestFailure(w, "rolled back rule failed its test cases", err) {
+			return
+		}
+		respondError(w, http.StatusBadRequest, "failed to roll back rule", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newRuleWithStatus(engine, rule, time.Now()))
+}
+
+// ruleTestRequest is the body of the rule test case create and update endpoints
+type ruleTestRequest struct {
+	Name            string         `json:"name"`
+	Facts           map[string]any `json:"facts"`
+	ExpectedMatched *bool          `json:"expectedMatched,omitempty"`
+	ExpectedOutput  any            `json:"expectedOutput,omitempty"`
+}
+
+// List rule test cases handler
+func (s *Server) handleListRuleTests(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ruleID := chi.URLParam(r, "ruleId")
+
+	store := rules.NewPostgresRuleTestStore(s.db, tenantID)
+	cases, err := store.ListByRule(ruleID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list test cases", err)
+		return
+	}
+	if cases == nil {
+		cases = []*rules.RuleTestCase{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"tests": cases,
+	})
+}
+
+// Create rule test case handler
+// The case must pass against the current rule before it is stored
+func (s *Server) handleCreateRuleTest(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ruleID := chi.URLParam(r, "ruleId")
+
+	var req ruleTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	if req.Name == "" || req.Facts == nil {
+		respondError(w, http.StatusBadRequest, "name and facts are required", nil)
+		return
+	}
+
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	tc := &rules.RuleTestCase{
+		ID:              generateUUID(),
+		RuleID:          ruleID,
+		Name:            req.Name,
+		Facts:           req.Facts,
+		ExpectedMatched: req.ExpectedMatched,
+		ExpectedOutput:  req.ExpectedOutput,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	if err := engine.AddTestCase(tc); err != nil {
+		if respondTestFailure(w, "test case does not pass against the current rule", err) {
+			return
+		}
+		respondError(w, http.StatusBadRequest, "failed to add test case", err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, tc)
+}
+
+// Update rule test case handler
+func (s *Server) handleUpdateRuleTest(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ruleID := chi.URLParam(r, "ruleId")
+	testID := chi.URLParam(r, "testId")
+
+	var req ruleTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	if req.Name == "" || req.Facts == nil {
+		respondError(w, http.StatusBadRequest, "name and facts are required", nil)
+		return
+	}
+
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	tc := &rules.RuleTestCase{
+		ID:              testID,
+		RuleID:          ruleID,
+		Name:            req.Name,
+		Facts:           req.Facts,
+		ExpectedMatched: req.ExpectedMatched,
+		ExpectedOutput:  req.ExpectedOutput,
+		UpdatedAt:       time.Now(),
+	}
+
+	if err := engine.UpdateTestCase(tc); err != nil {
+		if respondTestFailure(w, "test case does not pass against the current rule", err) {
+			return
+		}
+		respondError(w, http.StatusBadRequest, "failed to update test case", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, tc)
+}
+
+// Delete rule test case handler
+func (s *Server) handleDeleteRuleTest(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	testID := chi.URLParam(r, "testId")
+
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	if err := engine.DeleteTestCase(testID); err != nil {
+		respondError(w, http.StatusNotFound, "test case not found", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Run tests handler
+// Runs every stored test case of the tenant against its current rules
+func (s *Server) handleRunTests(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	startTime := time.Now()
+
+	suite, err := engine.RunTests()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to run test cases", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"total":   suite.Total,
+		"passed":  suite.Passed,
+		"failed":  suite.Failed,
+		"results": testCaseResultsResponse(suite.Results),
+		"runTime": time.Since(startTime).String(),
+	})
+}
+
+// testCaseResultResponse is the JSON form of a rules.TestCaseResult
+type testCaseResultResponse struct {
+	TestCaseID string `json:"testCaseId"`
+	RuleID     string `json:"ruleId"`
+	Name       string `json:"name"`
+	Passed     bool   `json:"passed"`
+	Matched    bool   `json:"matched"`
+	Output     any    `json:"output,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Failure    string `json:"failure,omitempty"`
+}
+
+func testCaseResultsResponse(results []*rules.TestCaseResult) []testCaseResultResponse {
+	response := make([]testCaseResultResponse, len(results))
+	for i, result := range results {
+		response[i] = testCaseResultResponse{
+			TestCaseID: result.TestCaseID,
+			RuleID:     result.RuleID,
+			Name:       result.Name,
+			Passed:     result.Passed,
+			Matched:    result.Matched,
+			Output:     result.Output,
+			Error:      result.Error,
+			Failure:    result.Failure,
+		}
+	}
+	return response
+}
+
+// respondTestFailure writes a 422 listing the failed test cases when err is
+// a rules.TestFailureError, reporting whether it did
+func respondTestFailure(w http.ResponseWriter, message string, err error) bool {
+	var failure *rules.TestFailureError
+	if !errors.As(err, &failure) {
+		return false
+	}
+
+	respondJSON(w, http.StatusUnprocessableEntity, map[string]any{
+		"error":    message,
+		"details":  err.Error(),
+		"failures": testCaseResultsResponse(failure.Failures),
+	})
+	return true
+}
+
+// diagnosticResponse is the JSON form of a rules.Diagnostic
+type diagnosticResponse struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Offset   int    `json:"offset"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Snippet  string `json:"snippet"`
+}
+
+func diagnosticsResponse(diagnostics []rules.Diagnostic) []diagnosticResponse {
+	response := make([]diagnosticResponse, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		response[i] = diagnosticResponse{
+			Line:     diagnostic.Line,
+			Column:   diagnostic.Column,
+			Offset:   diagnostic.Offset,
+			Severity: string(diagnostic.Severity),
+			Message:  diagnostic.Message,
+			Snippet:  diagnostic.Snippet,
+		}
+	}
+	return response
+}
+
+// respondCompileError writes a 400 listing the diagnostics when err is a
+// rules.CompileError in expression, reporting whether it did
+// Compile errors in other rules broken by the change are left to respondError,
+// since their positions do not refer to the submitted expression.
+func respondCompileError(w http.ResponseWriter, message string, err error, expression string) bool {
+	var compileErr *rules.CompileError
+	if !errors.As(err, &compileErr) || compileErr.Expression != expression {
+		return false
+	}
+
+	respondJSON(w, http.StatusBadRequest, map[string]any{
+		"error":       message,
+		"details":     err.Error(),
+		"diagnostics": diagnosticsResponse(compileErr.Diagnostics),
+	})
+	return true
+}
+
+// Create derived field handler
+// The field is compiled and every active rule recompiled before it is stored
+func (s *Server) handleCreateDerivedField(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	var req struct {
+		Name       string `json:"name"`
 		Expression string `json:"expression"`
-		Active     bool   `json:"active"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -612,94 +1955,66 @@ func (s *Server) handleCreateRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get engine to add and compile the rule
 	engine, err := s.engineManager.GetEngine(tenantID)
 	if err != nil {
 		respondError(w, http.StatusNotFound, "tenant not found", err)
 		return
 	}
 
-	// Create rule
-	rule := &rules.Rule{
+	field := &rules.DerivedField{
 		Name:       req.Name,
 		Expression: req.Expression,
-		Active:     req.Active,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
 	}
 
-	// Generate UUID for rule ID
-	rule.ID = generateUUID()
-
-	// Add rule (this validates and compiles it)
-	if err := engine.AddRule(rule); err != nil {
-		respondError(w, http.StatusBadRequest, "failed to add rule", err)
+	if err := engine.AddDerivedField(field); err != nil {
+		respondError(w, http.StatusBadRequest, "failed to add derived field", err)
 		return
 	}
 
-	respondJSON(w, http.StatusCreated, map[string]any{
-		"id":         rule.ID,
-		"name":       rule.Name,
-		"expression": rule.Expression,
-		"active":     rule.Active,
-	})
+	respondJSON(w, http.StatusCreated, field)
 }
 
-// List rules handler
-func (s *Server) handleListRules(w http.ResponseWriter, r *http.Request) {
+// List derived fields handler
+func (s *Server) handleListDerivedFields(w http.ResponseWriter, r *http.Request) {
 	tenantID := chi.URLParam(r, "tenantId")
 
-	rows, err := s.db.Query(`
-		SELECT id, name, expression, active, created_at, updated_at
-		FROM rules
-		WHERE tenant_id = $1
-		ORDER BY created_at DESC
-	`, tenantID)
+	store := rules.NewPostgresDerivedFieldStore(s.db, tenantID)
+	fields, err := store.List()
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to list rules", err)
+		respondError(w, http.StatusInternalServerError, "failed to list derived fields", err)
 		return
 	}
-	defer rows.Close()
-
-	rulesList := []*rules.Rule{}
-	for rows.Next() {
-		var r rules.Rule
-		if err := rows.Scan(&r.ID, &r.Name, &r.Expression, &r.Active, &r.CreatedAt, &r.UpdatedAt); err != nil {
-			respondError(w, http.StatusInternalServerError, "failed to scan rule", err)
-			return
-		}
-		rulesList = append(rulesList, &r)
+	if fields == nil {
+		fields = []*rules.DerivedField{}
 	}
 
 	respondJSON(w, http.StatusOK, map[string]any{
-		"rules": rulesList,
+		"derivedFields": fields,
 	})
 }
 
-// Get rule handler
-func (s *Server) handleGetRule(w http.ResponseWriter, r *http.Request) {
+// Get derived field handler
+func (s *Server) handleGetDerivedField(w http.ResponseWriter, r *http.Request) {
 	tenantID := chi.URLParam(r, "tenantId")
-	ruleID := chi.URLParam(r, "ruleId")
+	name := chi.URLParam(r, "name")
 
-	store := rules.NewPostgresRuleStore(s.db, tenantID)
-	rule, err := store.Get(ruleID)
+	store := rules.NewPostgresDerivedFieldStore(s.db, tenantID)
+	field, err := store.Get(name)
 	if err != nil {
-		respondError(w, http.StatusNotFound, "rule not found", err)
+		respondError(w, http.StatusNotFound, "derived field not found", err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, rule)
+	respondJSON(w, http.StatusOK, field)
 }
 
-// Update rule handler
-func (s *Server) handleUpdateRule(w http.ResponseWriter, r *http.Request) {
+// Update derived field handler
+func (s *Server) handleUpdateDerivedField(w http.ResponseWriter, r *http.Request) {
 	tenantID := chi.URLParam(r, "tenantId")
-	ruleID := chi.URLParam(r, "ruleId")
+	name := chi.URLParam(r, "name")
 
 	var req struct {
-		Name       string `json:"name"`
 		Expression string `json:"expression"`
-		Active     bool   `json:"active"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -707,34 +2022,35 @@ func (s *Server) handleUpdateRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get engine
+	if req.Expression == "" {
+		respondError(w, http.StatusBadRequest, "expression is required", nil)
+		return
+	}
+
 	engine, err := s.engineManager.GetEngine(tenantID)
 	if err != nil {
 		respondError(w, http.StatusNotFound, "tenant not found", err)
 		return
 	}
 
-	// Update rule
-	rule := &rules.Rule{
-		ID:         ruleID,
-		Name:       req.Name,
+	field := &rules.DerivedField{
+		Name:       name,
 		Expression: req.Expression,
-		Active:     req.Active,
-		UpdatedAt:  time.Now(),
 	}
 
-	if err := engine.UpdateRule(rule); err != nil {
-		respondError(w, http.StatusBadRequest, "failed to update rule", err)
+	if err := engine.UpdateDerivedField(field); err != nil {
+		respondError(w, http.StatusBadRequest, "failed to update derived field", err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, rule)
+	respondJSON(w, http.StatusOK, field)
 }
 
-// Delete rule handler
-func (s *Server) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
+// Delete derived field handler
+// Deletion fails while rules or other derived fields still reference the field
+func (s *Server) handleDeleteDerivedField(w http.ResponseWriter, r *http.Request) {
 	tenantID := chi.URLParam(r, "tenantId")
-	ruleID := chi.URLParam(r, "ruleId")
+	name := chi.URLParam(r, "name")
 
 	engine, err := s.engineManager.GetEngine(tenantID)
 	if err != nil {
@@ -742,8 +2058,144 @@ func (s *Server) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := engine.DeleteRule(ruleID); err != nil {
-		respondError(w, http.StatusNotFound, "rule not found", err)
+	if err := engine.DeleteDerivedField(name); err != nil {
+		respondError(w, http.StatusBadRequest, "failed to delete derived field", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// referenceListRequest is the request body for creating or updating a reference list
+// Only the members matching kind may be set
+type referenceListRequest struct {
+	Name    string               `json:"name"`
+	Kind    rules.ListKind       `json:"kind"`
+	Strings []string             `json:"strings"`
+	Numbers []float64            `json:"numbers"`
+	Ranges  []rules.NumericRange `json:"ranges"`
+}
+
+// Create reference list handler
+// The list is declared to rules as lists.<name>
+func (s *Server) handleCreateReferenceList(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	var req referenceListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	if req.Name == "" || req.Kind == "" {
+		respondError(w, http.StatusBadRequest, "name and kind are required", nil)
+		return
+	}
+
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	list := &rules.ReferenceList{
+		Name:    req.Name,
+		Kind:    req.Kind,
+		Strings: req.Strings,
+		Numbers: req.Numbers,
+		Ranges:  req.Ranges,
+	}
+
+	if err := engine.AddReferenceList(list); err != nil {
+		respondError(w, http.StatusBadRequest, "failed to add list", err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, list)
+}
+
+// List reference lists handler
+func (s *Server) handleListReferenceLists(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	store := rules.NewPostgresReferenceListStore(s.db, tenantID)
+	lists, err := store.List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list lists", err)
+		return
+	}
+	if lists == nil {
+		lists = []*rules.ReferenceList{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"lists": lists,
+	})
+}
+
+// Get reference list handler
+func (s *Server) handleGetReferenceList(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	name := chi.URLParam(r, "name")
+
+	store := rules.NewPostgresReferenceListStore(s.db, tenantID)
+	list, err := store.Get(name)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "list not found", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// Update reference list handler
+// The members are replaced and take effect immediately, without recompiling rules
+func (s *Server) handleUpdateReferenceList(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	name := chi.URLParam(r, "name")
+
+	var req referenceListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	list := &rules.ReferenceList{
+		Name:    name,
+		Kind:    req.Kind,
+		Strings: req.Strings,
+		Numbers: req.Numbers,
+		Ranges:  req.Ranges,
+	}
+
+	if err := engine.UpdateReferenceList(list); err != nil {
+		respondError(w, http.StatusBadRequest, "failed to update list", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// Delete reference list handler
+// Deletion fails while rules or derived fields still reference the list
+func (s *Server) handleDeleteReferenceList(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	name := chi.URLParam(r, "name")
+
+	engine, err := s.engineManager.GetEngine(tenantID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "tenant not found", err)
+		return
+	}
+
+	if err := engine.DeleteReferenceList(name); err != nil {
+		respondError(w, http.StatusBadRequest, "failed to delete list", err)
 		return
 	}
 
@@ -813,7 +2265,9 @@ func main() {
 	}
 
 	// Create server
-	server, err := NewServer(databaseURL)
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	defer stopListening()
+	server, err := NewServer(listenCtx, databaseURL)
 	if err != nil {
 		logger.Fatal("Failed to create server", "error", err)
 	}
@@ -854,5 +2308,10 @@ func main() {
 		logger.Error("Server shutdown error", "error", err)
 	}
 
+	// Keep the rule stats recorded since the last flush
+	if err := server.engineManager.FlushStats(); err != nil {
+		logger.Error("Failed to flush rule stats", "error", err)
+	}
+
 	logger.Info("Server stopped")
 }
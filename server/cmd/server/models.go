@@ -26,6 +26,25 @@ type TenantsListResponse struct {
 	Tenants []TenantResponse `json:"tenants"`
 } // @name TenantsListResponse
 
+// TenantSettingsRequest represents a tenant's evaluation settings
+type TenantSettingsRequest struct {
+	RuleTimeoutMs    int64 `json:"ruleTimeoutMs" example:"50"`
+	RequestTimeoutMs int64 `json:"requestTimeoutMs" example:"500"`
+	Concurrency      int   `json:"concurrency" example:"4"`
+	MaxBatchSize     int   `json:"maxBatchSize" example:"1000"`
+	MaxRuleCost      int64 `json:"maxRuleCost" example:"100000"`
+} // @name TenantSettingsRequest
+
+// EngineOptionsRequest represents the options a tenant's engine is built with
+type EngineOptionsRequest struct {
+	CostLimit            uint64   `json:"costLimit" example:"1000000"`
+	DisableStateTracking bool     `json:"disableStateTracking" example:"false"`
+	CacheTTLMs           int64    `json:"cacheTtlMs" example:"0"`
+	Extensions           []string `json:"extensions" example:"strings,math"`
+	EvaluationMode       string   `json:"evaluationMode" example:"first-match"`
+	MaxMatches           int      `json:"maxMatches" example:"0"`
+} // @name EngineOptionsRequest
+
 // CreateSchemaRequest represents the request body for creating a schema
 type CreateSchemaRequest struct {
 	Definition multitenantengine.Schema `json:"definition" binding:"required"`
@@ -33,23 +52,31 @@ type CreateSchemaRequest struct {
 
 // SchemaResponse represents a schema in API responses
 type SchemaResponse struct {
-	Version    int                       `json:"version" example:"1"`
-	Status     string                    `json:"status" example:"active"`
-	Definition multitenantengine.Schema  `json:"definition"`
-	CreatedAt  *time.Time                `json:"created_at,omitempty" example:"2024-01-15T10:30:00Z"`
+	Version    int                      `json:"version" example:"1"`
+	Status     string                   `json:"status" example:"active"`
+	Definition multitenantengine.Schema `json:"definition"`
+	CreatedAt  *time.Time               `json:"created_at,omitempty" example:"2024-01-15T10:30:00Z"`
 } // @name SchemaResponse
 
 // CreateRuleRequest represents the request body for creating a rule
 type CreateRuleRequest struct {
-	Name       string `json:"name" example:"Adult User Check" binding:"required"`
-	Expression string `json:"expression" example:"User.Age >= 18" binding:"required"`
+	Name       string   `json:"name" example:"Adult User Check" binding:"required"`
+	Expression string   `json:"expression" example:"User.Age >= 18" binding:"required"`
+	Priority   int      `json:"priority,omitempty" example:"10"`
+	OutputType string   `json:"outputType,omitempty" example:"bool"`
+	Tags       []string `json:"tags,omitempty" example:"kyc,onboarding"`
+	RuleSets   []string `json:"ruleSets,omitempty" example:"checkout"`
 } // @name CreateRuleRequest
 
 // UpdateRuleRequest represents the request body for updating a rule
 type UpdateRuleRequest struct {
-	Name       string `json:"name" example:"Adult User Check"`
-	Expression string `json:"expression" example:"User.Age >= 18"`
-	Active     *bool  `json:"active,omitempty" example:"true"`
+	Name       string   `json:"name" example:"Adult User Check"`
+	Expression string   `json:"expression" example:"User.Age >= 18"`
+	Active     *bool    `json:"active,omitempty" example:"true"`
+	Priority   int      `json:"priority,omitempty" example:"10"`
+	OutputType string   `json:"outputType,omitempty" example:"bool"`
+	Tags       []string `json:"tags,omitempty" example:"kyc,onboarding"`
+	RuleSets   []string `json:"ruleSets,omitempty" example:"checkout"`
 } // @name UpdateRuleRequest
 
 // RuleResponse represents a rule in API responses
@@ -58,6 +85,11 @@ type RuleResponse struct {
 	Name       string    `json:"name" example:"Adult User Check"`
 	Expression string    `json:"expression" example:"User.Age >= 18"`
 	Active     bool      `json:"active" example:"true"`
+	Priority   int       `json:"priority" example:"10"`
+	OutputType string    `json:"outputType,omitempty" example:"bool"`
+	Tags       []string  `json:"tags" example:"kyc,onboarding"`
+	RuleSets   []string  `json:"ruleSets" example:"checkout"`
+	Version    int       `json:"version" example:"1"`
 	CreatedAt  time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
 	UpdatedAt  time.Time `json:"updated_at" example:"2024-01-15T10:30:00Z"`
 } // @name RuleResponse
@@ -67,28 +99,123 @@ type RulesListResponse struct {
 	Rules []RuleResponse `json:"rules"`
 } // @name RulesListResponse
 
+// CreateDerivedFieldRequest represents the request body for creating a derived field
+type CreateDerivedFieldRequest struct {
+	Name       string `json:"name" example:"isAdult" binding:"required"`
+	Expression string `json:"expression" example:"User.Age >= 18" binding:"required"`
+} // @name CreateDerivedFieldRequest
+
+// UpdateDerivedFieldRequest represents the request body for updating a derived field
+type UpdateDerivedFieldRequest struct {
+	Expression string `json:"expression" example:"User.Age >= 21" binding:"required"`
+} // @name UpdateDerivedFieldRequest
+
+// DerivedFieldResponse represents a derived field in API responses
+type DerivedFieldResponse struct {
+	Name         string    `json:"Name" example:"isAdult"`
+	Expression   string    `json:"Expression" example:"User.Age >= 18"`
+	Dependencies []string  `json:"Dependencies"`
+	CreatedAt    time.Time `json:"CreatedAt" example:"2024-01-15T10:30:00Z"`
+} // @name DerivedFieldResponse
+
 // EvaluateRequest represents the request body for evaluating rules
 type EvaluateRequest struct {
-	TenantID string                 `json:"tenantId" example:"123e4567-e89b-12d3-a456-426614174000" binding:"required"`
-	Facts    map[string]interface{} `json:"facts" binding:"required"`
-	Rules    []string               `json:"rules,omitempty" example:"rule-123,rule-456"`
+	TenantID   string                 `json:"tenantId" example:"123e4567-e89b-12d3-a456-426614174000" binding:"required"`
+	Facts      map[string]interface{} `json:"facts" binding:"required"`
+	Rules      []string               `json:"rules,omitempty" example:"rule-123,rule-456"`
+	Mode       string                 `json:"mode,omitempty" example:"first-match" enums:"all,first-match,stop-after-N-matches"`
+	MaxMatches int                    `json:"maxMatches,omitempty" example:"2"`
+	Tags       []string               `json:"tags,omitempty" example:"kyc"`
+	RuleSet    string                 `json:"ruleSet,omitempty" example:"checkout"`
+	Explain    bool                   `json:"explain,omitempty" example:"true"`
 } // @name EvaluateRequest
 
 // EvaluationResultResponse represents a single rule evaluation result
 type EvaluationResultResponse struct {
-	RuleID   string                 `json:"RuleID" example:"rule-123"`
-	RuleName string                 `json:"RuleName" example:"Adult User Check"`
-	Matched  bool                   `json:"Matched" example:"true"`
-	Error    *string                `json:"Error,omitempty"`
-	Trace    map[string]interface{} `json:"Trace,omitempty"`
+	RuleID      string                 `json:"RuleID" example:"rule-123"`
+	RuleName    string                 `json:"RuleName" example:"Adult User Check"`
+	Matched     bool                   `json:"Matched" example:"true"`
+	Output      interface{}            `json:"Output,omitempty"`
+	Error       *string                `json:"Error,omitempty"`
+	TimedOut    bool                   `json:"TimedOut" example:"false"`
+	Trace       map[string]interface{} `json:"Trace,omitempty"`
+	Explanation *ExplanationResponse   `json:"Explanation,omitempty"`
 } // @name EvaluationResultResponse
 
+// ExplanationResponse describes how one sub-expression of a rule evaluated
+type ExplanationResponse struct {
+	Expression     string                 `json:"Expression" example:"Transaction.Amount > 1000.0"`
+	Value          interface{}            `json:"Value"`
+	Evaluated      bool                   `json:"Evaluated" example:"true"`
+	ShortCircuited bool                   `json:"ShortCircuited" example:"false"`
+	Error          string                 `json:"Error" example:""`
+	Summary        string                 `json:"Summary" example:"Transaction.Amount (1500.5) > 1000.0 → true"`
+	Children       []*ExplanationResponse `json:"Children"`
+} // @name ExplanationResponse
+
 // EvaluateResponse represents the response for rule evaluation
 type EvaluateResponse struct {
 	Results        []EvaluationResultResponse `json:"results"`
 	EvaluationTime string                     `json:"evaluationTime" example:"2.3ms"`
 } // @name EvaluateResponse
 
+// BatchItemRequest represents one fact set in a batch evaluation
+type BatchItemRequest struct {
+	ID    string                 `json:"id,omitempty" example:"customer-42"`
+	Facts map[string]interface{} `json:"facts" binding:"required"`
+} // @name BatchItemRequest
+
+// BatchEvaluateRequest represents the request body for batch evaluation
+type BatchEvaluateRequest struct {
+	TenantID   string             `json:"tenantId" example:"123e4567-e89b-12d3-a456-426614174000" binding:"required"`
+	Items      []BatchItemRequest `json:"items" binding:"required"`
+	Mode       string             `json:"mode,omitempty" example:"all" enums:"all,first-match,stop-after-N-matches"`
+	MaxMatches int                `json:"maxMatches,omitempty" example:"3"`
+	Tags       []string           `json:"tags,omitempty" example:"kyc"`
+	RuleSet    string             `json:"ruleSet,omitempty" example:"checkout"`
+	Explain    bool               `json:"explain,omitempty" example:"false"`
+} // @name BatchEvaluateRequest
+
+// BatchItemResponse represents the results for one fact set in a batch
+type BatchItemResponse struct {
+	ID      string                     `json:"id,omitempty" example:"customer-42"`
+	Index   int                        `json:"index" example:"0"`
+	Results []EvaluationResultResponse `json:"results"`
+	Error   string                     `json:"error,omitempty"`
+} // @name BatchItemResponse
+
+// BatchEvaluateResponse represents the response from batch evaluation
+type BatchEvaluateResponse struct {
+	Items          []BatchItemResponse `json:"items"`
+	EvaluationTime string              `json:"evaluationTime" example:"120ms"`
+} // @name BatchEvaluateResponse
+
+// PartialEvaluateRequest represents the request body for partial evaluation
+type PartialEvaluateRequest struct {
+	TenantID string                 `json:"tenantId" example:"123e4567-e89b-12d3-a456-426614174000" binding:"required"`
+	Facts    map[string]interface{} `json:"facts"`
+	Tags     []string               `json:"tags,omitempty" example:"kyc"`
+	RuleSet  string                 `json:"ruleSet,omitempty" example:"onboarding"`
+} // @name PartialEvaluateRequest
+
+// PartialResultResponse represents the partial evaluation of a single rule
+type PartialResultResponse struct {
+	RuleID            string      `json:"ruleId" example:"rule-123"`
+	RuleName          string      `json:"ruleName" example:"Adult CA"`
+	Decided           bool        `json:"decided" example:"false"`
+	Matched           bool        `json:"matched" example:"false"`
+	Output            interface{} `json:"output,omitempty"`
+	Residual          string      `json:"residual,omitempty" example:"User.Country == \"CA\""`
+	MissingAttributes []string    `json:"missingAttributes,omitempty" example:"User.Country"`
+	Error             string      `json:"error,omitempty"`
+} // @name PartialResultResponse
+
+// PartialEvaluateResponse represents the response from partial evaluation
+type PartialEvaluateResponse struct {
+	Results        []PartialResultResponse `json:"results"`
+	EvaluationTime string                  `json:"evaluationTime" example:"1.1ms"`
+} // @name PartialEvaluateResponse
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error string `json:"error" example:"validation failed: schema cannot be empty"`
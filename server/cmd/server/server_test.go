@@ -11,6 +11,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -69,14 +70,21 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	// Run migrations
-	migrationSQL, err := os.ReadFile("../../migrations/000001_initial_schema.up.sql")
-	if err != nil {
-		t.Fatalf("Failed to read migration file: %v", err)
+	// Run migrations in version order
+	migrationFiles, err := filepath.Glob(filepath.Join("..", "..", "migrations", "*.up.sql"))
+	if err != nil || len(migrationFiles) == 0 {
+		t.Fatalf("Failed to find migration files: %v", err)
 	}
 
-	if _, err := db.Exec(string(migrationSQL)); err != nil {
-		t.Fatalf("Failed to run migrations: %v", err)
+	for _, file := range migrationFiles {
+		migrationSQL, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read migration file %s: %v", file, err)
+		}
+
+		if _, err := db.Exec(string(migrationSQL)); err != nil {
+			t.Fatalf("Failed to run migration %s: %v", file, err)
+		}
 	}
 
 	cleanup := func() {
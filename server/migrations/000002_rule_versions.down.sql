@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rule_versions;
+
+ALTER TABLE rules DROP COLUMN IF EXISTS version;
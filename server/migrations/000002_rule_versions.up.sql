@@ -0,0 +1,23 @@
+-- Current revision number of each rule
+ALTER TABLE rules ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Rule Versions (immutable revision history)
+CREATE TABLE rule_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_id UUID NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    expression TEXT NOT NULL,
+    active BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_rule_version UNIQUE(rule_id, version)
+);
+
+CREATE INDEX idx_rule_versions_tenant_rule ON rule_versions(tenant_id, rule_id, version DESC);
+
+-- Existing rules start their history at version 1
+INSERT INTO rule_versions (rule_id, tenant_id, version, name, expression, active, created_at)
+SELECT id, tenant_id, 1, name, expression, active, updated_at
+FROM rules;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_rules_tenant_priority;
+
+ALTER TABLE rule_versions DROP COLUMN IF EXISTS priority;
+ALTER TABLE rules DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,5 @@
+-- Rule priority: higher priorities are evaluated first
+ALTER TABLE rules ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE rule_versions ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_rules_tenant_priority ON rules(tenant_id, priority DESC, created_at ASC) WHERE active = true;
@@ -0,0 +1,2 @@
+ALTER TABLE rule_versions DROP COLUMN IF EXISTS output_type;
+ALTER TABLE rules DROP COLUMN IF EXISTS output_type;
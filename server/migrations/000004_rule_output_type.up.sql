@@ -0,0 +1,4 @@
+-- Optional declared output type for rules returning decision payloads
+-- An empty string means the rule is a plain boolean condition
+ALTER TABLE rules ADD COLUMN output_type VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE rule_versions ADD COLUMN output_type VARCHAR(50) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE tenants DROP COLUMN IF EXISTS request_timeout_ms;
+ALTER TABLE tenants DROP COLUMN IF EXISTS rule_timeout_ms;
@@ -0,0 +1,4 @@
+-- Per-tenant evaluation time budgets in milliseconds
+-- Zero means no limit
+ALTER TABLE tenants ADD COLUMN rule_timeout_ms INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tenants ADD COLUMN request_timeout_ms INTEGER NOT NULL DEFAULT 0;
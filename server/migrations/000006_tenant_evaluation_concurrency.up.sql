@@ -0,0 +1,3 @@
+-- Number of workers used to evaluate a tenant's rules in parallel
+-- 0 or 1 evaluates rules sequentially
+ALTER TABLE tenants ADD COLUMN evaluation_concurrency INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Largest number of fact sets accepted by a tenant's batch evaluation
+-- 0 uses the engine default
+ALTER TABLE tenants ADD COLUMN max_batch_size INTEGER NOT NULL DEFAULT 0;
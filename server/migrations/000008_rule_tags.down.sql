@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_rules_rule_sets;
+DROP INDEX IF EXISTS idx_rules_tags;
+
+ALTER TABLE rule_versions DROP COLUMN IF EXISTS rule_sets;
+ALTER TABLE rule_versions DROP COLUMN IF EXISTS tags;
+ALTER TABLE rules DROP COLUMN IF EXISTS rule_sets;
+ALTER TABLE rules DROP COLUMN IF EXISTS tags;
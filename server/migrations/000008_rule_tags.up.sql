@@ -0,0 +1,8 @@
+-- Tags and named rule sets used to select rules for evaluation
+ALTER TABLE rules ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE rules ADD COLUMN rule_sets TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE rule_versions ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE rule_versions ADD COLUMN rule_sets TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_rules_tags ON rules USING GIN(tags);
+CREATE INDEX idx_rules_rule_sets ON rules USING GIN(rule_sets);
@@ -0,0 +1,16 @@
+-- Rule test cases: example facts with the expected outcome of a rule
+CREATE TABLE rule_test_cases (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    rule_id UUID NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    facts JSONB NOT NULL,
+    expected_matched BOOLEAN,
+    expected_output JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT rule_test_case_expectation CHECK (expected_matched IS NOT NULL OR expected_output IS NOT NULL)
+);
+
+CREATE INDEX idx_rule_test_cases_tenant_rule ON rule_test_cases(tenant_id, rule_id, created_at);
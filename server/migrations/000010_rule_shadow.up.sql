@@ -0,0 +1,3 @@
+-- Shadow rules are evaluated for stats only and never reported
+ALTER TABLE rules ADD COLUMN shadow BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE rule_versions ADD COLUMN shadow BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+ALTER TABLE rule_versions DROP COLUMN IF EXISTS effective_until;
+ALTER TABLE rule_versions DROP COLUMN IF EXISTS effective_from;
+
+ALTER TABLE rules DROP CONSTRAINT IF EXISTS rule_effective_window;
+ALTER TABLE rules DROP COLUMN IF EXISTS effective_until;
+ALTER TABLE rules DROP COLUMN IF EXISTS effective_from;
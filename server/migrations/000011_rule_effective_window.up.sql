@@ -0,0 +1,10 @@
+-- Optional window during which an active rule is evaluated
+-- effective_from is inclusive, effective_until exclusive; NULL leaves that side open
+-- Stored with time zone so a window opens at the same instant on every replica
+ALTER TABLE rules ADD COLUMN effective_from TIMESTAMP WITH TIME ZONE;
+ALTER TABLE rules ADD COLUMN effective_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE rules ADD CONSTRAINT rule_effective_window
+    CHECK (effective_from IS NULL OR effective_until IS NULL OR effective_until > effective_from);
+
+ALTER TABLE rule_versions ADD COLUMN effective_from TIMESTAMP WITH TIME ZONE;
+ALTER TABLE rule_versions ADD COLUMN effective_until TIMESTAMP WITH TIME ZONE;
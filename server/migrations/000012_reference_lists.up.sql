@@ -0,0 +1,15 @@
+-- Reference lists: named sets of strings, numbers or numeric ranges that
+-- rules reference as lists.<name>
+CREATE TABLE reference_lists (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('strings', 'numbers', 'ranges')),
+    items JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_tenant_list_name UNIQUE(tenant_id, name)
+);
+
+CREATE INDEX idx_reference_lists_tenant ON reference_lists(tenant_id);
@@ -0,0 +1,3 @@
+-- Largest estimated worst-case cost, in CEL cost units, of a tenant's rules
+-- 0 means no limit
+ALTER TABLE tenants ADD COLUMN max_rule_cost BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Engine options of a tenant, e.g. {"costLimit": 50000, "extensions": ["strings"]}
+-- An empty object uses the engine defaults
+ALTER TABLE tenants ADD COLUMN engine_options JSONB NOT NULL DEFAULT '{}';
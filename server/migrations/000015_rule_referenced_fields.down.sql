@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_rules_referenced_fields;
+ALTER TABLE rules DROP COLUMN IF EXISTS referenced_fields;
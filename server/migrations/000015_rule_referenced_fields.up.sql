@@ -0,0 +1,5 @@
+-- Variable and field paths each rule's expression reads, e.g. {"User.Age"}
+-- Rules created before this migration are filled in when next updated
+ALTER TABLE rules ADD COLUMN referenced_fields TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_rules_referenced_fields ON rules USING GIN(referenced_fields);
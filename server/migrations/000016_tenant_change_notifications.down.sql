@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS notify_rule_test_cases_change ON rule_test_cases;
+DROP TRIGGER IF EXISTS notify_reference_lists_change ON reference_lists;
+DROP TRIGGER IF EXISTS notify_derived_fields_change ON derived_fields;
+DROP TRIGGER IF EXISTS notify_rules_change ON rules;
+DROP TRIGGER IF EXISTS notify_schemas_change ON schemas;
+DROP TRIGGER IF EXISTS notify_tenants_change ON tenants;
+
+DROP FUNCTION IF EXISTS notify_tenant_change();
@@ -0,0 +1,45 @@
+-- Publish a notification on the tenant_changes channel whenever a tenant, its
+-- schemas, rules, derived fields, reference lists or rule test cases change,
+-- so every server replica can bring its engine for the tenant up to date
+-- Payload: {"tenantId": "...", "source": "<table name>", "origin": "..."}
+-- origin is the rules.replica_id setting of the connection that made the
+-- change, letting the replica that made it skip it, or null when unset
+-- Notifications are sent when the transaction commits, and identical payloads
+-- within one transaction are delivered once
+CREATE OR REPLACE FUNCTION notify_tenant_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed JSONB;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := to_jsonb(OLD);
+    ELSE
+        changed := to_jsonb(NEW);
+    END IF;
+
+    PERFORM pg_notify('tenant_changes', json_build_object(
+        'tenantId', CASE WHEN TG_TABLE_NAME = 'tenants' THEN changed->>'id' ELSE changed->>'tenant_id' END,
+        'source', TG_TABLE_NAME,
+        'origin', current_setting('rules.replica_id', true)
+    )::text);
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER notify_tenants_change AFTER INSERT OR UPDATE OR DELETE ON tenants
+    FOR EACH ROW EXECUTE FUNCTION notify_tenant_change();
+
+CREATE TRIGGER notify_schemas_change AFTER INSERT OR UPDATE OR DELETE ON schemas
+    FOR EACH ROW EXECUTE FUNCTION notify_tenant_change();
+
+CREATE TRIGGER notify_rules_change AFTER INSERT OR UPDATE OR DELETE ON rules
+    FOR EACH ROW EXECUTE FUNCTION notify_tenant_change();
+
+CREATE TRIGGER notify_derived_fields_change AFTER INSERT OR UPDATE OR DELETE ON derived_fields
+    FOR EACH ROW EXECUTE FUNCTION notify_tenant_change();
+
+CREATE TRIGGER notify_reference_lists_change AFTER INSERT OR UPDATE OR DELETE ON reference_lists
+    FOR EACH ROW EXECUTE FUNCTION notify_tenant_change();
+
+CREATE TRIGGER notify_rule_test_cases_change AFTER INSERT OR UPDATE OR DELETE ON rule_test_cases
+    FOR EACH ROW EXECUTE FUNCTION notify_tenant_change();
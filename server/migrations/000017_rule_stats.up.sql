@@ -0,0 +1,18 @@
+-- Evaluation stats of each rule, added to by every server replica so shadow
+-- rules can be judged on all the traffic they have seen
+-- Replicas add the counts they have recorded every few seconds
+CREATE TABLE rule_stats (
+    rule_id UUID PRIMARY KEY REFERENCES rules(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    evaluations BIGINT NOT NULL DEFAULT 0,
+    matches BIGINT NOT NULL DEFAULT 0,
+    errors BIGINT NOT NULL DEFAULT 0,
+    timed_out BIGINT NOT NULL DEFAULT 0,
+    skipped BIGINT NOT NULL DEFAULT 0,
+    total_latency_ns BIGINT NOT NULL DEFAULT 0,
+    max_latency_ns BIGINT NOT NULL DEFAULT 0,
+    last_evaluated TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_rule_stats_tenant ON rule_stats(tenant_id);
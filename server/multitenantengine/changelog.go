@@ -0,0 +1,143 @@
+package multitenantengine
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SchemaChangeType is the outcome of a schema update recorded in the changelog
+type SchemaChangeType string
+
+const (
+	// SchemaChangeApplied means the new schema broke no rules and is active
+	SchemaChangeApplied SchemaChangeType = "applied"
+	// SchemaChangeForced means the new schema is active and the active rules it
+	// broke were deactivated
+	SchemaChangeForced SchemaChangeType = "forced"
+	// SchemaChangeRejected means the new schema would have broken rules and
+	// was not applied
+	SchemaChangeRejected SchemaChangeType = "rejected"
+	// SchemaChangeFailed means the update failed and was rolled back
+	SchemaChangeFailed SchemaChangeType = "failed"
+)
+
+// SchemaChange is an entry of a tenant's schema changelog
+// A rejected or failed change refers to the schema that stayed active.
+type SchemaChange struct {
+	ID              string
+	SchemaID        string
+	SchemaVersion   int
+	ChangeType      SchemaChangeType
+	RulesRecompiled int // active rules compiled by the new engine
+	RulesFailed     int // rules the new schema breaks
+	Details         *SchemaChangeDetails
+	CreatedAt       time.Time
+}
+
+// SchemaChangeDetails explains why rules failed or the change was not
+// applied, stored as error_details
+type SchemaChangeDetails struct {
+	Error string       `json:"error,omitempty"`
+	Rules []FailedRule `json:"rules,omitempty"`
+}
+
+// FailedRule is a rule broken by a schema change
+type FailedRule struct {
+	RuleID   string `json:"ruleId"`
+	RuleName string `json:"ruleName"`
+	Active   bool   `json:"active"`
+	Error    string `json:"error,omitempty"`
+}
+
+// newSchemaChangeDetails collects the rules a report says break, and err
+// when the change was not applied
+// It returns nil when there is nothing to explain.
+func newSchemaChangeDetails(report *SchemaChangeReport, err error) *SchemaChangeDetails {
+	details := &SchemaChangeDetails{}
+	if err != nil {
+		details.Error = err.Error()
+	}
+	if report != nil {
+		for _, impact := range report.Breaking() {
+			failed := FailedRule{RuleID: impact.RuleID, RuleName: impact.RuleName, Active: impact.Active}
+			if impact.Err != nil {
+				failed.Error = impact.Err.Error()
+			}
+			details.Rules = append(details.Rules, failed)
+		}
+	}
+	if details.Error == "" && len(details.Rules) == 0 {
+		return nil
+	}
+	return details
+}
+
+// sqlExecer is implemented by both *sql.DB and *sql.Tx
+type sqlExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// recordSchemaChange adds an entry to a tenant's schema changelog for the
+// schema with the given ID, or for its active schema when schemaID is empty
+func recordSchemaChange(db sqlExecer, tenantID, schemaID string, change SchemaChange) error {
+	var details []byte
+	if change.Details != nil {
+		var err error
+		details, err = json.Marshal(change.Details)
+		if err != nil {
+			return fmt.Errorf("failed to marshal change details: %w", err)
+		}
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO schema_changelog (tenant_id, schema_id, change_type, rules_recompiled, rules_failed, error_details)
+		SELECT $1, id, $3, $4, $5, $6
+		FROM schemas
+		WHERE tenant_id = $1 AND (id::text = $2 OR ($2 = '' AND active = true))
+	`, tenantID, schemaID, string(change.ChangeType), change.RulesRecompiled, change.RulesFailed, details)
+	if err != nil {
+		return fmt.Errorf("failed to record schema change: %w", err)
+	}
+	return nil
+}
+
+// SchemaChangelog returns a tenant's schema changes, newest first
+func (m *MultiTenantEngineManager) SchemaChangelog(tenantID string) ([]SchemaChange, error) {
+	rows, err := m.db.Query(`
+		SELECT c.id, c.schema_id, s.version, c.change_type, COALESCE(c.rules_recompiled, 0),
+			COALESCE(c.rules_failed, 0), c.error_details, c.created_at
+		FROM schema_changelog c
+		JOIN schemas s ON s.id = c.schema_id
+		WHERE c.tenant_id = $1
+		ORDER BY c.created_at DESC
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema changelog: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []SchemaChange{}
+	for rows.Next() {
+		var change SchemaChange
+		var details []byte
+		if err := rows.Scan(&change.ID, &change.SchemaID, &change.SchemaVersion, &change.ChangeType,
+			&change.RulesRecompiled, &change.RulesFailed, &details, &change.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema change: %w", err)
+		}
+		if details != nil {
+			change.Details = &SchemaChangeDetails{}
+			if err := json.Unmarshal(details, change.Details); err != nil {
+				return nil, fmt.Errorf("invalid details for schema change %s: %w", change.ID, err)
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schema changelog: %w", err)
+	}
+
+	return changes, nil
+}
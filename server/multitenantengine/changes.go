@@ -0,0 +1,343 @@
+package multitenantengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ChangeChannel is the Postgres notification channel database triggers
+// publish tenant changes on; see migration 000016
+const ChangeChannel = "tenant_changes"
+
+// replicaSetting is the Postgres setting a replica's connections carry its ID
+// in, so the notifications its own changes cause name it as their origin
+const replicaSetting = "rules.replica_id"
+
+const (
+	// listenerPingInterval is how often the change listener checks its
+	// connection is alive
+	listenerPingInterval = 90 * time.Second
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+)
+
+// errNeedsRebuild is returned for changes a loaded engine cannot apply in
+// place, so the engine is rebuilt instead
+var errNeedsRebuild = errors.New("change needs the engine rebuilt")
+
+// ChangeEvent is a change to a tenant's stored state, made by any replica
+type ChangeEvent struct {
+	TenantID string `json:"tenantId"`
+	Source   string `json:"source"`           // the table that changed, e.g. "rules" or "schemas"
+	Origin   string `json:"origin,omitempty"` // the replica that made the change, if known
+}
+
+// rebuilds reports whether the change needs the tenant's engine rebuilt
+// Changes to rules, reference lists, derived fields, test cases and settings
+// are applied to the loaded engine in place.
+func (e ChangeEvent) rebuilds() bool {
+	switch e.Source {
+	case "rules", "reference_lists", "derived_fields", "rule_test_cases", "tenants":
+		return false
+	}
+	return true
+}
+
+// ReplicaConnString returns connStr, either a URL or key/value connection
+// string, with every connection it opens carrying replicaID
+// The change notifications caused through those connections name replicaID
+// as their origin, so ListenForChanges can skip them on the replica that made
+// the change.
+func ReplicaConnString(connStr, replicaID string) (string, error) {
+	if strings.HasPrefix(connStr, "postgres://") || strings.HasPrefix(connStr, "postgresql://") {
+		parsed, err := pq.ParseURL(connStr)
+		if err != nil {
+			return "", fmt.Errorf("invalid database URL: %w", err)
+		}
+		connStr = parsed
+	}
+	return fmt.Sprintf("%s %s=%s", connStr, replicaSetting, replicaID), nil
+}
+
+// parseChangeEvent decodes the payload of a change notification
+func parseChangeEvent(payload string) (ChangeEvent, error) {
+	var event ChangeEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return ChangeEvent{}, fmt.Errorf("invalid change notification %q: %w", payload, err)
+	}
+	if event.TenantID == "" {
+		return ChangeEvent{}, fmt.Errorf("invalid change notification %q: missing tenantId", payload)
+	}
+	return event, nil
+}
+
+// mergeChangeEvent adds event to the pending events of its tenant, once per
+// source
+// A change that rebuilds the tenant's engine supersedes all the others.
+func mergeChangeEvent(pending map[string][]ChangeEvent, event ChangeEvent) {
+	events := pending[event.TenantID]
+	if len(events) > 0 && events[0].rebuilds() {
+		return
+	}
+	if event.rebuilds() {
+		pending[event.TenantID] = []ChangeEvent{event}
+		return
+	}
+	for _, existing := range events {
+		if existing.Source == event.Source {
+			return
+		}
+	}
+	pending[event.TenantID] = append(events, event)
+}
+
+// ApplyChange brings a tenant's engine up to date with a change to its stored
+// state
+// Changes to rules, reference lists and derived fields are applied to the
+// loaded engine in place, as when they are made through it, and settings are
+// applied to it as with UpdateTenantSettings; if that fails, e.g. because a
+// changed rule does not compile, the engine is left as it was and the error
+// returned. Any other change, new engine options, or a change to a tenant
+// that is not loaded rebuilds the engine from the tenant's active schema and
+// swaps it in; a tenant without one, e.g. because it was deleted, is dropped.
+func (m *MultiTenantEngineManager) ApplyChange(event ChangeEvent) error {
+	m.mu.RLock()
+	te, exists := m.engines[event.TenantID]
+	m.mu.RUnlock()
+
+	if exists && !event.rebuilds() {
+		err := m.applyChangeInPlace(te, event)
+		if !errors.Is(err, errNeedsRebuild) {
+			return err
+		}
+	}
+
+	return m.reloadTenant(event.TenantID)
+}
+
+// applyChangeInPlace applies a change that does not need the tenant's engine
+// rebuilt to its loaded engine
+func (m *MultiTenantEngineManager) applyChangeInPlace(te *TenantEngine, event ChangeEvent) error {
+	switch event.Source {
+	case "rules":
+		return te.Engine.CompileAllRules()
+	case "reference_lists":
+		return te.Engine.ReloadReferenceLists()
+	case "derived_fields":
+		return te.Engine.ReloadDerivedFields()
+	case "rule_test_cases":
+		// Test cases are read from the store whenever they run
+		return nil
+	case "tenants":
+		return m.reloadTenantSettings(te)
+	}
+	return fmt.Errorf("a change to %s: %w", event.Source, errNeedsRebuild)
+}
+
+// reloadTenantSettings applies a tenant's stored settings to its loaded engine
+// Engine options are fixed for an engine's lifetime, so changed options are
+// reported as errNeedsRebuild.
+func (m *MultiTenantEngineManager) reloadTenantSettings(te *TenantEngine) error {
+	options, err := m.GetEngineOptions(te.TenantID)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(options, te.Engine.Options()) {
+		return fmt.Errorf("engine options of tenant %s changed: %w", te.TenantID, errNeedsRebuild)
+	}
+
+	settings, err := m.GetTenantSettings(te.TenantID)
+	if err != nil {
+		return err
+	}
+	return applyTenantSettings(te.Engine, settings)
+}
+
+// Resync brings every tenant engine up to date with the database: tenants
+// with an active schema are rebuilt and swapped in, and the engines of
+// tenants without one are dropped
+// It is used when changes may have been missed, e.g. while the change
+// listener was disconnected. Tenants that fail to rebuild keep their engine.
+func (m *MultiTenantEngineManager) Resync() error {
+	m.rebuildMu.Lock()
+	defer m.rebuildMu.Unlock()
+
+	schemas, err := m.activeSchemas("")
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for tenantID, schema := range schemas {
+		if err := m.swapTenantEngine(tenantID, schema); err != nil {
+			errs = append(errs, fmt.Errorf("failed to resync tenant %s: %w", tenantID, err))
+		}
+	}
+
+	m.mu.Lock()
+	for tenantID := range m.engines {
+		if _, ok := schemas[tenantID]; !ok {
+			delete(m.engines, tenantID)
+		}
+	}
+	m.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// reloadTenant rebuilds a tenant's engine from its active schema, or drops it
+// when the tenant has none
+func (m *MultiTenantEngineManager) reloadTenant(tenantID string) error {
+	m.rebuildMu.Lock()
+	defer m.rebuildMu.Unlock()
+
+	schemas, err := m.activeSchemas(tenantID)
+	if err != nil {
+		return err
+	}
+
+	schema, ok := schemas[tenantID]
+	if !ok {
+		m.mu.Lock()
+		delete(m.engines, tenantID)
+		m.mu.Unlock()
+		return nil
+	}
+
+	return m.swapTenantEngine(tenantID, schema)
+}
+
+// swapTenantEngine builds a tenant's engine from schema and its stored state
+// and swaps it in
+// Rule changes made meanwhile wait for the swap and go to the new engine.
+// Callers must hold m.rebuildMu.
+func (m *MultiTenantEngineManager) swapTenantEngine(tenantID string, schema Schema) error {
+	defer m.pauseTenantWrites(tenantID)()
+
+	engine, err := m.newTenantEngine(tenantID, schema)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.setTenantEngine(&TenantEngine{
+		TenantID: tenantID,
+		Schema:   schema,
+		Engine:   engine,
+	})
+	m.mu.Unlock()
+
+	return nil
+}
+
+// ListenForChanges starts applying the tenant changes every replica publishes,
+// so engines pick up rules, schemas and settings changed elsewhere, and keeps
+// applying them in the background until ctx is done
+// Changes are received over Postgres LISTEN/NOTIFY on a dedicated connection
+// opened from connStr. It returns once listening, so tenants loaded afterwards
+// cannot miss a change: call it before LoadAllTenants. Changes whose origin is
+// replicaID were applied by this replica when it made them and are skipped;
+// see ReplicaConnString. Engines are resynced whenever the connection is
+// re-established, since notifications sent while it was down are lost. Errors
+// applying changes, and connection errors, are passed to onError, which may
+// be called concurrently; the listener keeps running after them.
+func (m *MultiTenantEngineManager) ListenForChanges(ctx context.Context, connStr, replicaID string, onError func(error)) error {
+	report := func(err error) {
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+
+	listener := pq.NewListener(connStr, minReconnectInterval, maxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			report(fmt.Errorf("change listener connection: %w", err))
+		}
+	})
+	stop := context.AfterFunc(ctx, func() {
+		listener.Close()
+	})
+
+	if err := listener.Listen(ChangeChannel); err != nil {
+		stop()
+		listener.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to listen for tenant changes: %w", err)
+	}
+
+	go func() {
+		defer listener.Close()
+		defer stop()
+		m.applyChanges(ctx, listener, replicaID, report)
+	}()
+
+	return nil
+}
+
+// applyChanges applies the changes received by listener until ctx is done
+func (m *MultiTenantEngineManager) applyChanges(ctx context.Context, listener *pq.Listener, replicaID string, report func(error)) {
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case notification, ok := <-listener.Notify:
+			if !ok {
+				return
+			}
+			if notification == nil {
+				// Reconnected; notifications sent while disconnected were lost
+				report(m.Resync())
+				continue
+			}
+
+			// Apply the changes that arrived together once per tenant and source
+			pending := make(map[string][]ChangeEvent)
+			resync := false
+			for notification != nil {
+				event, err := parseChangeEvent(notification.Extra)
+				if err != nil {
+					report(err)
+				} else if replicaID == "" || event.Origin != replicaID {
+					mergeChangeEvent(pending, event)
+				}
+
+				notification = nil
+				select {
+				case next, ok := <-listener.Notify:
+					if ok && next == nil {
+						resync = true
+					}
+					notification = next
+				default:
+				}
+			}
+
+			if resync {
+				report(m.Resync())
+				continue
+			}
+			for _, events := range pending {
+				for _, event := range events {
+					report(m.ApplyChange(event))
+				}
+			}
+
+		case <-ping.C:
+			go func() {
+				report(listener.Ping())
+			}()
+		}
+	}
+}
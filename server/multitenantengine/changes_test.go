@@ -0,0 +1,85 @@
+package multitenantengine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseChangeEvent verifies change notifications are decoded, and
+// malformed ones rejected
+func TestParseChangeEvent(t *testing.T) {
+	event, err := parseChangeEvent(`{"tenantId": "t1", "source": "rules", "origin": "r1"}`)
+	if err != nil {
+		t.Fatalf("parseChangeEvent() failed: %v", err)
+	}
+	if event != (ChangeEvent{TenantID: "t1", Source: "rules", Origin: "r1"}) || event.rebuilds() {
+		t.Errorf("parseChangeEvent() = %+v", event)
+	}
+
+	// Changes made outside any replica have no origin
+	event, err = parseChangeEvent(`{"tenantId": "t1", "source": "schemas", "origin": null}`)
+	if err != nil {
+		t.Fatalf("parseChangeEvent() failed: %v", err)
+	}
+	if event != (ChangeEvent{TenantID: "t1", Source: "schemas"}) || !event.rebuilds() {
+		t.Errorf("parseChangeEvent() = %+v", event)
+	}
+
+	for _, payload := range []string{``, `not json`, `{"source": "rules"}`} {
+		if _, err := parseChangeEvent(payload); err == nil {
+			t.Errorf("parseChangeEvent(%q) should fail", payload)
+		}
+	}
+}
+
+// TestMergeChangeEvent verifies changes are merged per tenant and source, and
+// a change that rebuilds the tenant supersedes the others
+func TestMergeChangeEvent(t *testing.T) {
+	pending := make(map[string][]ChangeEvent)
+	for _, event := range []ChangeEvent{
+		{TenantID: "t1", Source: "rules"},
+		{TenantID: "t1", Source: "schemas"},
+		{TenantID: "t1", Source: "rules"},
+		{TenantID: "t2", Source: "rules"},
+		{TenantID: "t2", Source: "reference_lists"},
+		{TenantID: "t2", Source: "rules"},
+		{TenantID: "t3", Source: "tenants"},
+	} {
+		mergeChangeEvent(pending, event)
+	}
+
+	want := map[string][]ChangeEvent{
+		"t1": {{TenantID: "t1", Source: "schemas"}},
+		"t2": {{TenantID: "t2", Source: "rules"}, {TenantID: "t2", Source: "reference_lists"}},
+		"t3": {{TenantID: "t3", Source: "tenants"}},
+	}
+	if !reflect.DeepEqual(pending, want) {
+		t.Errorf("merged events = %+v, want %+v", pending, want)
+	}
+}
+
+// TestReplicaConnString verifies the replica ID is added to URL and key/value
+// connection strings
+func TestReplicaConnString(t *testing.T) {
+	testCases := []struct {
+		connStr string
+		want    string
+	}{
+		{"postgres://user@localhost:5432/rules?sslmode=disable", "dbname='rules' host='localhost' port='5432' sslmode='disable' user='user' rules.replica_id=abc"},
+		{"host=localhost dbname=rules", "host=localhost dbname=rules rules.replica_id=abc"},
+	}
+
+	for _, tc := range testCases {
+		got, err := ReplicaConnString(tc.connStr, "abc")
+		if err != nil {
+			t.Fatalf("ReplicaConnString(%q) failed: %v", tc.connStr, err)
+		}
+		if got != tc.want {
+			t.Errorf("ReplicaConnString(%q) = %q, want %q", tc.connStr, got, tc.want)
+		}
+	}
+
+	if _, err := ReplicaConnString("postgres://%zz", "abc"); err == nil {
+		t.Error("ReplicaConnString() should fail for an invalid URL")
+	}
+}
@@ -0,0 +1,96 @@
+package multitenantengine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/liamcoop/rules/rules"
+)
+
+// SchemaFieldUsage lists the rules and derived fields that read a schema field
+// A field read by neither is unused by the tenant's rules.
+type SchemaFieldUsage struct {
+	Object        string
+	Field         string
+	Type          string
+	Rules         []string // rule IDs, active or not
+	DerivedFields []string // derived field names
+}
+
+// Path returns the field's path as rules read it, e.g. "User.Age"
+func (u SchemaFieldUsage) Path() string {
+	return u.Object + "." + u.Field
+}
+
+// FieldUsage reports, for every field of a tenant's schema, which of its rules
+// and derived fields read it, ordered by object and field name
+func (m *MultiTenantEngineManager) FieldUsage(tenantID string) ([]SchemaFieldUsage, error) {
+	m.mu.RLock()
+	te, exists := m.engines[tenantID]
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("tenant %s not found", tenantID)
+	}
+
+	rulesList, err := rules.NewPostgresRuleStore(m.db, tenantID).List()
+	if err != nil {
+		return nil, err
+	}
+
+	return schemaFieldUsage(te.Schema, te.Engine.FieldUsage(rulesList)), nil
+}
+
+// schemaFieldUsage matches the paths read by rules against the fields of a
+// schema
+// A path below a field, e.g. User.Address.City, reads User.Address; a whole
+// object, e.g. User passed to a function, reads each of its fields.
+func schemaFieldUsage(schema Schema, usage []*rules.FieldUsage) []SchemaFieldUsage {
+	objects := make([]string, 0, len(schema))
+	for object := range schema {
+		objects = append(objects, object)
+	}
+	sort.Strings(objects)
+
+	var result []SchemaFieldUsage
+	for _, object := range objects {
+		fields := make([]string, 0, len(schema[object]))
+		for field := range schema[object] {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			entry := SchemaFieldUsage{Object: object, Field: field, Type: schema[object][field]}
+			path := entry.Path()
+			ruleIDs := make(map[string]bool)
+			derived := make(map[string]bool)
+			for _, u := range usage {
+				if u.Path != object && u.Path != path && !strings.HasPrefix(u.Path, path+".") {
+					continue
+				}
+				for _, id := range u.Rules {
+					ruleIDs[id] = true
+				}
+				for _, name := range u.DerivedFields {
+					derived[name] = true
+				}
+			}
+			entry.Rules = sortedKeys(ruleIDs)
+			entry.DerivedFields = sortedKeys(derived)
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// sortedKeys returns the keys of set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,41 @@
+package multitenantengine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/liamcoop/rules/rules"
+)
+
+// TestSchemaFieldUsage verifies paths read by rules are matched to schema
+// fields, and fields no rule reads are reported as unused
+func TestSchemaFieldUsage(t *testing.T) {
+	schema := Schema{
+		"User": {
+			"Age":     "int",
+			"Country": "string",
+			"Email":   "string",
+		},
+		"Transaction": {
+			"Amount": "float64",
+			"Tags":   "[]string",
+		},
+	}
+	usage := []*rules.FieldUsage{
+		{Path: "Transaction", Rules: []string{"r3"}},
+		{Path: "User.Age", Rules: []string{"r1", "r2"}, DerivedFields: []string{"isAdult"}},
+		{Path: "User.Country", Rules: []string{"r2"}},
+	}
+
+	got := schemaFieldUsage(schema, usage)
+	want := []SchemaFieldUsage{
+		{Object: "Transaction", Field: "Amount", Type: "float64", Rules: []string{"r3"}, DerivedFields: []string{}},
+		{Object: "Transaction", Field: "Tags", Type: "[]string", Rules: []string{"r3"}, DerivedFields: []string{}},
+		{Object: "User", Field: "Age", Type: "int", Rules: []string{"r1", "r2"}, DerivedFields: []string{"isAdult"}},
+		{Object: "User", Field: "Country", Type: "string", Rules: []string{"r2"}, DerivedFields: []string{}},
+		{Object: "User", Field: "Email", Type: "string", Rules: []string{}, DerivedFields: []string{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("schemaFieldUsage() = %+v\nwant %+v", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package multitenantengine
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -24,9 +25,10 @@ type TenantEngine struct {
 
 // MultiTenantEngineManager manages engines for all tenants
 type MultiTenantEngineManager struct {
-	engines map[string]*TenantEngine
-	db      *sql.DB
-	mu      sync.RWMutex
+	engines   map[string]*TenantEngine
+	db        *sql.DB
+	mu        sync.RWMutex
+	rebuildMu sync.Mutex // serializes engine rebuilds so none is swapped in stale
 }
 
 // NewMultiTenantEngineManager creates a new manager instance
@@ -38,11 +40,39 @@ func NewMultiTenantEngineManager(db *sql.DB) *MultiTenantEngineManager {
 }
 
 // CreateCELEnvFromSchema creates a CEL environment with variables defined by the schema
+// Each top-level object is declared as a CEL object type whose fields carry the
+// schema types, so misspelled fields and type mismatches fail at compile time
 // Satisfies REQ-SEC-002: Creates secure CEL environment with restricted features
 func CreateCELEnvFromSchema(schema Schema) (*cel.Env, error) {
+	provider, err := newSchemaTypeProvider(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []cel.EnvOption{
+		cel.CustomTypeProvider(provider),
+		// Facts arrive as JSON, so numbers compare across int/double boundaries
+		cel.CrossTypeNumericComparisons(true),
+	}
+	for objectName := range schema {
+		opts = append(opts, cel.Variable(objectName, cel.ObjectType(schemaTypeName(objectName))))
+	}
+
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	return env, nil
+}
+
+// CreateLegacyCELEnvFromSchema creates a CEL environment that declares every
+// top-level object as a dynamic type, matching environments built before
+// schema types were enforced. It is only used as a fallback so that stored
+// rules which no longer type-check keep loading.
+func CreateLegacyCELEnvFromSchema(schema Schema) (*cel.Env, error) {
 	var opts []cel.EnvOption
 
-	// Create a CEL variable for each top-level object in the schema
 	// Using DynType allows flexible runtime type checking
 	for objectName := range schema {
 		opts = append(opts, cel.Variable(objectName, cel.DynType))
@@ -50,7 +80,7 @@ func CreateCELEnvFromSchema(schema Schema) (*cel.Env, error) {
 
 	env, err := cel.NewEnv(opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+		return nil, fmt.Errorf("failed to create legacy CEL environment: %w", err)
 	}
 
 	return env, nil
@@ -58,72 +88,177 @@ func CreateCELEnvFromSchema(schema Schema) (*cel.Env, error) {
 
 // LoadAllTenants loads all tenants from the database and initializes their engines
 func (m *MultiTenantEngineManager) LoadAllTenants() error {
-	// Fetch all active tenant schemas from database
+	schemas, err := m.activeSchemas("")
+	if err != nil {
+		return err
+	}
+
+	for tenantID, schema := range schemas {
+		if err := m.CreateTenant(tenantID, schema); err != nil {
+			return fmt.Errorf("failed to initialize tenant %s: %w", tenantID, err)
+		}
+	}
+
+	return nil
+}
+
+// activeSchemas fetches the active schema of every tenant, or only of
+// tenantID when it is not empty, keyed by tenant ID
+func (m *MultiTenantEngineManager) activeSchemas(tenantID string) (map[string]Schema, error) {
 	rows, err := m.db.Query(`
 		SELECT t.id, s.definition
 		FROM tenants t
 		JOIN schemas s ON s.tenant_id = t.id
-		WHERE s.active = true
-	`)
+		WHERE s.active = true AND ($1 = '' OR t.id::text = $1)
+	`, tenantID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch tenants: %w", err)
+		return nil, fmt.Errorf("failed to fetch tenants: %w", err)
 	}
 	defer rows.Close()
 
-	tenantsLoaded := 0
+	schemas := make(map[string]Schema)
 	for rows.Next() {
-		var tenantID string
+		var id string
 		var schemaJSON []byte
-		if err := rows.Scan(&tenantID, &schemaJSON); err != nil {
-			return fmt.Errorf("failed to scan tenant row: %w", err)
+		if err := rows.Scan(&id, &schemaJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant row: %w", err)
 		}
 
 		var schema Schema
 		if err := json.Unmarshal(schemaJSON, &schema); err != nil {
-			return fmt.Errorf("invalid schema for tenant %s: %w", tenantID, err)
-		}
-
-		if err := m.CreateTenant(tenantID, schema); err != nil {
-			return fmt.Errorf("failed to initialize tenant %s: %w", tenantID, err)
+			return nil, fmt.Errorf("invalid schema for tenant %s: %w", id, err)
 		}
-
-		tenantsLoaded++
+		schemas[id] = schema
 	}
 
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating tenant rows: %w", err)
+		return nil, fmt.Errorf("error iterating tenant rows: %w", err)
 	}
 
-	return nil
+	return schemas, nil
 }
 
-// CreateTenant creates a new tenant engine with the given schema
-func (m *MultiTenantEngineManager) CreateTenant(tenantID string, schema Schema) error {
+// newTenantEngine builds an engine for a tenant from its schema, stored
+// settings and stored engine options
+func (m *MultiTenantEngineManager) newTenantEngine(tenantID string, schema Schema) (*rules.Engine, error) {
+	options, err := m.GetEngineOptions(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return m.newTenantEngineWithOptions(tenantID, schema, options)
+}
+
+// newTenantEngineWithOptions builds an engine for a tenant from its schema and
+// stored settings with the given engine options
+func (m *MultiTenantEngineManager) newTenantEngineWithOptions(tenantID string, schema Schema, options rules.EngineOptions) (*rules.Engine, error) {
+	// Create a custom RuleStore that filters by tenant
+	return m.newTenantEngineWithStore(tenantID, schema, options, rules.NewPostgresRuleStore(m.db, tenantID))
+}
+
+// newTenantEngineWithStore builds an engine for a tenant over the given rule
+// store, with its other stores, settings and the given engine options
+func (m *MultiTenantEngineManager) newTenantEngineWithStore(tenantID string, schema Schema, options rules.EngineOptions, store rules.RuleStore) (*rules.Engine, error) {
 	// Create CEL environment from schema
 	env, err := CreateCELEnvFromSchema(schema)
 	if err != nil {
-		return fmt.Errorf("failed to create CEL env: %w", err)
+		return nil, fmt.Errorf("failed to create CEL env: %w", err)
 	}
 
-	// Create a custom RuleStore that filters by tenant
-	store := rules.NewPostgresRuleStore(m.db, tenantID)
+	legacyEnv, err := CreateLegacyCELEnvFromSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create legacy CEL env: %w", err)
+	}
+
+	settings, err := m.GetTenantSettings(tenantID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create the engine using the schema-specific environment
-	engine, err := rules.NewEngineWithEnv(env, store)
+	// Stored rules that predate typed schemas fall back to the legacy environment
+	engine, err := rules.NewEngineWithConfig(rules.EngineConfig{
+		Env:          env,
+		FallbackEnv:  legacyEnv,
+		Store:        store,
+		DerivedStore: rules.NewPostgresDerivedFieldStore(m.db, tenantID),
+		ListStore:    rules.NewPostgresReferenceListStore(m.db, tenantID),
+		TestStore:    rules.NewPostgresRuleTestStore(m.db, tenantID),
+		StatsStore:   rules.NewPostgresRuleStatsStore(m.db, tenantID),
+		Limits:       settings.evaluationLimits(),
+		Concurrency:  settings.Concurrency,
+		MaxBatchSize: settings.MaxBatchSize,
+		MaxCost:      uint64(settings.MaxRuleCost),
+		Options:      options,
+		ConvertFacts: schemaFactConverter(schema),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create engine: %w", err)
+		return nil, fmt.Errorf("failed to create engine: %w", err)
 	}
 
-	// Store in cache
-	m.mu.Lock()
-	m.engines[tenantID] = &TenantEngine{
-		TenantID: tenantID,
-		Schema:   schema,
-		Engine:   engine,
+	return engine, nil
+}
+
+// CreateTenant creates a new tenant engine with the given schema
+func (m *MultiTenantEngineManager) CreateTenant(tenantID string, schema Schema) error {
+	m.rebuildMu.Lock()
+	defer m.rebuildMu.Unlock()
+
+	return m.swapTenantEngine(tenantID, schema)
+}
+
+// setTenantEngine stores te as its tenant's engine, handing over the rule
+// stats of the engine it replaces so none are lost
+// Callers must hold m.mu.
+func (m *MultiTenantEngineManager) setTenantEngine(te *TenantEngine) {
+	if previous, ok := m.engines[te.TenantID]; ok {
+		te.Engine.InheritStats(previous.Engine)
 	}
-	m.mu.Unlock()
+	m.engines[te.TenantID] = te
+}
 
-	return nil
+// pauseTenantWrites blocks rule, list, derived field and test case changes
+// on a tenant's loaded engine while the engine is rebuilt, so none are lost
+// with the old engine; see rules.Engine.PauseWrites
+// The returned resume applies them to whichever engine serves the tenant by
+// then. Callers must hold m.rebuildMu.
+func (m *MultiTenantEngineManager) pauseTenantWrites(tenantID string) (resume func()) {
+	m.mu.RLock()
+	te, exists := m.engines[tenantID]
+	m.mu.RUnlock()
+	if !exists {
+		return func() {}
+	}
+
+	resumeWrites := te.Engine.PauseWrites()
+	return func() {
+		m.mu.RLock()
+		current, ok := m.engines[tenantID]
+		m.mu.RUnlock()
+		if ok {
+			resumeWrites(current.Engine)
+		} else {
+			resumeWrites(nil)
+		}
+	}
+}
+
+// FlushStats writes the rule stats every engine has recorded since its last
+// flush to the database, e.g. before the server stops
+func (m *MultiTenantEngineManager) FlushStats() error {
+	m.mu.RLock()
+	engines := make([]*TenantEngine, 0, len(m.engines))
+	for _, te := range m.engines {
+		engines = append(engines, te)
+	}
+	m.mu.RUnlock()
+
+	var errs []error
+	for _, te := range engines {
+		if err := te.Engine.FlushStats(); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", te.TenantID, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // GetEngine retrieves the engine for a specific tenant
@@ -141,75 +276,201 @@ func (m *MultiTenantEngineManager) GetEngine(tenantID string) (*rules.Engine, er
 
 // UpdateTenantSchema updates a tenant's schema and recompiles all rules
 // This operation is zero-downtime: creates new engine and atomically swaps it
+// The schema is stored in a transaction committed only once the new engine
+// builds, and the outcome is recorded in the schema changelog.
+// A schema that would break rules is refused with a *SchemaChangeError
+// carrying the impact on each rule; see ForceUpdateTenantSchema.
 func (m *MultiTenantEngineManager) UpdateTenantSchema(tenantID string, newSchema Schema) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	_, err := m.updateTenantSchema(tenantID, newSchema, false)
+	return err
+}
 
+// ForceUpdateTenantSchema updates a tenant's schema even if it breaks rules,
+// deactivating the active rules that no longer compile
+// The returned report says which rules were affected.
+func (m *MultiTenantEngineManager) ForceUpdateTenantSchema(tenantID string, newSchema Schema) (*SchemaChangeReport, error) {
+	return m.updateTenantSchema(tenantID, newSchema, true)
+}
+
+func (m *MultiTenantEngineManager) updateTenantSchema(tenantID string, newSchema Schema, force bool) (*SchemaChangeReport, error) {
+	m.rebuildMu.Lock()
+	defer m.rebuildMu.Unlock()
+
+	m.mu.RLock()
 	existingEngine, exists := m.engines[tenantID]
+	m.mu.RUnlock()
+
 	if !exists {
-		m.mu.Unlock()
-		defer m.mu.Lock()
-		return m.CreateTenant(tenantID, newSchema)
+		return &SchemaChangeReport{}, m.swapTenantEngine(tenantID, newSchema)
 	}
 
-	// Step 1: Save new schema to database
-	_, err := m.db.Exec(`
+	// Rule changes wait for the update so none are checked against only the
+	// old schema
+	defer m.pauseTenantWrites(tenantID)()
+
+	// Step 1: Check the rules against the new schema before anything is stored
+	report, err := m.checkSchemaChange(existingEngine, newSchema)
+	if err != nil {
+		m.recordFailedSchemaChange(tenantID, SchemaChangeFailed, nil, err)
+		return nil, err
+	}
+	if len(report.Breaking()) > 0 && !force {
+		err := &SchemaChangeError{Report: report}
+		m.recordFailedSchemaChange(tenantID, SchemaChangeRejected, report, err)
+		return report, err
+	}
+
+	// Steps 2-4 run in one transaction, committed once the new engine builds
+	newEngine, err := m.applySchemaChange(tenantID, newSchema, report)
+	if err != nil {
+		m.recordFailedSchemaChange(tenantID, SchemaChangeFailed, report, err)
+		return nil, err
+	}
+
+	// Step 5: Atomically swap the engine, unless the tenant was removed meanwhile
+	m.mu.Lock()
+	if _, ok := m.engines[tenantID]; ok {
+		m.setTenantEngine(&TenantEngine{
+			TenantID: tenantID,
+			Schema:   newSchema,
+			Engine:   newEngine,
+		})
+	}
+	m.mu.Unlock()
+
+	return report, nil
+}
+
+// applySchemaChange stores a new schema, deactivates the active rules report
+// says it breaks and records the change, committing only once an engine has
+// been built from the result
+func (m *MultiTenantEngineManager) applySchemaChange(tenantID string, newSchema Schema, report *SchemaChangeReport) (*rules.Engine, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Step 2: Save new schema to database
+	_, err = tx.Exec(`
 		UPDATE schemas
 		SET active = false
 		WHERE tenant_id = $1
 	`, tenantID)
 	if err != nil {
-		return fmt.Errorf("failed to deactivate old schemas: %w", err)
+		return nil, fmt.Errorf("failed to deactivate old schemas: %w", err)
 	}
 
 	schemaJSON, err := json.Marshal(newSchema)
 	if err != nil {
-		return fmt.Errorf("failed to marshal schema: %w", err)
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
 	}
 
-	var newVersion int
-	err = m.db.QueryRow(`
+	var schemaID string
+	err = tx.QueryRow(`
 		INSERT INTO schemas (tenant_id, version, definition, active, created_at)
 		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, true, NOW()
 		FROM schemas
 		WHERE tenant_id = $1
-		RETURNING version
-	`, tenantID, schemaJSON).Scan(&newVersion)
+		RETURNING id
+	`, tenantID, schemaJSON).Scan(&schemaID)
 	if err != nil {
-		return fmt.Errorf("failed to save new schema: %w", err)
+		return nil, fmt.Errorf("failed to save new schema: %w", err)
 	}
 
-	// Step 2: Create new CEL environment
-	env, err := CreateCELEnvFromSchema(newSchema)
-	if err != nil {
-		return fmt.Errorf("failed to create new CEL env: %w", err)
+	// Step 3: Deactivate the rules the new schema breaks
+	store := rules.NewPostgresRuleStore(m.db, tenantID)
+	for _, impact := range report.Breaking() {
+		if !impact.Active {
+			continue
+		}
+		rule, err := store.GetInTx(tx, impact.RuleID)
+		if err != nil {
+			return nil, err
+		}
+		rule.Active = false
+		if err := store.UpdateInTx(tx, rule); err != nil {
+			return nil, fmt.Errorf("failed to deactivate rule %s: %w", impact.RuleID, err)
+		}
 	}
 
-	// Step 3: Create new Engine instance
-	store := rules.NewPostgresRuleStore(m.db, tenantID)
-	newEngine, err := rules.NewEngineWithEnv(env, store)
+	// Step 4: Create new CEL environment and Engine instance over the rules
+	// as they will be once the transaction commits
+	active, err := store.ListActiveInTx(tx)
 	if err != nil {
-		return fmt.Errorf("failed to create new engine: %w", err)
+		return nil, fmt.Errorf("failed to load rules: %w", err)
 	}
+	staged := &stagedRuleStore{PostgresRuleStore: store, staging: true, active: active}
 
-	// Step 4: Get compilation stats
-	activeRules, err := store.ListActive()
+	options, err := m.GetEngineOptions(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	newEngine, err := m.newTenantEngineWithStore(tenantID, newSchema, options, staged)
 	if err != nil {
-		return fmt.Errorf("failed to load rules: %w", err)
+		return nil, fmt.Errorf("failed to create new engine: %w", err)
 	}
 
-	// Step 5: Atomically swap the engine
-	m.engines[tenantID] = &TenantEngine{
-		TenantID: tenantID,
-		Schema:   newSchema,
-		Engine:   newEngine,
+	change := SchemaChange{
+		ChangeType:      SchemaChangeApplied,
+		RulesRecompiled: len(staged.active),
+		RulesFailed:     len(report.Breaking()),
+		Details:         newSchemaChangeDetails(report, nil),
+	}
+	if change.RulesFailed > 0 {
+		change.ChangeType = SchemaChangeForced
+	}
+	if err := recordSchemaChange(tx, tenantID, schemaID, change); err != nil {
+		return nil, err
 	}
 
-	// Log success (in production, use structured logging)
-	_ = existingEngine // Keep reference to avoid breaking change detection
-	_ = activeRules
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit schema change: %w", err)
+	}
+	staged.commit()
 
-	return nil
+	return newEngine, nil
+}
+
+// recordFailedSchemaChange records a schema change that was not applied
+// against the tenant's active schema
+// The change has already failed, so an error recording it is dropped.
+func (m *MultiTenantEngineManager) recordFailedSchemaChange(tenantID string, changeType SchemaChangeType, report *SchemaChangeReport, err error) {
+	change := SchemaChange{ChangeType: changeType, Details: newSchemaChangeDetails(report, err)}
+	if report != nil {
+		change.RulesFailed = len(report.Breaking())
+	}
+	_ = recordSchemaChange(m.db, tenantID, "", change)
+}
+
+// stagedRuleStore is a tenant's rule store whose active rules are staged by a
+// schema change that has not committed yet, so the new engine can be built
+// before it commits
+type stagedRuleStore struct {
+	*rules.PostgresRuleStore
+	mu      sync.Mutex
+	staging bool
+	active  []*rules.Rule
+}
+
+// ListActive returns the staged rules until the change commits
+func (s *stagedRuleStore) ListActive() ([]*rules.Rule, error) {
+	s.mu.Lock()
+	staging, active := s.staging, s.active
+	s.mu.Unlock()
+
+	if staging {
+		return active, nil
+	}
+	return s.PostgresRuleStore.ListActive()
+}
+
+// commit switches the store over to the database once the change commits
+func (s *stagedRuleStore) commit() {
+	s.mu.Lock()
+	s.staging = false
+	s.active = nil
+	s.mu.Unlock()
 }
 
 // ListTenants returns all loaded tenant IDs
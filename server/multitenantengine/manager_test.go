@@ -6,14 +6,16 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 
@@ -22,6 +24,13 @@ import (
 
 // setupTestDB creates a PostgreSQL testcontainer and runs migrations
 func setupTestDB(t *testing.T) (*sql.DB, func()) {
+	db, _, cleanup := setupTestDBWithConnStr(t)
+	return db, cleanup
+}
+
+// setupTestDBWithConnStr is setupTestDB that also returns the connection
+// string, for opening further connections
+func setupTestDBWithConnStr(t *testing.T) (*sql.DB, string, func()) {
 	ctx := context.Background()
 
 	req := testcontainers.ContainerRequest{
@@ -69,14 +78,21 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	// Run migrations
-	migrationSQL, err := os.ReadFile("../migrations/000001_initial_schema.up.sql")
-	if err != nil {
-		t.Fatalf("Failed to read migration file: %v", err)
+	// Run migrations in version order
+	migrationFiles, err := filepath.Glob(filepath.Join("..", "migrations", "*.up.sql"))
+	if err != nil || len(migrationFiles) == 0 {
+		t.Fatalf("Failed to find migration files: %v", err)
 	}
 
-	if _, err := db.Exec(string(migrationSQL)); err != nil {
-		t.Fatalf("Failed to run migrations: %v", err)
+	for _, file := range migrationFiles {
+		migrationSQL, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read migration file %s: %v", file, err)
+		}
+
+		if _, err := db.Exec(string(migrationSQL)); err != nil {
+			t.Fatalf("Failed to run migration %s: %v", file, err)
+		}
 	}
 
 	cleanup := func() {
@@ -84,7 +100,7 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 		postgres.Terminate(ctx)
 	}
 
-	return db, cleanup
+	return db, connStr, cleanup
 }
 
 // createTenantWithSchema creates a tenant and schema in the database
@@ -392,6 +408,137 @@ func TestMultiTenantEngineManager_UpdateTenantSchema(t *testing.T) {
 	}
 }
 
+// TestMultiTenantEngineManager_SchemaChange verifies schema changes that break
+// rules are reported by a dry run and refused unless forced
+func TestMultiTenantEngineManager_SchemaChange(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := uuid.New().String()
+	schema := Schema{"User": {"Age": "int", "Country": "string"}}
+	createTenantWithSchema(t, db, tenantID, schema)
+
+	manager := NewMultiTenantEngineManager(db)
+	if err := manager.CreateTenant(tenantID, schema); err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	engine, _ := manager.GetEngine(tenantID)
+
+	adult := &rules.Rule{ID: uuid.New().String(), Name: "adult", Expression: "User.Age >= 18", Active: true}
+	canadian := &rules.Rule{ID: uuid.New().String(), Name: "canadian", Expression: `User.Country == "CA"`, Active: true}
+	for _, rule := range []*rules.Rule{adult, canadian} {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("Failed to add rule %s: %v", rule.Name, err)
+		}
+	}
+
+	// Dropping Country breaks the canadian rule only
+	newSchema := Schema{"User": {"Age": "int"}}
+	report, err := manager.CheckSchemaChange(tenantID, newSchema)
+	if err != nil {
+		t.Fatalf("Failed to check schema change: %v", err)
+	}
+	breaking := report.Breaking()
+	if len(report.Rules) != 2 || len(breaking) != 1 || breaking[0].RuleID != canadian.ID {
+		t.Fatalf("Expected only the canadian rule to break, got %+v", report.Rules)
+	}
+	if breaking[0].Before != rules.CompatibilityTyped || breaking[0].After != rules.CompatibilityNone || breaking[0].Err == nil {
+		t.Errorf("Expected canadian to go from typed to none with an error, got %+v", breaking[0])
+	}
+
+	// The dry run stores nothing
+	var version int
+	if err := db.QueryRow(`SELECT version FROM schemas WHERE tenant_id = $1 AND active = true`, tenantID).Scan(&version); err != nil || version != 1 {
+		t.Errorf("Expected schema version 1 to stay active, got %d, %v", version, err)
+	}
+
+	// The update is refused unless forced
+	err = manager.UpdateTenantSchema(tenantID, newSchema)
+	var changeErr *SchemaChangeError
+	if !errors.As(err, &changeErr) || len(changeErr.Report.Breaking()) != 1 {
+		t.Fatalf("Expected a SchemaChangeError, got %v", err)
+	}
+	if current, _ := manager.GetEngine(tenantID); current != engine {
+		t.Error("Expected the engine not to be replaced by a refused update")
+	}
+
+	if _, err := manager.ForceUpdateTenantSchema(tenantID, newSchema); err != nil {
+		t.Fatalf("Failed to force schema update: %v", err)
+	}
+	stored, err := rules.NewPostgresRuleStore(db, tenantID).Get(canadian.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rule: %v", err)
+	}
+	if stored.Active {
+		t.Error("Expected the broken rule to be deactivated")
+	}
+	engine, _ = manager.GetEngine(tenantID)
+	result, err := engine.Evaluate(adult.ID, map[string]any{"User": map[string]any{"Age": 30}})
+	if err != nil || !result.Matched {
+		t.Errorf("Expected the unaffected rule to keep matching, got %+v, %v", result, err)
+	}
+
+	// Both outcomes are in the changelog, newest first
+	changes, err := manager.SchemaChangelog(tenantID)
+	if err != nil {
+		t.Fatalf("Failed to get schema changelog: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changelog entries, got %+v", changes)
+	}
+	forced, rejected := changes[0], changes[1]
+	if forced.ChangeType != SchemaChangeForced || forced.SchemaVersion != 2 || forced.RulesRecompiled != 1 || forced.RulesFailed != 1 {
+		t.Errorf("Expected a forced change to version 2 recompiling 1 rule, got %+v", forced)
+	}
+	if forced.Details == nil || len(forced.Details.Rules) != 1 || forced.Details.Rules[0].RuleID != canadian.ID {
+		t.Errorf("Expected the forced change to list the canadian rule, got %+v", forced.Details)
+	}
+	if rejected.ChangeType != SchemaChangeRejected || rejected.SchemaVersion != 1 || rejected.Details == nil || rejected.Details.Error == "" {
+		t.Errorf("Expected a rejected change against version 1 with an error, got %+v", rejected)
+	}
+}
+
+// TestMultiTenantEngineManager_SchemaChangeRollback verifies a schema whose
+// engine cannot be built leaves the old schema and engine in place
+func TestMultiTenantEngineManager_SchemaChangeRollback(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := uuid.New().String()
+	schema := Schema{"User": {"Age": "int"}}
+	createTenantWithSchema(t, db, tenantID, schema)
+
+	manager := NewMultiTenantEngineManager(db)
+	if err := manager.CreateTenant(tenantID, schema); err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	engine, _ := manager.GetEngine(tenantID)
+	if err := engine.AddDerivedField(&rules.DerivedField{Name: "isAdult", Expression: "User.Age >= 18"}); err != nil {
+		t.Fatalf("Failed to add derived field: %v", err)
+	}
+
+	// The derived field no longer compiles, so even a forced update fails
+	if _, err := manager.ForceUpdateTenantSchema(tenantID, Schema{"User": {"Name": "string"}}); err == nil {
+		t.Fatal("Expected the update to fail")
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT version FROM schemas WHERE tenant_id = $1 AND active = true`, tenantID).Scan(&version); err != nil || version != 1 {
+		t.Errorf("Expected schema version 1 to stay active, got %d, %v", version, err)
+	}
+	if current, _ := manager.GetEngine(tenantID); current != engine {
+		t.Error("Expected the old engine to stay in place")
+	}
+
+	changes, err := manager.SchemaChangelog(tenantID)
+	if err != nil {
+		t.Fatalf("Failed to get schema changelog: %v", err)
+	}
+	if len(changes) != 1 || changes[0].ChangeType != SchemaChangeFailed || changes[0].Details == nil || changes[0].Details.Error == "" {
+		t.Errorf("Expected one failed change with an error, got %+v", changes)
+	}
+}
+
 // TestMultiTenantEngineManager_TenantIsolation verifies tenant isolation
 // Maps to: REQ-TENANT-001 (Tenant Isolation), REQ-TENANT-005 (Cross-Tenant Evaluation Prevention)
 func TestMultiTenantEngineManager_TenantIsolation(t *testing.T) {
@@ -645,3 +792,525 @@ func TestMultiTenantEngineManager_UpdateNonexistentTenant(t *testing.T) {
 		t.Error("Engine should not be nil")
 	}
 }
+
+func TestMultiTenantEngineManager_TenantSettings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := uuid.New().String()
+	_, err := db.Exec(`
+		INSERT INTO tenants (id, name, created_at, updated_at)
+		VALUES ($1, $2, NOW(), NOW())
+	`, tenantID, "test-tenant")
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	manager := NewMultiTenantEngineManager(db)
+	schema := Schema{"User": {"Age": "int"}}
+	if err := manager.CreateTenant(tenantID, schema); err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+
+	settings := TenantSettings{
+		RuleTimeout:    50 * time.Millisecond,
+		RequestTimeout: 500 * time.Millisecond,
+		Concurrency:    4,
+		MaxBatchSize:   50,
+		MaxRuleCost:    500,
+	}
+	if err := manager.UpdateTenantSettings(tenantID, settings); err != nil {
+		t.Fatalf("Failed to update tenant settings: %v", err)
+	}
+
+	// Settings are applied to the loaded engine immediately
+	engine, _ := manager.GetEngine(tenantID)
+	limits := engine.EvaluationLimits()
+	if limits.RuleTimeout != settings.RuleTimeout || limits.RequestTimeout != settings.RequestTimeout {
+		t.Errorf("Expected engine limits %+v, got %+v", settings, limits)
+	}
+
+	// Settings persist across engine rebuilds
+	if err := manager.UpdateTenantSchema(tenantID, Schema{"User": {"Age": "int", "Name": "string"}}); err != nil {
+		t.Fatalf("Failed to update schema: %v", err)
+	}
+	engine, _ = manager.GetEngine(tenantID)
+	if got := engine.EvaluationLimits(); got.RuleTimeout != settings.RuleTimeout {
+		t.Errorf("Expected rule timeout %v after rebuild, got %v", settings.RuleTimeout, got.RuleTimeout)
+	}
+	if got := engine.Concurrency(); got != settings.Concurrency {
+		t.Errorf("Expected concurrency %d after rebuild, got %d", settings.Concurrency, got)
+	}
+	if got := engine.MaxBatchSize(); got != settings.MaxBatchSize {
+		t.Errorf("Expected max batch size %d after rebuild, got %d", settings.MaxBatchSize, got)
+	}
+	if got := engine.MaxCost(); got != uint64(settings.MaxRuleCost) {
+		t.Errorf("Expected max rule cost %d after rebuild, got %d", settings.MaxRuleCost, got)
+	}
+
+	if err := manager.UpdateTenantSettings(tenantID, TenantSettings{RuleTimeout: -time.Second}); err == nil {
+		t.Error("Expected negative timeout to be rejected")
+	}
+	if err := manager.UpdateTenantSettings(tenantID, TenantSettings{Concurrency: -1}); err == nil {
+		t.Error("Expected negative concurrency to be rejected")
+	}
+	if err := manager.UpdateTenantSettings(tenantID, TenantSettings{MaxRuleCost: -1}); err == nil {
+		t.Error("Expected negative max rule cost to be rejected")
+	}
+	if err := manager.UpdateTenantSettings(uuid.New().String(), settings); err == nil {
+		t.Error("Expected error updating settings for unknown tenant")
+	}
+}
+
+func TestMultiTenantEngineManager_EngineOptions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := uuid.New().String()
+	schema := Schema{"User": {"Name": "string"}}
+	createTenantWithSchema(t, db, tenantID, schema)
+
+	manager := NewMultiTenantEngineManager(db)
+	if err := manager.CreateTenant(tenantID, schema); err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	oldEngine, _ := manager.GetEngine(tenantID)
+
+	// A rule using an extension library cannot be added before it is enabled
+	rule := &rules.Rule{ID: uuid.New().String(), Name: "shout", Expression: `User.Name.upperAscii() == "ADA"`, Active: true}
+	if err := oldEngine.AddRule(rule); err == nil {
+		t.Fatal("Expected rule using a disabled extension library to be rejected")
+	}
+
+	options := rules.EngineOptions{
+		CostLimit:      5000,
+		CacheTTL:       time.Minute,
+		Extensions:     []string{"strings"},
+		EvaluationMode: rules.EvaluationModeFirstMatch,
+	}
+	if err := manager.UpdateEngineOptions(tenantID, options); err != nil {
+		t.Fatalf("Failed to update engine options: %v", err)
+	}
+
+	// The engine is rebuilt and swapped in; the old one keeps working
+	engine, _ := manager.GetEngine(tenantID)
+	if engine == oldEngine {
+		t.Fatal("Expected the engine to be rebuilt")
+	}
+	if _, err := oldEngine.EvaluateAll(map[string]any{"User": map[string]any{"Name": "Ada"}}); err != nil {
+		t.Errorf("Old engine should keep evaluating: %v", err)
+	}
+	if err := engine.AddRule(rule); err != nil {
+		t.Fatalf("Failed to add rule with the strings library enabled: %v", err)
+	}
+	if got := engine.Options(); got.CostLimit != options.CostLimit || got.EvaluationMode != options.EvaluationMode {
+		t.Errorf("Expected engine options %+v, got %+v", options, got)
+	}
+
+	stored, err := manager.GetEngineOptions(tenantID)
+	if err != nil {
+		t.Fatalf("Failed to get engine options: %v", err)
+	}
+	if stored.CacheTTL != options.CacheTTL || len(stored.Extensions) != 1 || stored.Extensions[0] != "strings" {
+		t.Errorf("Expected stored options %+v, got %+v", options, stored)
+	}
+
+	// Options persist across schema rebuilds
+	if err := manager.UpdateTenantSchema(tenantID, Schema{"User": {"Name": "string", "Age": "int"}}); err != nil {
+		t.Fatalf("Failed to update schema: %v", err)
+	}
+	engine, _ = manager.GetEngine(tenantID)
+	result, err := engine.Evaluate(rule.ID, map[string]any{"User": map[string]any{"Name": "Ada"}})
+	if err != nil || !result.Matched {
+		t.Errorf("Expected rule to match after schema rebuild, got %+v, %v", result, err)
+	}
+
+	// Options the stored rules do not compile under are not stored
+	if err := manager.UpdateEngineOptions(tenantID, rules.EngineOptions{}); err == nil {
+		t.Error("Expected disabling a library in use to be rejected")
+	}
+	if stored, _ := manager.GetEngineOptions(tenantID); len(stored.Extensions) != 1 {
+		t.Errorf("Expected rejected options not to be stored, got %+v", stored)
+	}
+
+	if err := manager.UpdateEngineOptions(tenantID, rules.EngineOptions{Extensions: []string{"unknown"}}); err == nil {
+		t.Error("Expected unknown extension library to be rejected")
+	}
+	if err := manager.UpdateEngineOptions(uuid.New().String(), options); err == nil {
+		t.Error("Expected error updating options for unknown tenant")
+	}
+}
+
+// waitFor polls cond until it holds or the timeout passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestMultiTenantEngineManager_ChangeNotifications(t *testing.T) {
+	db, connStr, cleanup := setupTestDBWithConnStr(t)
+	defer cleanup()
+
+	tenantID := uuid.New().String()
+	schema := Schema{"User": {"Age": "int"}}
+	createTenantWithSchema(t, db, tenantID, schema)
+
+	// Two replicas over the same database; only the second listens
+	replicaA := NewMultiTenantEngineManager(db)
+	replicaB := NewMultiTenantEngineManager(db)
+	for _, replica := range []*MultiTenantEngineManager{replicaA, replicaB} {
+		if err := replica.LoadAllTenants(); err != nil {
+			t.Fatalf("Failed to load tenants: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := replicaB.ListenForChanges(ctx, connStr, "", func(err error) {
+		t.Logf("change listener: %v", err)
+	})
+	if err != nil {
+		t.Fatalf("ListenForChanges() failed: %v", err)
+	}
+
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+	engineB := func() *rules.Engine {
+		engine, _ := replicaB.GetEngine(tenantID)
+		return engine
+	}
+
+	// A rule added on one replica is compiled on the other
+	engineA, _ := replicaA.GetEngine(tenantID)
+	rule := &rules.Rule{ID: uuid.New().String(), Name: "adult", Expression: `User.Age >= 18`, Active: true}
+	if err := engineA.AddRule(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	waitFor(t, "the rule on the other replica", func() bool {
+		engine := engineB()
+		if engine == nil {
+			return false
+		}
+		result, err := engine.Evaluate(rule.ID, facts)
+		return err == nil && result.Matched
+	})
+
+	// A schema change swaps the other replica's engine
+	if err := replicaA.UpdateTenantSchema(tenantID, Schema{"User": {"Age": "int", "Name": "string"}}); err != nil {
+		t.Fatalf("Failed to update schema: %v", err)
+	}
+	waitFor(t, "the schema on the other replica", func() bool {
+		replicaB.mu.RLock()
+		defer replicaB.mu.RUnlock()
+		te, ok := replicaB.engines[tenantID]
+		return ok && te.Schema["User"]["Name"] == "string"
+	})
+
+	// Reference list changes are applied to the loaded engine in place
+	inPlace := engineB()
+	engineA, _ = replicaA.GetEngine(tenantID)
+	if err := engineA.AddReferenceList(&rules.ReferenceList{Name: "ages", Kind: rules.ListNumbers, Numbers: []float64{30}}); err != nil {
+		t.Fatalf("Failed to add reference list: %v", err)
+	}
+	waitFor(t, "the reference list on the other replica", func() bool {
+		lists, err := engineB().ReferenceLists()
+		return err == nil && len(lists) == 1
+	})
+	if engineB() != inPlace {
+		t.Error("Expected a reference list change to keep the loaded engine")
+	}
+
+	// Settings changes are applied
+	settings, err := replicaA.GetTenantSettings(tenantID)
+	if err != nil {
+		t.Fatalf("Failed to get settings: %v", err)
+	}
+	settings.Concurrency = 4
+	if err := replicaA.UpdateTenantSettings(tenantID, settings); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	waitFor(t, "the settings on the other replica", func() bool {
+		engine := engineB()
+		return engine != nil && engine.Concurrency() == 4
+	})
+
+	// A deactivated rule stops being evaluated
+	inactive := *rule
+	inactive.Active = false
+	engineA, _ = replicaA.GetEngine(tenantID)
+	if err := engineA.UpdateRule(&inactive); err != nil {
+		t.Fatalf("Failed to deactivate rule: %v", err)
+	}
+	waitFor(t, "the deactivation on the other replica", func() bool {
+		_, err := engineB().Evaluate(rule.ID, facts)
+		return errors.Is(err, rules.ErrRuleNotActive)
+	})
+
+	// A deleted tenant is dropped
+	if _, err := db.Exec(`DELETE FROM tenants WHERE id = $1`, tenantID); err != nil {
+		t.Fatalf("Failed to delete tenant: %v", err)
+	}
+	waitFor(t, "the tenant to be dropped", func() bool {
+		_, err := replicaB.GetEngine(tenantID)
+		return err != nil
+	})
+
+}
+
+func TestMultiTenantEngineManager_ChangesFromSameReplica(t *testing.T) {
+	_, connStr, cleanup := setupTestDBWithConnStr(t)
+	defer cleanup()
+
+	replicaConnStr, err := ReplicaConnString(connStr, "replica-a")
+	if err != nil {
+		t.Fatalf("ReplicaConnString() failed: %v", err)
+	}
+	db, err := sql.Open("postgres", replicaConnStr)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	tenantID := uuid.New().String()
+	schema := Schema{"User": {"Age": "int"}}
+	createTenantWithSchema(t, db, tenantID, schema)
+
+	replica := NewMultiTenantEngineManager(db)
+	if err := replica.LoadAllTenants(); err != nil {
+		t.Fatalf("Failed to load tenants: %v", err)
+	}
+	loaded, _ := replica.GetEngine(tenantID)
+
+	var origins []string
+	var mu sync.Mutex
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listener := pq.NewListener(connStr, minReconnectInterval, maxReconnectInterval, nil)
+	defer listener.Close()
+	if err := listener.Listen(ChangeChannel); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go func() {
+		for notification := range listener.Notify {
+			if event, err := parseChangeEvent(notification.Extra); err == nil {
+				mu.Lock()
+				origins = append(origins, event.Origin)
+				mu.Unlock()
+			}
+		}
+	}()
+	if err := replica.ListenForChanges(ctx, connStr, "replica-a", nil); err != nil {
+		t.Fatalf("ListenForChanges() failed: %v", err)
+	}
+
+	// A change made through the replica's own connections is not reapplied
+	if _, err := db.Exec(`UPDATE schemas SET definition = definition WHERE tenant_id = $1`, tenantID); err != nil {
+		t.Fatalf("Failed to touch schema: %v", err)
+	}
+	waitFor(t, "the change notification", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(origins) > 0
+	})
+	mu.Lock()
+	if origins[0] != "replica-a" {
+		t.Errorf("Expected the notification origin to be replica-a, got %q", origins[0])
+	}
+	mu.Unlock()
+
+	// A later change from elsewhere is applied, so the first was handled
+	otherDB, err := sql.Open("postgres", connStr)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer otherDB.Close()
+	rule := &rules.Rule{ID: uuid.New().String(), Name: "adult", Expression: `User.Age >= 18`, Active: true}
+	if err := rules.NewPostgresRuleStore(otherDB, tenantID).Add(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	waitFor(t, "the rule from elsewhere", func() bool {
+		engine, err := replica.GetEngine(tenantID)
+		if err != nil {
+			return false
+		}
+		result, err := engine.Evaluate(rule.ID, map[string]any{"User": map[string]any{"Age": 30}})
+		return err == nil && result.Matched
+	})
+	if engine, _ := replica.GetEngine(tenantID); engine != loaded {
+		t.Error("Expected a change from the same replica to keep the loaded engine")
+	}
+}
+
+func TestMultiTenantEngineManager_ChangeListenerReconnects(t *testing.T) {
+	db, connStr, cleanup := setupTestDBWithConnStr(t)
+	defer cleanup()
+
+	tenantID := uuid.New().String()
+	schema := Schema{"User": {"Age": "int"}}
+	createTenantWithSchema(t, db, tenantID, schema)
+
+	replica := NewMultiTenantEngineManager(db)
+	if err := replica.LoadAllTenants(); err != nil {
+		t.Fatalf("Failed to load tenants: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := replica.ListenForChanges(ctx, connStr, "", nil); err != nil {
+		t.Fatalf("ListenForChanges() failed: %v", err)
+	}
+
+	var listeners int
+	waitFor(t, "the listener to connect", func() bool {
+		db.QueryRow(`SELECT COUNT(*) FROM pg_stat_activity WHERE query LIKE 'LISTEN%'`).Scan(&listeners)
+		return listeners > 0
+	})
+
+	// Drop the listener's connection, then change rules and add a tenant
+	// directly, as another replica would, while it may still be down
+	if _, err := db.Exec(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE query LIKE 'LISTEN%'`); err != nil {
+		t.Fatalf("Failed to terminate listener connection: %v", err)
+	}
+	rule := &rules.Rule{ID: uuid.New().String(), Name: "adult", Expression: `User.Age >= 18`, Active: true}
+	if err := rules.NewPostgresRuleStore(db, tenantID).Add(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	otherTenantID := uuid.New().String()
+	createTenantWithSchema(t, db, otherTenantID, schema)
+
+	// Reconnecting resyncs every tenant
+	waitFor(t, "the resync after reconnecting", func() bool {
+		engine, err := replica.GetEngine(tenantID)
+		if err != nil {
+			return false
+		}
+		result, err := engine.Evaluate(rule.ID, map[string]any{"User": map[string]any{"Age": 30}})
+		if err != nil || !result.Matched {
+			return false
+		}
+		_, err = replica.GetEngine(otherTenantID)
+		return err == nil
+	})
+}
+
+func TestMultiTenantEngineManager_Resync(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := uuid.New().String()
+	schema := Schema{"User": {"Age": "int"}}
+	createTenantWithSchema(t, db, tenantID, schema)
+
+	manager := NewMultiTenantEngineManager(db)
+	if err := manager.LoadAllTenants(); err != nil {
+		t.Fatalf("Failed to load tenants: %v", err)
+	}
+
+	// Changes made behind the manager's back
+	rule := &rules.Rule{ID: uuid.New().String(), Name: "adult", Expression: `User.Age >= 18`, Active: true}
+	if err := rules.NewPostgresRuleStore(db, tenantID).Add(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	newTenantID := uuid.New().String()
+	createTenantWithSchema(t, db, newTenantID, schema)
+
+	if err := manager.Resync(); err != nil {
+		t.Fatalf("Resync() failed: %v", err)
+	}
+	engine, err := manager.GetEngine(tenantID)
+	if err != nil {
+		t.Fatalf("Failed to get engine: %v", err)
+	}
+	if result, err := engine.Evaluate(rule.ID, map[string]any{"User": map[string]any{"Age": 30}}); err != nil || !result.Matched {
+		t.Errorf("Expected the resynced engine to evaluate the new rule, got %+v, %v", result, err)
+	}
+	if _, err := manager.GetEngine(newTenantID); err != nil {
+		t.Errorf("Expected the new tenant to be loaded: %v", err)
+	}
+
+	if _, err := db.Exec(`DELETE FROM tenants WHERE id = $1`, newTenantID); err != nil {
+		t.Fatalf("Failed to delete tenant: %v", err)
+	}
+	if err := manager.Resync(); err != nil {
+		t.Fatalf("Resync() failed: %v", err)
+	}
+	if _, err := manager.GetEngine(newTenantID); err == nil {
+		t.Error("Expected the deleted tenant to be dropped")
+	}
+}
+
+func TestMultiTenantEngineManager_RuleStatsPersisted(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := uuid.New().String()
+	createTenantWithSchema(t, db, tenantID, Schema{"User": {"Age": "int"}})
+
+	manager := NewMultiTenantEngineManager(db)
+	if err := manager.LoadAllTenants(); err != nil {
+		t.Fatalf("Failed to load tenants: %v", err)
+	}
+	engine, _ := manager.GetEngine(tenantID)
+	rule := &rules.Rule{ID: uuid.New().String(), Name: "newAdult", Expression: `User.Age >= 21`, Active: true, Shadow: true}
+	if err := engine.AddRule(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	evaluations := func(manager *MultiTenantEngineManager) int64 {
+		engine, err := manager.GetEngine(tenantID)
+		if err != nil {
+			t.Fatalf("Failed to get engine: %v", err)
+		}
+		stats, err := engine.RuleStats()
+		if err != nil {
+			t.Fatalf("RuleStats() failed: %v", err)
+		}
+		for _, s := range stats {
+			if s.RuleID == rule.ID {
+				return s.Evaluations
+			}
+		}
+		return 0
+	}
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+	if _, err := engine.EvaluateAll(facts); err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+	waitFor(t, "the shadow evaluation", func() bool {
+		return evaluations(manager) == 1
+	})
+
+	// A rebuilt engine keeps the stats recorded before it
+	if err := manager.UpdateEngineOptions(tenantID, rules.EngineOptions{CacheTTL: time.Minute}); err != nil {
+		t.Fatalf("Failed to update engine options: %v", err)
+	}
+	if got := evaluations(manager); got != 1 {
+		t.Errorf("Expected 1 evaluation after the rebuild, got %d", got)
+	}
+
+	// Flushed stats are seen by another replica
+	if err := manager.FlushStats(); err != nil {
+		t.Fatalf("FlushStats() failed: %v", err)
+	}
+	other := NewMultiTenantEngineManager(db)
+	if err := other.LoadAllTenants(); err != nil {
+		t.Fatalf("Failed to load tenants: %v", err)
+	}
+	if got := evaluations(other); got != 1 {
+		t.Errorf("Expected 1 stored evaluation on another replica, got %d", got)
+	}
+
+	// Stats are dropped with their rule
+	engine, _ = manager.GetEngine(tenantID)
+	if err := engine.DeleteRule(rule.ID); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	if got := evaluations(other); got != 0 {
+		t.Errorf("Expected no stored evaluations after deleting the rule, got %d", got)
+	}
+}
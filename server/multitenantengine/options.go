@@ -0,0 +1,128 @@
+package multitenantengine
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/liamcoop/rules/rules"
+)
+
+// storedEngineOptions is the JSON form of rules.EngineOptions kept in the
+// tenants.engine_options column
+type storedEngineOptions struct {
+	CostLimit            uint64   `json:"costLimit,omitempty"`
+	DisableStateTracking bool     `json:"disableStateTracking,omitempty"`
+	CacheTTLMs           int64    `json:"cacheTtlMs,omitempty"`
+	Extensions           []string `json:"extensions,omitempty"`
+	EvaluationMode       string   `json:"evaluationMode,omitempty"`
+	MaxMatches           int      `json:"maxMatches,omitempty"`
+}
+
+// GetEngineOptions loads a tenant's engine options from the database
+// Tenants without a database row get the default options
+func (m *MultiTenantEngineManager) GetEngineOptions(tenantID string) (rules.EngineOptions, error) {
+	var optionsJSON []byte
+	err := m.db.QueryRow(`
+		SELECT engine_options
+		FROM tenants
+		WHERE id = $1
+	`, tenantID).Scan(&optionsJSON)
+
+	if err == sql.ErrNoRows {
+		return rules.EngineOptions{}, nil
+	}
+	if err != nil {
+		return rules.EngineOptions{}, fmt.Errorf("failed to load engine options: %w", err)
+	}
+
+	var stored storedEngineOptions
+	if err := json.Unmarshal(optionsJSON, &stored); err != nil {
+		return rules.EngineOptions{}, fmt.Errorf("invalid engine options for tenant %s: %w", tenantID, err)
+	}
+
+	return rules.EngineOptions{
+		CostLimit:            stored.CostLimit,
+		DisableStateTracking: stored.DisableStateTracking,
+		CacheTTL:             time.Duration(stored.CacheTTLMs) * time.Millisecond,
+		Extensions:           stored.Extensions,
+		EvaluationMode:       rules.EvaluationMode(stored.EvaluationMode),
+		MaxMatches:           stored.MaxMatches,
+	}, nil
+}
+
+// UpdateEngineOptions stores a tenant's engine options and rebuilds its loaded
+// engine with them
+// Options are fixed for an engine's lifetime, so the new engine is built and
+// compiled while the old one keeps serving, then swapped in. Evaluations
+// already running finish on the old engine, and rule changes wait for the
+// swap and are applied to the new one. If the rules do not compile under
+// the new options, e.g. because they use a disabled extension library, the
+// options are not stored.
+func (m *MultiTenantEngineManager) UpdateEngineOptions(tenantID string, options rules.EngineOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	optionsJSON, err := json.Marshal(storedEngineOptions{
+		CostLimit:            options.CostLimit,
+		DisableStateTracking: options.DisableStateTracking,
+		CacheTTLMs:           options.CacheTTL.Milliseconds(),
+		Extensions:           options.Extensions,
+		EvaluationMode:       string(options.EvaluationMode),
+		MaxMatches:           options.MaxMatches,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal engine options: %w", err)
+	}
+
+	m.rebuildMu.Lock()
+	defer m.rebuildMu.Unlock()
+	defer m.pauseTenantWrites(tenantID)()
+
+	m.mu.RLock()
+	te, exists := m.engines[tenantID]
+	m.mu.RUnlock()
+
+	var newEngine *rules.Engine
+	if exists {
+		newEngine, err = m.newTenantEngineWithOptions(tenantID, te.Schema, options)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild engine: %w", err)
+		}
+	}
+
+	result, err := m.db.Exec(`
+		UPDATE tenants
+		SET engine_options = $1, updated_at = NOW()
+		WHERE id = $2
+	`, optionsJSON, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to update engine options: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("tenant %s not found", tenantID)
+	}
+
+	if !exists {
+		return nil
+	}
+
+	m.mu.Lock()
+	if _, ok := m.engines[tenantID]; ok {
+		m.setTenantEngine(&TenantEngine{
+			TenantID: tenantID,
+			Schema:   te.Schema,
+			Engine:   newEngine,
+		})
+	}
+	m.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package multitenantengine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/liamcoop/rules/rules"
+)
+
+// RuleImpact is how replacing a tenant's schema affects one of its rules
+type RuleImpact struct {
+	RuleID   string
+	RuleName string
+	Active   bool
+	Before   rules.Compatibility // against the current schema
+	After    rules.Compatibility // against the new schema
+	Err      error               // why the rule does not type-check against the new schema
+}
+
+// Breaks reports whether the rule compiles worse against the new schema than
+// against the current one
+func (i RuleImpact) Breaks() bool {
+	return i.After.Worse(i.Before)
+}
+
+// SchemaChangeReport is the impact of replacing a tenant's schema on each of
+// its rules, active or not
+type SchemaChangeReport struct {
+	Rules []RuleImpact
+}
+
+// Breaking returns the rules the change breaks
+func (r *SchemaChangeReport) Breaking() []RuleImpact {
+	var breaking []RuleImpact
+	for _, impact := range r.Rules {
+		if impact.Breaks() {
+			breaking = append(breaking, impact)
+		}
+	}
+	return breaking
+}
+
+// SchemaChangeError is returned by UpdateTenantSchema when the new schema
+// would break rules
+type SchemaChangeError struct {
+	Report *SchemaChangeReport
+}
+
+func (e *SchemaChangeError) Error() string {
+	breaking := e.Report.Breaking()
+	names := make([]string, len(breaking))
+	for i, impact := range breaking {
+		names[i] = impact.RuleName
+	}
+	return fmt.Sprintf("schema change breaks %d rule(s): %s", len(breaking), strings.Join(names, ", "))
+}
+
+// CheckSchemaChange compiles every rule of a tenant against a new schema,
+// without storing anything, and reports how each is affected
+// Derived fields that do not compile against the new schema are returned as
+// an error, since no engine could be built from it.
+func (m *MultiTenantEngineManager) CheckSchemaChange(tenantID string, newSchema Schema) (*SchemaChangeReport, error) {
+	m.mu.RLock()
+	te, exists := m.engines[tenantID]
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("tenant %s not found", tenantID)
+	}
+
+	return m.checkSchemaChange(te, newSchema)
+}
+
+// checkSchemaChange compares how a tenant's rules compile against its current
+// engine and against an engine built from newSchema
+func (m *MultiTenantEngineManager) checkSchemaChange(te *TenantEngine, newSchema Schema) (*SchemaChangeReport, error) {
+	options, err := m.GetEngineOptions(te.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The candidate loads no rules; they are checked against it below
+	candidate, err := m.newTenantEngineWithStore(te.TenantID, newSchema, options, rules.NewInMemoryRuleStore())
+	if err != nil {
+		return nil, fmt.Errorf("new schema cannot be applied: %w", err)
+	}
+
+	rulesList, err := rules.NewPostgresRuleStore(m.db, te.TenantID).List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	before, err := te.Engine.CheckRules(rulesList)
+	if err != nil {
+		return nil, err
+	}
+	after, err := candidate.CheckRules(rulesList)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SchemaChangeReport{Rules: make([]RuleImpact, len(rulesList))}
+	for i, rule := range rulesList {
+		report.Rules[i] = RuleImpact{
+			RuleID:   rule.ID,
+			RuleName: rule.Name,
+			Active:   rule.Active,
+			Before:   before[i].Compatibility,
+			After:    after[i].Compatibility,
+			Err:      after[i].Err,
+		}
+	}
+	return report, nil
+}
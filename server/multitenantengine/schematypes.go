@@ -0,0 +1,242 @@
+package multitenantengine
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/liamcoop/rules/rules"
+)
+
+// schemaTypePrefix namespaces the CEL object types generated from a tenant schema
+// so they can never collide with the variable names declared for the same objects
+const schemaTypePrefix = "schema."
+
+// schemaTypeProvider exposes each schema object as a CEL object type so the
+// type-checker can reject unknown fields and mismatched operand types.
+// Facts are still supplied as map[string]any at runtime; field selection on
+// these types falls back to map key lookup because no GetFrom accessor is set.
+type schemaTypeProvider struct {
+	types.Provider
+	objects map[string]map[string]*types.Type // type name -> field name -> CEL type
+}
+
+// newSchemaTypeProvider builds a provider for every object in the schema,
+// delegating all other lookups to the default CEL registry
+func newSchemaTypeProvider(schema Schema) (*schemaTypeProvider, error) {
+	registry, err := types.NewRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create type registry: %w", err)
+	}
+
+	objects := make(map[string]map[string]*types.Type, len(schema))
+	for objectName, fields := range schema {
+		fieldTypes := make(map[string]*types.Type, len(fields))
+		for fieldName, typeName := range fields {
+			fieldTypes[fieldName] = celTypeForSchemaType(typeName)
+		}
+		objects[schemaTypeName(objectName)] = fieldTypes
+	}
+
+	return &schemaTypeProvider{
+		Provider: registry,
+		objects:  objects,
+	}, nil
+}
+
+// FindStructType returns the object type for a schema object, or defers to the registry
+func (p *schemaTypeProvider) FindStructType(structType string) (*types.Type, bool) {
+	if _, exists := p.objects[structType]; exists {
+		return types.NewTypeTypeWithParam(types.NewObjectType(structType)), true
+	}
+	return p.Provider.FindStructType(structType)
+}
+
+// FindStructFieldNames returns the sorted field names of a schema object
+func (p *schemaTypeProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	fields, exists := p.objects[structType]
+	if !exists {
+		return p.Provider.FindStructFieldNames(structType)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// FindStructFieldType returns the declared CEL type of a schema object field
+func (p *schemaTypeProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	fields, exists := p.objects[structType]
+	if !exists {
+		return p.Provider.FindStructFieldType(structType, fieldName)
+	}
+
+	fieldType, exists := fields[fieldName]
+	if !exists {
+		return nil, false
+	}
+	return &types.FieldType{Type: fieldType}, true
+}
+
+// NewValue rejects message construction for schema objects; facts are supplied by the caller
+func (p *schemaTypeProvider) NewValue(structType string, fields map[string]ref.Val) ref.Val {
+	if _, exists := p.objects[structType]; exists {
+		return types.NewErr("cannot construct schema object %s in an expression", structType)
+	}
+	return p.Provider.NewValue(structType, fields)
+}
+
+// schemaTypeName returns the CEL type name generated for a schema object
+func schemaTypeName(objectName string) string {
+	return schemaTypePrefix + objectName
+}
+
+// celTypeForSchemaType maps a schema type name to its CEL type
+// Unknown type names map to DynType so schemas stored before validation
+// was introduced keep loading
+func celTypeForSchemaType(typeName string) *cel.Type {
+	switch typeName {
+	case "int", "int64":
+		return cel.IntType
+	case "float64", "double":
+		return cel.DoubleType
+	case "string":
+		return cel.StringType
+	case "bool":
+		return cel.BoolType
+	case "bytes":
+		return cel.BytesType
+	case "timestamp":
+		return cel.TimestampType
+	case "duration":
+		return cel.DurationType
+	default:
+		return cel.DynType
+	}
+}
+
+// schemaFactConverter returns a converter from facts decoded from JSON to the
+// types the schema declares, so they evaluate as the types rules were checked
+// against: whole numbers become int64, RFC 3339 strings timestamps and Go
+// duration strings durations
+// Objects and fields the schema does not declare, fields of unknown types and
+// null fields are left as given; any other value that does not match its
+// declared type is rejected.
+func schemaFactConverter(schema Schema) rules.FactConverter {
+	return func(facts map[string]any) (map[string]any, error) {
+		converted := make(map[string]any, len(facts))
+		for name, value := range facts {
+			fields, declared := schema[name]
+			if !declared || value == nil {
+				converted[name] = value
+				continue
+			}
+
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s must be an object, got %T", name, value)
+			}
+
+			convertedObject := make(map[string]any, len(object))
+			for fieldName, fieldValue := range object {
+				typeName, declared := fields[fieldName]
+				if !declared || fieldValue == nil {
+					convertedObject[fieldName] = fieldValue
+					continue
+				}
+				v, err := convertFactValue(typeName, fieldValue)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", name, fieldName, err)
+				}
+				convertedObject[fieldName] = v
+			}
+			converted[name] = convertedObject
+		}
+		return converted, nil
+	}
+}
+
+// convertFactValue converts a fact value to the Go type CEL evaluates as the
+// schema type typeName
+func convertFactValue(typeName string, value any) (any, error) {
+	switch typeName {
+	case "int", "int64":
+		switch v := value.(type) {
+		case int:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case float64:
+			if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+				return nil, fmt.Errorf("%v is not an int", v)
+			}
+			return int64(v), nil
+		}
+	case "float64", "double":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case float32:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		}
+	case "string":
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case "bool":
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case "bytes":
+		switch v := value.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			// encoding/json carries bytes as base64
+			decoded, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not base64-encoded bytes", v)
+			}
+			return decoded, nil
+		}
+	case "timestamp":
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			ts, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an RFC 3339 timestamp", v)
+			}
+			return ts, nil
+		}
+	case "duration":
+		switch v := value.(type) {
+		case time.Duration:
+			return v, nil
+		case string:
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a duration", v)
+			}
+			return d, nil
+		}
+	default:
+		return value, nil
+	}
+	return nil, fmt.Errorf("%v of type %T is not a %s", value, value, typeName)
+}
@@ -0,0 +1,289 @@
+package multitenantengine
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/liamcoop/rules/rules"
+)
+
+// TestCreateCELEnvFromSchema_TypeChecking verifies that schema field types are enforced at compile time
+func TestCreateCELEnvFromSchema_TypeChecking(t *testing.T) {
+	schema := Schema{
+		"User": {
+			"Age":       "int",
+			"Name":      "string",
+			"IsActive":  "bool",
+			"CreatedAt": "timestamp",
+		},
+		"Transaction": {
+			"Amount": "float64",
+		},
+	}
+
+	env, err := CreateCELEnvFromSchema(schema)
+	if err != nil {
+		t.Fatalf("Failed to create CEL environment: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		expression    string
+		shouldCompile bool
+	}{
+		{"Valid int comparison", `User.Age > 18`, true},
+		{"Valid string comparison", `User.Name == "Alice"`, true},
+		{"Valid bool field", `User.IsActive`, true},
+		{"Valid timestamp comparison", `User.CreatedAt < timestamp("2024-01-01T00:00:00Z")`, true},
+		{"Double compared to int literal", `Transaction.Amount > 1000`, true},
+		{"Int compared to double literal", `User.Age >= 17.5`, true},
+		{"Presence test", `has(User.Name)`, true},
+		{"Misspelled field", `User.Agee > 18`, false},
+		{"String compared to int", `User.Name > 5`, false},
+		{"Int added to string", `User.Age + "1" == "19"`, false},
+		{"Bool used as number", `User.IsActive > 1`, false},
+		{"Undeclared object", `Account.Balance > 0`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, issues := env.Compile(tc.expression)
+			compiled := issues == nil || issues.Err() == nil
+			if compiled != tc.shouldCompile {
+				t.Errorf("Compile(%q) compiled = %v, want %v (issues: %v)", tc.expression, compiled, tc.shouldCompile, issues)
+			}
+		})
+	}
+}
+
+// TestCreateCELEnvFromSchema_EvaluatesMapFacts verifies typed objects still evaluate against map-based facts
+func TestCreateCELEnvFromSchema_EvaluatesMapFacts(t *testing.T) {
+	schema := Schema{
+		"User": {
+			"Age":  "int",
+			"Name": "string",
+		},
+	}
+
+	env, err := CreateCELEnvFromSchema(schema)
+	if err != nil {
+		t.Fatalf("Failed to create CEL environment: %v", err)
+	}
+
+	engine, err := rules.NewEngineWithEnv(env, rules.NewInMemoryRuleStore())
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	rule := &rules.Rule{ID: "adult", Name: "Adult", Expression: `User.Age >= 18 && User.Name != ""`, Active: true}
+	if err := engine.AddRule(rule); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	// JSON-decoded facts carry numbers as float64
+	facts := map[string]any{
+		"User": map[string]any{"Age": float64(25), "Name": "Alice"},
+	}
+
+	result, err := engine.Evaluate("adult", facts)
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if !result.Matched {
+		t.Error("Expected rule to match")
+	}
+}
+
+// TestSchemaFactConverter_Evaluation verifies facts decoded from JSON evaluate
+// as their schema types, so int arithmetic and timestamp functions work
+func TestSchemaFactConverter_Evaluation(t *testing.T) {
+	schema := Schema{
+		"User": {
+			"Age":       "int",
+			"Score":     "float64",
+			"CreatedAt": "timestamp",
+		},
+	}
+
+	env, err := CreateCELEnvFromSchema(schema)
+	if err != nil {
+		t.Fatalf("Failed to create CEL environment: %v", err)
+	}
+	engine, err := rules.NewEngineWithConfig(rules.EngineConfig{
+		Env:          env,
+		Store:        rules.NewInMemoryRuleStore(),
+		ConvertFacts: schemaFactConverter(schema),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	expressions := map[string]string{
+		"add":       `User.Age + 1 > 19`,
+		"modulo":    `User.Age % 2 == 0`,
+		"int":       `int(User.Age) == 30`,
+		"double":    `User.Score * 2.0 == 10.0`,
+		"timestamp": `User.CreatedAt.getFullYear() == 2024`,
+	}
+	for id, expression := range expressions {
+		if err := engine.AddRule(&rules.Rule{ID: id, Name: id, Expression: expression, Active: true}); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", id, err)
+		}
+	}
+
+	var facts map[string]any
+	if err := json.Unmarshal([]byte(`{"User": {"Age": 30, "Score": 5, "CreatedAt": "2024-01-15T10:30:00Z"}}`), &facts); err != nil {
+		t.Fatalf("Failed to decode facts: %v", err)
+	}
+
+	results, err := engine.EvaluateAll(facts)
+	if err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+	for _, result := range results {
+		if result.Error != nil || !result.Matched {
+			t.Errorf("rule %s: Matched = %v, error = %v; want matched", result.RuleID, result.Matched, result.Error)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"User": {"Age": 30.5}}`), &facts); err != nil {
+		t.Fatalf("Failed to decode facts: %v", err)
+	}
+	if _, err := engine.EvaluateAll(facts); !errors.Is(err, rules.ErrInvalidFacts) {
+		t.Errorf("EvaluateAll() with a fractional int error = %v, want ErrInvalidFacts", err)
+	}
+}
+
+// TestSchemaFactConverter verifies fact values are converted to their schema
+// types and mismatches are rejected
+func TestSchemaFactConverter(t *testing.T) {
+	schema := Schema{
+		"User": {
+			"Age":       "int",
+			"Score":     "float64",
+			"Name":      "string",
+			"IsActive":  "bool",
+			"Avatar":    "bytes",
+			"CreatedAt": "timestamp",
+			"Session":   "duration",
+			"Extra":     "custom",
+		},
+	}
+	convert := schemaFactConverter(schema)
+
+	testCases := []struct {
+		name    string
+		field   string
+		value   any
+		want    any
+		wantErr bool
+	}{
+		{"Whole number to int", "Age", float64(30), int64(30), false},
+		{"Go int to int", "Age", 30, int64(30), false},
+		{"Fractional int", "Age", 30.5, nil, true},
+		{"String int", "Age", "30", nil, true},
+		{"Int to double", "Score", 5, float64(5), false},
+		{"String", "Name", "Alice", "Alice", false},
+		{"Number as string", "Name", float64(1), nil, true},
+		{"Bool", "IsActive", true, true, false},
+		{"Base64 bytes", "Avatar", "aGk=", []byte("hi"), false},
+		{"Invalid base64", "Avatar", "not base64!", nil, true},
+		{"Timestamp", "CreatedAt", "2024-01-15T10:30:00Z", time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), false},
+		{"Invalid timestamp", "CreatedAt", "2024-01-15", nil, true},
+		{"Duration", "Session", "1h30m", 90 * time.Minute, false},
+		{"Null", "Age", nil, nil, false},
+		{"Unknown type", "Extra", "anything", "anything", false},
+		{"Undeclared field", "Nickname", float64(1), float64(1), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			facts := map[string]any{"User": map[string]any{tc.field: tc.value}}
+			converted, err := convert(facts)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("convert() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			got := converted["User"].(map[string]any)[tc.field]
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("converted %s = %#v, want %#v", tc.field, got, tc.want)
+			}
+		})
+	}
+
+	if _, err := convert(map[string]any{"User": "Alice"}); err == nil {
+		t.Error("convert() should reject an object that is not a map")
+	}
+	facts := map[string]any{"Other": float64(1)}
+	if converted, err := convert(facts); err != nil || converted["Other"] != float64(1) {
+		t.Errorf("convert() = %v, %v; want undeclared objects passed through", converted, err)
+	}
+}
+
+// TestCreateCELEnvFromSchema_LegacyFallback verifies stored rules that fail type checking keep loading
+func TestCreateCELEnvFromSchema_LegacyFallback(t *testing.T) {
+	schema := Schema{
+		"User": {
+			"Age": "int",
+		},
+	}
+
+	env, err := CreateCELEnvFromSchema(schema)
+	if err != nil {
+		t.Fatalf("Failed to create CEL environment: %v", err)
+	}
+	legacyEnv, err := CreateLegacyCELEnvFromSchema(schema)
+	if err != nil {
+		t.Fatalf("Failed to create legacy CEL environment: %v", err)
+	}
+
+	// A rule stored before schema types were enforced
+	store := rules.NewInMemoryRuleStore()
+	if err := store.Add(&rules.Rule{ID: "typo", Name: "Typo", Expression: `User.Agee > 18`, Active: true}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	if _, err := rules.NewEngineWithEnv(env, store); err == nil {
+		t.Error("Expected strict engine to reject stored rule with unknown field")
+	}
+
+	engine, err := rules.NewEngineWithFallbackEnv(env, legacyEnv, store)
+	if err != nil {
+		t.Fatalf("Expected engine with fallback to load stored rule, got: %v", err)
+	}
+	if !engine.IsLegacyRule("typo") {
+		t.Error("Expected stored rule to be marked as legacy")
+	}
+
+	// New rules are still checked against the typed environment
+	err = engine.AddRule(&rules.Rule{ID: "new-typo", Name: "New Typo", Expression: `User.Agee > 18`, Active: true})
+	if err == nil {
+		t.Error("Expected AddRule() to reject unknown field")
+	}
+}
+
+// TestCelTypeForSchemaType verifies schema type names map to CEL types
+func TestCelTypeForSchemaType(t *testing.T) {
+	testCases := map[string]string{
+		"int":       "int",
+		"int64":     "int",
+		"float64":   "double",
+		"string":    "string",
+		"bool":      "bool",
+		"bytes":     "bytes",
+		"timestamp": "google.protobuf.Timestamp",
+		"duration":  "google.protobuf.Duration",
+		"unknown":   "dyn",
+	}
+
+	for typeName, want := range testCases {
+		if got := celTypeForSchemaType(typeName).String(); got != want {
+			t.Errorf("celTypeForSchemaType(%q) = %s, want %s", typeName, got, want)
+		}
+	}
+}
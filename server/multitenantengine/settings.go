@@ -0,0 +1,120 @@
+package multitenantengine
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/liamcoop/rules/rules"
+)
+
+// TenantSettings holds per-tenant engine settings stored on the tenants table
+// A zero timeout means no limit; a Concurrency of 0 or 1 evaluates rules
+// sequentially; a MaxBatchSize of 0 uses rules.DefaultMaxBatchSize; a
+// MaxRuleCost of 0 accepts rules of any estimated cost
+type TenantSettings struct {
+	RuleTimeout    time.Duration
+	RequestTimeout time.Duration
+	Concurrency    int
+	MaxBatchSize   int
+	MaxRuleCost    int64 // worst-case cost budget checked when rules are added or updated
+}
+
+// Validate checks that the settings can be applied to an engine
+func (s TenantSettings) Validate() error {
+	if s.Concurrency < 0 {
+		return fmt.Errorf("concurrency must not be negative")
+	}
+	if s.MaxBatchSize < 0 {
+		return fmt.Errorf("max batch size must not be negative")
+	}
+	if s.MaxRuleCost < 0 {
+		return fmt.Errorf("max rule cost must not be negative")
+	}
+	return s.evaluationLimits().Validate()
+}
+
+// evaluationLimits converts the settings into engine evaluation limits
+func (s TenantSettings) evaluationLimits() rules.EvaluationLimits {
+	return rules.EvaluationLimits{
+		RuleTimeout:    s.RuleTimeout,
+		RequestTimeout: s.RequestTimeout,
+	}
+}
+
+// GetTenantSettings loads a tenant's settings from the database
+// Tenants without a database row get the default settings
+func (m *MultiTenantEngineManager) GetTenantSettings(tenantID string) (TenantSettings, error) {
+	var ruleTimeoutMs, requestTimeoutMs int64
+	var concurrency, maxBatchSize int
+	var maxRuleCost int64
+	err := m.db.QueryRow(`
+		SELECT rule_timeout_ms, request_timeout_ms, evaluation_concurrency, max_batch_size, max_rule_cost
+		FROM tenants
+		WHERE id = $1
+	`, tenantID).Scan(&ruleTimeoutMs, &requestTimeoutMs, &concurrency, &maxBatchSize, &maxRuleCost)
+
+	if err == sql.ErrNoRows {
+		return TenantSettings{}, nil
+	}
+	if err != nil {
+		return TenantSettings{}, fmt.Errorf("failed to load tenant settings: %w", err)
+	}
+
+	return TenantSettings{
+		RuleTimeout:    time.Duration(ruleTimeoutMs) * time.Millisecond,
+		RequestTimeout: time.Duration(requestTimeoutMs) * time.Millisecond,
+		Concurrency:    concurrency,
+		MaxBatchSize:   maxBatchSize,
+		MaxRuleCost:    maxRuleCost,
+	}, nil
+}
+
+// UpdateTenantSettings stores a tenant's settings and applies them to its
+// loaded engine; evaluations already running keep their original budgets
+func (m *MultiTenantEngineManager) UpdateTenantSettings(tenantID string, settings TenantSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	result, err := m.db.Exec(`
+		UPDATE tenants
+		SET rule_timeout_ms = $1, request_timeout_ms = $2, evaluation_concurrency = $3,
+			max_batch_size = $4, max_rule_cost = $5, updated_at = NOW()
+		WHERE id = $6
+	`, settings.RuleTimeout.Milliseconds(), settings.RequestTimeout.Milliseconds(),
+		settings.Concurrency, settings.MaxBatchSize, settings.MaxRuleCost, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to update tenant settings: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("tenant %s not found", tenantID)
+	}
+
+	m.mu.RLock()
+	te, exists := m.engines[tenantID]
+	m.mu.RUnlock()
+
+	if exists {
+		return applyTenantSettings(te.Engine, settings)
+	}
+
+	return nil
+}
+
+// applyTenantSettings applies settings to a loaded engine
+func applyTenantSettings(engine *rules.Engine, settings TenantSettings) error {
+	if err := engine.SetEvaluationLimits(settings.evaluationLimits()); err != nil {
+		return err
+	}
+	if err := engine.SetConcurrency(settings.Concurrency); err != nil {
+		return err
+	}
+	engine.SetMaxCost(uint64(settings.MaxRuleCost))
+	return engine.SetMaxBatchSize(settings.MaxBatchSize)
+}
@@ -0,0 +1,119 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultMaxBatchSize is the largest batch EvaluateBatch accepts when the
+// engine is not configured with its own limit
+const DefaultMaxBatchSize = 1000
+
+// ErrBatchTooLarge is returned when a batch exceeds the engine's batch size limit
+var ErrBatchTooLarge = errors.New("batch too large")
+
+// BatchItem is one fact set in a batch evaluation
+type BatchItem struct {
+	ID    string // optional caller-supplied identifier, echoed in the result
+	Facts map[string]any
+}
+
+// BatchResult holds the evaluation results for one BatchItem
+// Error is set when the item itself could not be evaluated; rule-level
+// failures are reported in Results as with EvaluateAll
+type BatchResult struct {
+	ID      string
+	Index   int // position of the item in the batch
+	Results []*EvaluationResult
+	Error   error
+}
+
+// MaxBatchSize returns the largest batch EvaluateBatch accepts
+func (en *Engine) MaxBatchSize() int {
+	en.mu.RLock()
+	defer en.mu.RUnlock()
+
+	if en.maxBatchSize == 0 {
+		return DefaultMaxBatchSize
+	}
+	return en.maxBatchSize
+}
+
+// SetMaxBatchSize sets the largest batch EvaluateBatch accepts
+// 0 restores DefaultMaxBatchSize
+func (en *Engine) SetMaxBatchSize(size int) error {
+	if size < 0 {
+		return fmt.Errorf("max batch size must not be negative")
+	}
+
+	en.mu.Lock()
+	en.maxBatchSize = size
+	en.mu.Unlock()
+
+	return nil
+}
+
+// EvaluateBatch evaluates all active rules against each fact set in a batch
+func (en *Engine) EvaluateBatch(items []BatchItem, opts EvaluateOptions) ([]*BatchResult, error) {
+	return en.EvaluateBatchContext(context.Background(), items, opts)
+}
+
+// EvaluateBatchContext evaluates all active rules against each fact set in a
+// batch, returning one result per item in the order given. The active rules
+// are loaded once for the whole batch, and the engine's request time budget
+// applies to each item separately. A failure in one item does not affect the
+// others; once ctx is done the remaining items report its error.
+func (en *Engine) EvaluateBatchContext(ctx context.Context, items []BatchItem, opts EvaluateOptions) ([]*BatchResult, error) {
+	if err := en.checkOptions(opts); err != nil {
+		return nil, err
+	}
+
+	if max := en.MaxBatchSize(); len(items) > max {
+		return nil, fmt.Errorf("%w: %d items exceeds the limit of %d", ErrBatchTooLarge, len(items), max)
+	}
+
+	// Every item sees the same rules, even if a rule's effective window
+	// opens or closes part way through the batch
+	snap, rules, now, err := en.liveRules()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*BatchResult, len(items))
+	for i, item := range items {
+		results[i] = en.evaluateBatchItem(ctx, i, item, snap, now, rules, opts)
+	}
+
+	return results, nil
+}
+
+// evaluateBatchItem evaluates one item, converting a panic into an item error
+// so a single malformed fact set cannot fail the whole batch
+func (en *Engine) evaluateBatchItem(ctx context.Context, index int, item BatchItem, snap *ruleSnapshot, now time.Time, rules []*Rule, opts EvaluateOptions) (result *BatchResult) {
+	result = &BatchResult{
+		ID:    item.ID,
+		Index: index,
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result.Results = nil
+			result.Error = fmt.Errorf("evaluation failed: %v", r)
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		result.Error = contextError(err)
+		return result
+	}
+
+	if item.Facts == nil {
+		result.Error = fmt.Errorf("facts are required")
+		return result
+	}
+
+	result.Results, result.Error = en.evaluateRules(ctx, snap, now, rules, item.Facts, opts)
+	return result
+}
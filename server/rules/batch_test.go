@@ -0,0 +1,136 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestEvaluateBatch verifies each item is evaluated independently and results keep item order
+func TestEvaluateBatch(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, priorityRules()...)
+
+	items := []BatchItem{
+		{ID: "adult", Facts: map[string]any{"User": map[string]any{"Age": 30}}},
+		{Facts: map[string]any{"User": map[string]any{"Age": 10}}},
+		{ID: "missing-facts"},
+		{ID: "bad-facts", Facts: map[string]any{"User": "not an object"}},
+	}
+
+	results, err := engine.EvaluateBatch(items, EvaluateOptions{})
+	if err != nil {
+		t.Fatalf("EvaluateBatch() failed: %v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("expected %d results, got %d", len(items), len(results))
+	}
+
+	for i, result := range results {
+		if result.Index != i || result.ID != items[i].ID {
+			t.Errorf("result %d: Index = %d, ID = %q, want %d, %q", i, result.Index, result.ID, i, items[i].ID)
+		}
+	}
+
+	countMatches := func(results []*EvaluationResult) int {
+		n := 0
+		for _, r := range results {
+			if r.Matched {
+				n++
+			}
+		}
+		return n
+	}
+
+	if results[0].Error != nil || countMatches(results[0].Results) != 3 {
+		t.Errorf("adult item: Error = %v, matches = %d, want 3 matches", results[0].Error, countMatches(results[0].Results))
+	}
+	if results[1].Error != nil || countMatches(results[1].Results) != 1 {
+		t.Errorf("minor item: Error = %v, matches = %d, want 1 match", results[1].Error, countMatches(results[1].Results))
+	}
+	if results[2].Error == nil {
+		t.Error("item without facts should report an error")
+	}
+
+	// Rule errors stay in the item's rule results rather than failing the item
+	if results[3].Error != nil {
+		t.Errorf("bad facts item: Error = %v, want rule-level errors only", results[3].Error)
+	}
+	for _, r := range results[3].Results {
+		if r.Error == nil {
+			t.Errorf("rule %s should fail for malformed facts", r.RuleID)
+		}
+	}
+}
+
+// TestEvaluateBatchModes verifies evaluation modes apply to each item
+func TestEvaluateBatchModes(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, priorityRules()...)
+
+	items := []BatchItem{
+		{Facts: map[string]any{"User": map[string]any{"Age": 30}}},
+		{Facts: map[string]any{"User": map[string]any{"Age": 40}}},
+	}
+
+	results, err := engine.EvaluateBatch(items, EvaluateOptions{Mode: EvaluationModeFirstMatch})
+	if err != nil {
+		t.Fatalf("EvaluateBatch() failed: %v", err)
+	}
+	for _, result := range results {
+		if len(result.Results) != 2 || result.Results[1].RuleID != "high" {
+			t.Errorf("item %d: expected evaluation to stop at rule high", result.Index)
+		}
+	}
+
+	if _, err := engine.EvaluateBatch(items, EvaluateOptions{Mode: "unknown"}); err == nil {
+		t.Error("expected invalid mode to be rejected")
+	}
+}
+
+// TestEvaluateBatchSizeLimit verifies batches over the configured limit are rejected
+func TestEvaluateBatchSizeLimit(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, priorityRules()...)
+
+	if got := engine.MaxBatchSize(); got != DefaultMaxBatchSize {
+		t.Errorf("MaxBatchSize() = %d, want default %d", got, DefaultMaxBatchSize)
+	}
+
+	if err := engine.SetMaxBatchSize(2); err != nil {
+		t.Fatalf("SetMaxBatchSize() failed: %v", err)
+	}
+
+	items := make([]BatchItem, 3)
+	for i := range items {
+		items[i] = BatchItem{Facts: map[string]any{"User": map[string]any{"Age": 30}}}
+	}
+
+	_, err := engine.EvaluateBatch(items, EvaluateOptions{})
+	if !errors.Is(err, ErrBatchTooLarge) {
+		t.Errorf("EvaluateBatch() error = %v, want ErrBatchTooLarge", err)
+	}
+
+	if _, err := engine.EvaluateBatch(items[:2], EvaluateOptions{}); err != nil {
+		t.Errorf("EvaluateBatch() at the limit failed: %v", err)
+	}
+
+	if err := engine.SetMaxBatchSize(-1); err == nil {
+		t.Error("expected negative max batch size to be rejected")
+	}
+}
+
+// TestEvaluateBatchCanceled verifies items after cancellation report the context error
+func TestEvaluateBatchCanceled(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, priorityRules()...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := engine.EvaluateBatchContext(ctx, []BatchItem{
+		{Facts: map[string]any{"User": map[string]any{"Age": 30}}},
+	}, EvaluateOptions{})
+	if err != nil {
+		t.Fatalf("EvaluateBatchContext() failed: %v", err)
+	}
+	if !errors.Is(results[0].Error, ErrEvaluationCanceled) {
+		t.Errorf("Error = %v, want ErrEvaluationCanceled", results[0].Error)
+	}
+}
@@ -0,0 +1,450 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter"
+)
+
+// ruleRefPrefix qualifies the variables other rules are referenced by,
+// e.g. rules.isHighRiskCountry
+const ruleRefPrefix = "rules."
+
+// ErrRuleInUse is returned when deleting a rule that other active rules reference
+var ErrRuleInUse = errors.New("rule is referenced by other rules")
+
+// ruleReferences returns the active rules that can be referenced from other
+// rules, keyed by name. Only names that are valid identifiers can be
+// referenced, and a name shared by several active rules is left out since a
+// reference to it would be ambiguous.
+func ruleReferences(rules []*Rule) map[string]*Rule {
+	refs := make(map[string]*Rule)
+	shared := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Shadow || !derivedFieldNamePattern.MatchString(rule.Name) || shared[rule.Name] {
+			continue
+		}
+		if _, ok := refs[rule.Name]; ok {
+			delete(refs, rule.Name)
+			shared[rule.Name] = true
+			continue
+		}
+		refs[rule.Name] = rule
+	}
+	return refs
+}
+
+// declareRuleReferences extends env and fallbackEnv with a rules.<name>
+// variable for every referenceable rule
+// A reference has the rule's declared output type, or bool when it declares none
+func declareRuleReferences(env, fallbackEnv *cel.Env, refs map[string]*Rule) (*cel.Env, *cel.Env, error) {
+	if len(refs) == 0 {
+		return env, fallbackEnv, nil
+	}
+
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	opts := make([]cel.EnvOption, 0, len(names))
+	for _, name := range names {
+		declared, err := ParseOutputType(refs[name].OutputType)
+		if err != nil {
+			// The rule itself fails to compile, so nothing can reference it
+			continue
+		}
+		if declared == nil {
+			declared = cel.BoolType
+		}
+		opts = append(opts, cel.Variable(ruleRefPrefix+name, declared))
+	}
+
+	chained, err := env.Extend(opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to declare rule references: %w", err)
+	}
+	chainedFallback := fallbackEnv
+	if fallbackEnv != nil {
+		chainedFallback, err = fallbackEnv.Extend(opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to declare rule references: %w", err)
+		}
+	}
+
+	return chained, chainedFallback, nil
+}
+
+// ruleDependencies returns the names of the rules a checked expression
+// references, sorted
+func ruleDependencies(checked *cel.Ast) []string {
+	seen := make(map[string]bool)
+	for _, reference := range checked.NativeRep().ReferenceMap() {
+		if name, ok := strings.CutPrefix(reference.Name, ruleRefPrefix); ok {
+			seen[name] = true
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil
+	}
+	deps := make([]string, 0, len(seen))
+	for name := range seen {
+		deps = append(deps, name)
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// changedReferences returns the names whose reference was added, removed, or
+// now resolves to a different rule or output type
+func changedReferences(before, after map[string]*Rule) map[string]bool {
+	changed := make(map[string]bool)
+	for name, rule := range before {
+		next, ok := after[name]
+		if !ok || next.ID != rule.ID || next.OutputType != rule.OutputType {
+			changed[name] = true
+		}
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			changed[name] = true
+		}
+	}
+	return changed
+}
+
+// checkRuleCycles returns an error naming the cycle if rules reference each
+// other in a loop; dependencies returns the rule names a rule references
+func checkRuleCycles(refs map[string]*Rule, dependencies func(rule *Rule) []string) error {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(refs))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("rule dependency cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range dependencies(refs[name]) {
+			if _, ok := refs[dep]; !ok {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rulePlan is the compiled state that results from a rule change
+type rulePlan struct {
+	refs        map[string]*Rule
+	env         *cel.Env
+	fallbackEnv *cel.Env
+	programs    map[string]*compiledRule // rules compiled for the change
+	legacy      map[string]bool          // recompiled rules that fell back to fallbackEnv
+}
+
+// planRuleChange compiles a rule change before anything is persisted
+// active is the active rule set after the change and changed is the rule being
+// added or updated, or nil for a delete. Rules that reference a name whose
+// meaning changed are recompiled, so renaming, deactivating, deleting or
+// retyping a referenced rule is rejected while other rules depend on it.
+// The changed rule must also fit the engine's cost budget.
+// Callers must hold writeMu
+func (en *Engine) planRuleChange(active []*Rule, changed *Rule) (*rulePlan, error) {
+	en.mu.RLock()
+	env := en.env
+	fallbackEnv := en.fallbackEnv
+	budget := en.maxCost
+	en.mu.RUnlock()
+
+	snap := en.snapshot.Load()
+	before := snap.refs
+	current := make(map[string]*compiledRule, len(active))
+	legacy := make(map[string]bool)
+	for _, rule := range active {
+		current[rule.ID] = snap.programs[rule.ID]
+		legacy[rule.ID] = snap.legacy[rule.ID]
+	}
+
+	plan := &rulePlan{
+		refs:     ruleReferences(active),
+		programs: make(map[string]*compiledRule),
+		legacy:   make(map[string]bool),
+	}
+
+	var err error
+	plan.env, plan.fallbackEnv, err = declareRuleReferences(env, fallbackEnv, plan.refs)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed != nil {
+		compiled, err := compileProgram(plan.env, changed.Expression, changed.OutputType, en.options)
+		if err != nil {
+			return nil, fmt.Errorf("rule validation failed: %w", err)
+		}
+		if err := checkCostBudget(compiled.cost, budget); err != nil {
+			return nil, fmt.Errorf("rule validation failed: %w", err)
+		}
+		plan.programs[changed.ID] = compiled
+	}
+
+	moved := changedReferences(before, plan.refs)
+	for _, rule := range active {
+		if changed != nil && rule.ID == changed.ID {
+			continue
+		}
+		compiled := current[rule.ID]
+		if compiled == nil || !dependsOnAny(compiled, moved) {
+			continue
+		}
+
+		compiled, err := compileProgram(plan.env, rule.Expression, rule.OutputType, en.options)
+		if err != nil && plan.fallbackEnv != nil && legacy[rule.ID] {
+			compiled, err = compileProgram(plan.fallbackEnv, rule.Expression, rule.OutputType, en.options)
+			plan.legacy[rule.ID] = true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("change breaks dependent rule %s: %w", rule.ID, err)
+		}
+		plan.programs[rule.ID] = compiled
+	}
+
+	err = checkRuleCycles(plan.refs, func(rule *Rule) []string {
+		if compiled, ok := plan.programs[rule.ID]; ok {
+			return compiled.dependencies
+		}
+		if compiled := current[rule.ID]; compiled != nil {
+			return compiled.dependencies
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// applyRulePlan publishes the programs and references of a planned change
+// with the active rules reloaded from the store, dropping the programs of
+// dropped, the rules deleted or left inactive by the change
+// The rules are loaded before publishing, so evaluations see the change's
+// programs and rules together. If they cannot be loaded they are reloaded by
+// the next evaluation instead.
+// Callers must hold writeMu
+func (en *Engine) applyRulePlan(plan *rulePlan, dropped []string) {
+	rules, err := en.store.ListActive()
+
+	en.publish(func(next *ruleSnapshot) {
+		en.ruleEnv = plan.env
+		en.ruleFallbackEnv = plan.fallbackEnv
+
+		programs, legacy := next.copyPrograms()
+		for ruleID, compiled := range plan.programs {
+			programs[ruleID] = compiled
+			if plan.legacy[ruleID] {
+				legacy[ruleID] = true
+			} else {
+				delete(legacy, ruleID)
+			}
+		}
+		for _, ruleID := range dropped {
+			delete(programs, ruleID)
+			delete(legacy, ruleID)
+		}
+
+		next.refs = plan.refs
+		next.setPrograms(programs, legacy)
+		if err != nil {
+			next.unloadRules()
+		} else {
+			next.setRules(rules)
+		}
+	})
+}
+
+// dependsOnAny reports whether a compiled rule references any of names
+func dependsOnAny(compiled *compiledRule, names map[string]bool) bool {
+	for _, dep := range compiled.dependencies {
+		if names[dep] {
+			return true
+		}
+	}
+	return false
+}
+
+// RuleDependencies returns the names of the rules a compiled rule references
+// through rules.<name>, sorted
+func (en *Engine) RuleDependencies(ruleID string) []string {
+	compiled, ok := en.snapshot.Load().programs[ruleID]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), compiled.dependencies...)
+}
+
+// ruleChain evaluates referenced rules lazily for one request
+// Each referenced rule runs at most once however many rules reference it, and
+// always before the rules that depend on it, since a reference is only
+// resolved when a dependent rule reads it. It is safe for concurrent use by
+// parallel workers.
+type ruleChain struct {
+	ctx    context.Context
+	limits EvaluationLimits
+	facts  interpreter.Activation
+	links  map[string]*chainLink // rule name -> memoized evaluation
+}
+
+// chainLink memoizes the evaluation of one referenceable rule
+type chainLink struct {
+	rule        *Rule
+	compiled    *compiledRule
+	outOfWindow bool // the rule is outside its effective window, so never run
+	once        sync.Once
+	result      *EvaluationResult
+	value       ref.Val
+}
+
+// newRuleChain creates a chain over the snapshot's referenceable rules for a
+// request, returning nil when no rule references another so evaluation can
+// use facts directly
+// Referenced rules outside their effective window at now are not run.
+func (s *ruleSnapshot) newRuleChain(ctx context.Context, facts map[string]any, now time.Time) *ruleChain {
+	if !s.chained {
+		return nil
+	}
+	return buildRuleChain(ctx, facts, s.limits, s.refs, s.programs, func(rule *Rule) bool {
+		return rule.EffectiveAt(now)
+	})
+}
+
+// buildRuleChain creates a chain over the given referenceable rules and
+// programs, returning nil if the facts cannot be bound
+// References to rules that effective rejects evaluate as if the rule did not
+// match, without running it; a nil effective runs every referenced rule.
+func buildRuleChain(ctx context.Context, facts map[string]any, limits EvaluationLimits, refs map[string]*Rule, programs map[string]*compiledRule, effective func(*Rule) bool) *ruleChain {
+	vars, err := interpreter.NewActivation(facts)
+	if err != nil {
+		return nil
+	}
+
+	links := make(map[string]*chainLink, len(refs))
+	for name, rule := range refs {
+		links[name] = &chainLink{
+			rule:        rule,
+			compiled:    programs[rule.ID],
+			outOfWindow: effective != nil && !effective(rule),
+		}
+	}
+
+	return &ruleChain{
+		ctx:    ctx,
+		limits: limits,
+		facts:  vars,
+		links:  links,
+	}
+}
+
+// evaluate evaluates a rule within the chain, reusing the result if another
+// rule already referenced it in this request
+func (c *ruleChain) evaluate(rule *Rule, compiled *compiledRule) *EvaluationResult {
+	if link, ok := c.links[rule.Name]; ok && link.rule.ID == rule.ID {
+		c.run(link)
+		return link.result
+	}
+	return evaluateCompiled(c.ctx, rule, compiled, c, c.limits)
+}
+
+// run evaluates a link once
+func (c *ruleChain) run(link *chainLink) {
+	link.once.Do(func() {
+		if link.compiled == nil {
+			link.result = evaluateCompiled(c.ctx, link.rule, nil, c, c.limits)
+			return
+		}
+		link.result, link.value = runProgram(c.ctx, link.rule, link.compiled, c, c.limits)
+	})
+}
+
+// ResolveName implements interpreter.Activation, resolving rules.<name> to the
+// referenced rule's value and everything else from the facts
+func (c *ruleChain) ResolveName(name string) (any, bool) {
+	if ruleName, ok := strings.CutPrefix(name, ruleRefPrefix); ok {
+		if link, ok := c.links[ruleName]; ok {
+			return c.referenceValue(ruleName, link), true
+		}
+	}
+	return c.facts.ResolveName(name)
+}
+
+// Parent implements interpreter.Activation
+func (c *ruleChain) Parent() interpreter.Activation {
+	return nil
+}
+
+// referenceValue is the value a dependent rule sees for a referenced rule:
+// its output when it declares an output type, otherwise whether it matched
+// A referenced rule that fails makes the reference an error, and one outside
+// its effective window is treated as not matched; see outOfWindowValue
+func (c *ruleChain) referenceValue(name string, link *chainLink) ref.Val {
+	if link.outOfWindow {
+		return outOfWindowValue(name, link.compiled)
+	}
+	c.run(link)
+	if link.result.Error != nil {
+		return types.NewErr("referenced rule %s failed: %v", name, link.result.Error)
+	}
+	if link.compiled.outputType == nil {
+		return types.Bool(link.result.Matched)
+	}
+	return link.value
+}
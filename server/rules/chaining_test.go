@@ -0,0 +1,259 @@
+package rules
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// TestRuleChaining verifies rules can reference other rules' results
+func TestRuleChaining(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	rules := []*Rule{
+		{ID: "country", Name: "isHighRiskCountry", Expression: `User.Country in ["XX", "YY"]`, Active: true},
+		{ID: "score", Name: "riskScore", Expression: `Transaction.Amount > 1000.0 ? 80 : 10`, Active: true, OutputType: "int"},
+		{ID: "fraud", Name: "Fraud", Expression: `rules.isHighRiskCountry && rules.riskScore > 50`, Active: true, Priority: 10},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	if deps := engine.RuleDependencies("fraud"); strings.Join(deps, ",") != "isHighRiskCountry,riskScore" {
+		t.Errorf("RuleDependencies() = %v, want [isHighRiskCountry riskScore]", deps)
+	}
+
+	testCases := []struct {
+		name    string
+		country string
+		amount  float64
+		want    bool
+	}{
+		{"Both match", "XX", 5000.0, true},
+		{"Low risk country", "CA", 5000.0, false},
+		{"Low score", "XX", 10.0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			facts := map[string]any{
+				"User":        map[string]any{"Country": tc.country},
+				"Transaction": map[string]any{"Amount": tc.amount},
+			}
+
+			result, err := engine.Evaluate("fraud", facts)
+			if err != nil {
+				t.Fatalf("Evaluate() failed: %v", err)
+			}
+			if result.Matched != tc.want {
+				t.Errorf("Evaluate() matched = %v, want %v", result.Matched, tc.want)
+			}
+
+			results, err := engine.EvaluateAll(facts)
+			if err != nil {
+				t.Fatalf("EvaluateAll() failed: %v", err)
+			}
+			if results[0].RuleID != "fraud" || results[0].Matched != tc.want {
+				t.Errorf("EvaluateAll() fraud result = %+v, want matched %v", results[0], tc.want)
+			}
+		})
+	}
+}
+
+// TestRuleChainingEvaluatesOnce verifies a rule referenced by several others
+// runs once per request, sequentially and in parallel
+func TestRuleChainingEvaluatesOnce(t *testing.T) {
+	for _, concurrency := range []int{0, 4} {
+		// tick(bool) counts its calls, so the test can observe how often a rule runs
+		var calls atomic.Int64
+		env, err := cel.NewEnv(
+			cel.Variable("User", cel.DynType),
+			cel.Variable("Transaction", cel.DynType),
+			cel.Function("tick",
+				cel.Overload("tick_bool", []*cel.Type{cel.BoolType}, cel.BoolType,
+					cel.UnaryBinding(func(v ref.Val) ref.Val {
+						calls.Add(1)
+						return v
+					}),
+				),
+			),
+		)
+		if err != nil {
+			t.Fatalf("cel.NewEnv() failed: %v", err)
+		}
+		engine := newTestEngine(t, EngineConfig{Env: env, Concurrency: concurrency},
+			&Rule{ID: "adult", Name: "isAdult", Expression: `tick(User.Age >= 18)`, Active: true},
+			&Rule{ID: "a", Name: "A", Expression: `rules.isAdult && User.Age < 65`, Active: true, Priority: 10},
+			&Rule{ID: "b", Name: "B", Expression: `rules.isAdult || User.Age > 100`, Active: true, Priority: 20},
+			&Rule{ID: "c", Name: "C", Expression: `!rules.isAdult`, Active: true, Priority: 30},
+		)
+
+		facts := map[string]any{"User": map[string]any{"Age": 30}}
+		results, err := engine.EvaluateAll(facts)
+		if err != nil {
+			t.Fatalf("EvaluateAll() failed: %v", err)
+		}
+		if len(results) != 4 {
+			t.Fatalf("EvaluateAll() returned %d results, want 4", len(results))
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("concurrency %d: referenced rule ran %d times, want 1", concurrency, got)
+		}
+
+		calls.Store(0)
+		if _, err := engine.EvaluateBatch([]BatchItem{{Facts: facts}, {Facts: facts}}, EvaluateOptions{}); err != nil {
+			t.Fatalf("EvaluateBatch() failed: %v", err)
+		}
+		if got := calls.Load(); got != 2 {
+			t.Errorf("concurrency %d: referenced rule ran %d times across 2 batch items, want 2", concurrency, got)
+		}
+	}
+}
+
+// TestRuleChainingCycles verifies AddRule and UpdateRule reject dependency cycles
+func TestRuleChainingCycles(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	rules := []*Rule{
+		{ID: "a", Name: "a", Expression: `User.Age > 18`, Active: true},
+		{ID: "b", Name: "b", Expression: `rules.a`, Active: true},
+		{ID: "c", Name: "c", Expression: `rules.b`, Active: true},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	err := engine.UpdateRule(&Rule{ID: "a", Name: "a", Expression: `rules.c`, Active: true})
+	if err == nil || !strings.Contains(err.Error(), "cycle detected: a -> c -> b -> a") {
+		t.Errorf("UpdateRule() error = %v, want cycle a -> c -> b -> a", err)
+	}
+
+	err = engine.AddRule(&Rule{ID: "self", Name: "self", Expression: `rules.self`, Active: true})
+	if err == nil {
+		t.Error("AddRule() should reject a rule referencing itself")
+	}
+
+	// The rejected update leaves the original rule in place
+	result, err := engine.Evaluate("c", map[string]any{"User": map[string]any{"Age": 30}})
+	if err != nil || !result.Matched {
+		t.Errorf("Evaluate(c) = %+v, %v; want matched", result, err)
+	}
+}
+
+// TestRuleChainingBreakingChanges verifies referenced rules cannot be changed
+// in ways that break the rules depending on them
+func TestRuleChainingBreakingChanges(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	rules := []*Rule{
+		{ID: "adult", Name: "isAdult", Expression: `User.Age >= 18`, Active: true},
+		{ID: "check", Name: "Check", Expression: `rules.isAdult && User.Age > 0`, Active: true},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		change func() error
+	}{
+		{"Rename", func() error {
+			return engine.UpdateRule(&Rule{ID: "adult", Name: "isGrownUp", Expression: `User.Age >= 18`, Active: true})
+		}},
+		{"Deactivate", func() error {
+			return engine.UpdateRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age >= 18`, Active: false})
+		}},
+		{"Change output type", func() error {
+			return engine.UpdateRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age`, Active: true, OutputType: "int"})
+		}},
+		{"Delete", func() error {
+			return engine.DeleteRule("adult")
+		}},
+		{"Ambiguous name", func() error {
+			return engine.AddRule(&Rule{ID: "adult2", Name: "isAdult", Expression: `true`, Active: true})
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.change(); err == nil {
+				t.Error("change should be rejected while another rule depends on the rule")
+			}
+		})
+	}
+
+	if err := engine.DeleteRule("adult"); !errors.Is(err, ErrRuleInUse) {
+		t.Errorf("DeleteRule() error = %v, want ErrRuleInUse", err)
+	}
+
+	// Changing only the expression keeps the reference valid
+	if err := engine.UpdateRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age >= 21`, Active: true}); err != nil {
+		t.Fatalf("UpdateRule() failed: %v", err)
+	}
+	result, _ := engine.Evaluate("check", map[string]any{"User": map[string]any{"Age": 19}})
+	if result.Matched {
+		t.Error("dependent rule should see the updated expression")
+	}
+
+	// Once the dependent is gone the referenced rule can be deleted
+	if err := engine.DeleteRule("check"); err != nil {
+		t.Fatalf("DeleteRule(check) failed: %v", err)
+	}
+	if err := engine.DeleteRule("adult"); err != nil {
+		t.Errorf("DeleteRule(adult) failed: %v", err)
+	}
+}
+
+// TestRuleChainingErrors verifies a failing referenced rule surfaces as an
+// error in the rules that depend on it
+func TestRuleChainingErrors(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	rules := []*Rule{
+		{ID: "missing", Name: "usesMissing", Expression: `User.Missing > 1`, Active: true},
+		{ID: "strict", Name: "Strict", Expression: `rules.usesMissing`, Active: true},
+		{ID: "lenient", Name: "Lenient", Expression: `rules.usesMissing || true`, Active: true},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	facts := map[string]any{"User": map[string]any{}}
+	if _, err := engine.Evaluate("strict", facts); err == nil || !strings.Contains(err.Error(), "referenced rule usesMissing failed") {
+		t.Errorf("Evaluate(strict) error = %v, want referenced rule failure", err)
+	}
+	result, err := engine.Evaluate("lenient", facts)
+	if err != nil || !result.Matched {
+		t.Errorf("Evaluate(lenient) = %+v, %v; want matched", result, err)
+	}
+}
+
+// TestRuleChainingReload verifies chained rules compile when an engine loads
+// them from its store
+func TestRuleChainingReload(t *testing.T) {
+	store := NewInMemoryRuleStore()
+	engine, _ := NewEngine(store)
+	if err := engine.AddRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age >= 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	if err := engine.AddRule(&Rule{ID: "check", Name: "Check", Expression: `rules.isAdult`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	reloaded, err := NewEngine(store)
+	if err != nil {
+		t.Fatalf("NewEngine() failed: %v", err)
+	}
+	result, err := reloaded.Evaluate("check", map[string]any{"User": map[string]any{"Age": 30}})
+	if err != nil || !result.Matched {
+		t.Errorf("Evaluate() = %+v, %v; want matched", result, err)
+	}
+}
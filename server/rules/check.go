@@ -0,0 +1,88 @@
+package rules
+
+// Compatibility is how a rule compiles against an engine's environment
+type Compatibility string
+
+const (
+	// CompatibilityTyped means the rule type-checks against the environment
+	CompatibilityTyped Compatibility = "typed"
+	// CompatibilityUntyped means the rule only compiles against the fallback
+	// environment, the way rules stored before schemas were typed are loaded
+	CompatibilityUntyped Compatibility = "untyped"
+	// CompatibilityNone means the rule does not compile, so the engine cannot
+	// load it while it is active
+	CompatibilityNone Compatibility = "none"
+)
+
+// Worse reports whether c is a worse result than other
+func (c Compatibility) Worse(other Compatibility) bool {
+	return compatibilityRank[c] > compatibilityRank[other]
+}
+
+var compatibilityRank = map[Compatibility]int{
+	CompatibilityTyped:   0,
+	CompatibilityUntyped: 1,
+	CompatibilityNone:    2,
+}
+
+// RuleCheck reports how a rule compiles against an engine's environment
+type RuleCheck struct {
+	Rule          *Rule
+	Compatibility Compatibility
+	Err           error // why the rule does not type-check, a *CompileError when its expression is at fault
+}
+
+// CheckRules compiles rules against the engine's environment without
+// installing or storing them, returning a check per rule in order
+// The active rules among them that compile can be referenced as
+// rules.<name>, so a rule referencing one that does not compile does not
+// compile either.
+func (en *Engine) CheckRules(rules []*Rule) ([]RuleCheck, error) {
+	en.mu.RLock()
+	env, fallbackEnv := en.env, en.fallbackEnv
+	en.mu.RUnlock()
+
+	var loadable []*Rule
+	for _, rule := range rules {
+		if rule.Active {
+			loadable = append(loadable, rule)
+		}
+	}
+
+	checks := make([]RuleCheck, len(rules))
+	for {
+		ruleEnv, ruleFallbackEnv, err := declareRuleReferences(env, fallbackEnv, ruleReferences(loadable))
+		if err != nil {
+			return nil, err
+		}
+
+		var next []*Rule
+		for i, rule := range rules {
+			checks[i] = RuleCheck{Rule: rule, Compatibility: CompatibilityTyped}
+			_, err := compileProgram(ruleEnv, rule.Expression, rule.OutputType, en.options)
+			if err == nil {
+				if rule.Active {
+					next = append(next, rule)
+				}
+				continue
+			}
+
+			checks[i].Err = err
+			checks[i].Compatibility = CompatibilityNone
+			if ruleFallbackEnv != nil {
+				if _, fallbackErr := compileProgram(ruleFallbackEnv, rule.Expression, rule.OutputType, en.options); fallbackErr == nil {
+					checks[i].Compatibility = CompatibilityUntyped
+					if rule.Active {
+						next = append(next, rule)
+					}
+				}
+			}
+		}
+
+		// Drop references to rules that no longer compile until none are left
+		if len(next) == len(loadable) {
+			return checks, nil
+		}
+		loadable = next
+	}
+}
@@ -0,0 +1,65 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+)
+
+// TestCheckRules verifies each rule is reported with how it compiles, without
+// being installed
+func TestCheckRules(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("User", cel.MapType(cel.StringType, cel.IntType)))
+	if err != nil {
+		t.Fatalf("cel.NewEnv() failed: %v", err)
+	}
+	fallbackEnv, err := cel.NewEnv(cel.Variable("User", cel.DynType))
+	if err != nil {
+		t.Fatalf("cel.NewEnv() failed: %v", err)
+	}
+	engine, err := NewEngineWithConfig(EngineConfig{Env: env, FallbackEnv: fallbackEnv, Store: NewInMemoryRuleStore()})
+	if err != nil {
+		t.Fatalf("NewEngineWithConfig() failed: %v", err)
+	}
+
+	rules := []*Rule{
+		{ID: "typed", Name: "typed", Expression: `User.Age > 18`, Active: true},
+		{ID: "untyped", Name: "untyped", Expression: `User.Name == "Ada"`, Active: true},
+		{ID: "broken", Name: "broken", Expression: `Usr.Age > 18`, Active: true},
+		{ID: "dependent", Name: "dependent", Expression: `rules.broken && rules.typed`, Active: true},
+		{ID: "inactive", Name: "inactive", Expression: `rules.typed`, Active: false},
+	}
+	want := []Compatibility{CompatibilityTyped, CompatibilityUntyped, CompatibilityNone, CompatibilityNone, CompatibilityTyped}
+
+	checks, err := engine.CheckRules(rules)
+	if err != nil {
+		t.Fatalf("CheckRules() failed: %v", err)
+	}
+	for i, check := range checks {
+		if check.Rule != rules[i] || check.Compatibility != want[i] {
+			t.Errorf("CheckRules()[%d] = %s %s, want %s %s", i, check.Rule.ID, check.Compatibility, rules[i].ID, want[i])
+		}
+		if (check.Err != nil) != (want[i] != CompatibilityTyped) {
+			t.Errorf("%s: Err = %v", check.Rule.ID, check.Err)
+		}
+	}
+
+	var compileErr *CompileError
+	if !errors.As(checks[2].Err, &compileErr) || len(compileErr.Diagnostics) == 0 {
+		t.Errorf("broken: Err = %v, want a CompileError with diagnostics", checks[2].Err)
+	}
+	if programs := engine.snapshot.Load().programs; len(programs) != 0 {
+		t.Errorf("CheckRules() installed %d programs, want none", len(programs))
+	}
+}
+
+// TestCompatibilityWorse verifies compatibility results are ordered
+func TestCompatibilityWorse(t *testing.T) {
+	if !CompatibilityNone.Worse(CompatibilityUntyped) || !CompatibilityUntyped.Worse(CompatibilityTyped) {
+		t.Error("expected none < untyped < typed")
+	}
+	if CompatibilityTyped.Worse(CompatibilityTyped) || CompatibilityTyped.Worse(CompatibilityNone) {
+		t.Error("typed should not be worse than anything")
+	}
+}
@@ -0,0 +1,112 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+)
+
+// ErrCostBudgetExceeded is returned when a rule's estimated worst-case cost
+// exceeds the engine's cost budget
+var ErrCostBudgetExceeded = errors.New("rule exceeds cost budget")
+
+// CostEstimate is the static estimate of a rule's evaluation cost, in CEL
+// cost units
+// Max is math.MaxUint64 when the cost is unbounded, e.g. a comprehension over
+// a list whose size the schema does not bound.
+type CostEstimate struct {
+	Min uint64
+	Max uint64
+}
+
+// Unbounded reports whether no worst-case cost could be established
+func (c CostEstimate) Unbounded() bool {
+	return c.Max == math.MaxUint64
+}
+
+// String renders the estimate as "min..max"
+func (c CostEstimate) String() string {
+	return formatCost(c.Min) + ".." + formatCost(c.Max)
+}
+
+// formatCost renders a cost, or "unbounded" for math.MaxUint64
+func formatCost(cost uint64) string {
+	if cost == math.MaxUint64 {
+		return "unbounded"
+	}
+	return strconv.FormatUint(cost, 10)
+}
+
+// costEstimator leaves every size and call cost to CEL's defaults
+// Facts are maps of unknown size, so lists and strings from facts are assumed
+// to be of any length.
+type costEstimator struct{}
+
+// EstimateSize returns nil, so CEL assumes any size
+func (costEstimator) EstimateSize(checker.AstNode) *checker.SizeEstimate {
+	return nil
+}
+
+// EstimateCallCost returns nil, so CEL uses its default cost for the call
+func (costEstimator) EstimateCallCost(function, overloadID string, target *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	return nil
+}
+
+// estimateCost runs CEL's static cost estimator over a checked expression
+// References to other rules count as variable reads; the cost of the
+// referenced rules is estimated separately
+func estimateCost(env *cel.Env, checked *cel.Ast) (CostEstimate, error) {
+	estimate, err := env.EstimateCost(checked, costEstimator{})
+	if err != nil {
+		return CostEstimate{}, fmt.Errorf("cost estimation error: %w", err)
+	}
+	return CostEstimate{Min: estimate.Min, Max: estimate.Max}, nil
+}
+
+// checkCostBudget rejects an estimate whose worst case exceeds budget
+// A budget of 0 means no limit
+func checkCostBudget(cost CostEstimate, budget uint64) error {
+	if budget == 0 || cost.Max <= budget {
+		return nil
+	}
+	return fmt.Errorf("%w: estimated worst-case cost %s exceeds budget %d", ErrCostBudgetExceeded, formatCost(cost.Max), budget)
+}
+
+// MaxCost returns the engine's cost budget; 0 means no limit
+func (en *Engine) MaxCost() uint64 {
+	en.mu.RLock()
+	defer en.mu.RUnlock()
+	return en.maxCost
+}
+
+// SetMaxCost changes the cost budget checked by AddRule and UpdateRule
+// Rules already stored are not rechecked; 0 removes the limit
+func (en *Engine) SetMaxCost(budget uint64) {
+	en.mu.Lock()
+	en.maxCost = budget
+	en.mu.Unlock()
+}
+
+// EstimateRuleCost returns the estimated cost of a rule, compiling it against
+// the engine's environment when it has no compiled program, e.g. because it
+// is inactive
+func (en *Engine) EstimateRuleCost(rule *Rule) (CostEstimate, error) {
+	compiled := en.snapshot.Load().programs[rule.ID]
+	en.mu.RLock()
+	env := en.ruleEnv
+	en.mu.RUnlock()
+
+	if compiled != nil {
+		return compiled.cost, nil
+	}
+
+	compiled, err := compileProgram(env, rule.Expression, rule.OutputType, en.options)
+	if err != nil {
+		return CostEstimate{}, err
+	}
+	return compiled.cost, nil
+}
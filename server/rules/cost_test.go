@@ -0,0 +1,79 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestEstimateRuleCost verifies rules carry a static cost estimate
+func TestEstimateRuleCost(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+
+	testCases := []struct {
+		name       string
+		expression string
+		unbounded  bool
+	}{
+		{"Comparison", `User.Age > 18`, false},
+		{"Literal list comprehension", `[1, 2, 3].all(x, x > 0)`, false},
+		{"Fact list comprehension", `User.Tags.exists(t, t == "vip")`, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cost, err := engine.EstimateRuleCost(&Rule{ID: "r1", Expression: tc.expression})
+			if err != nil {
+				t.Fatalf("EstimateRuleCost() failed: %v", err)
+			}
+			if cost.Unbounded() != tc.unbounded {
+				t.Errorf("EstimateRuleCost() = %s, want unbounded %v", cost, tc.unbounded)
+			}
+			if cost.Min > cost.Max {
+				t.Errorf("EstimateRuleCost() = %s, min exceeds max", cost)
+			}
+		})
+	}
+
+	// Compiled rules report the estimate of their program
+	if err := engine.AddRule(&Rule{ID: "r1", Name: "r1", Expression: `User.Age > 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	cost, err := engine.EstimateRuleCost(&Rule{ID: "r1"})
+	if err != nil || cost.Max == 0 {
+		t.Errorf("EstimateRuleCost(r1) = %s, %v; want the compiled rule's cost", cost, err)
+	}
+}
+
+// TestCostBudget verifies AddRule and UpdateRule reject rules whose worst-case
+// cost exceeds the engine's budget
+func TestCostBudget(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	engine.SetMaxCost(50)
+
+	if err := engine.AddRule(&Rule{ID: "cheap", Name: "cheap", Expression: `User.Age > 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule(cheap) failed: %v", err)
+	}
+
+	nested := `[1, 2, 3, 4, 5].all(x, [1, 2, 3, 4, 5].all(y, x + y > 0))`
+	err := engine.AddRule(&Rule{ID: "nested", Name: "nested", Expression: nested, Active: true})
+	if !errors.Is(err, ErrCostBudgetExceeded) {
+		t.Errorf("AddRule(nested) error = %v, want ErrCostBudgetExceeded", err)
+	}
+
+	err = engine.UpdateRule(&Rule{ID: "cheap", Name: "cheap", Expression: `User.Tags.exists(t, t == "vip")`, Active: true})
+	if !errors.Is(err, ErrCostBudgetExceeded) {
+		t.Errorf("UpdateRule() error = %v, want ErrCostBudgetExceeded", err)
+	}
+
+	// Inactive rules are checked too, so they can be activated later
+	err = engine.AddRule(&Rule{ID: "inactive", Name: "inactive", Expression: nested})
+	if !errors.Is(err, ErrCostBudgetExceeded) {
+		t.Errorf("AddRule(inactive) error = %v, want ErrCostBudgetExceeded", err)
+	}
+
+	// Removing the budget accepts the rule
+	engine.SetMaxCost(0)
+	if err := engine.AddRule(&Rule{ID: "nested", Name: "nested", Expression: nested, Active: true}); err != nil {
+		t.Errorf("AddRule(nested) without a budget failed: %v", err)
+	}
+}
@@ -0,0 +1,525 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// derivedFieldNamePattern matches valid CEL identifiers
+var derivedFieldNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// compiledDerivedField holds a derived field and its compiled program
+type compiledDerivedField struct {
+	field   *DerivedField
+	program cel.Program
+	fields  []string // fields read, with derived fields replaced by the fields they read
+}
+
+// derivedState is the set of derived fields compiled in dependency order,
+// together with the environments that declare them as variables
+type derivedState struct {
+	env         *cel.Env
+	fallbackEnv *cel.Env
+	fields      []*compiledDerivedField
+}
+
+// validateDerivedFieldName checks a name can be declared as a new top-level variable
+func validateDerivedFieldName(env *cel.Env, name string) error {
+	if !derivedFieldNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid derived field name %q: must be a valid identifier", name)
+	}
+
+	parsed, issues := env.Parse(name)
+	if issues != nil && issues.Err() != nil {
+		return fmt.Errorf("invalid derived field name %q: reserved word", name)
+	}
+	if parsed.NativeRep().Expr().Kind() != ast.IdentKind {
+		return fmt.Errorf("invalid derived field name %q: reserved word", name)
+	}
+
+	// An identifier that already type-checks is a schema object or builtin type
+	if _, issues := env.Compile(name); issues == nil || issues.Err() == nil {
+		return fmt.Errorf("invalid derived field name %q: conflicts with an existing variable", name)
+	}
+
+	return nil
+}
+
+// derivedDependencies returns the other derived fields an expression reads
+// env and fallbackEnv declare every derived field as dyn. The expression is
+// walked after type-checking, so identifiers are matched as the checker
+// resolved them and comprehension variables that shadow a derived field are
+// not counted as reading it.
+func derivedDependencies(env, fallbackEnv *cel.Env, expression string, names map[string]bool) ([]string, error) {
+	checked, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil && fallbackEnv != nil {
+		checked, issues = fallbackEnv.Compile(expression)
+	}
+	if issues != nil && issues.Err() != nil {
+		return nil, newCompileError(expression, issues)
+	}
+
+	seen := make(map[string]bool)
+	collectDerivedReferences(checked.NativeRep().Expr(), nil, names, seen)
+
+	deps := make([]string, 0, len(seen))
+	for name := range seen {
+		deps = append(deps, name)
+	}
+	sort.Strings(deps)
+	return deps, nil
+}
+
+// collectDerivedReferences records in seen the derived fields e reads, skipping
+// identifiers bound by an enclosing comprehension
+// Checking rewrites selections that resolve to a variable, such as a
+// qualified name, to identifiers, so every variable read is an ident node.
+func collectDerivedReferences(e ast.Expr, bound []string, names, seen map[string]bool) {
+	switch e.Kind() {
+	case ast.IdentKind:
+		if name := e.AsIdent(); names[name] && !slices.Contains(bound, name) {
+			seen[name] = true
+		}
+	case ast.SelectKind:
+		collectDerivedReferences(e.AsSelect().Operand(), bound, names, seen)
+	case ast.CallKind:
+		call := e.AsCall()
+		if call.IsMemberFunction() {
+			collectDerivedReferences(call.Target(), bound, names, seen)
+		}
+		for _, arg := range call.Args() {
+			collectDerivedReferences(arg, bound, names, seen)
+		}
+	case ast.ListKind:
+		for _, elem := range e.AsList().Elements() {
+			collectDerivedReferences(elem, bound, names, seen)
+		}
+	case ast.MapKind:
+		for _, entry := range e.AsMap().Entries() {
+			collectDerivedReferences(entry.AsMapEntry().Key(), bound, names, seen)
+			collectDerivedReferences(entry.AsMapEntry().Value(), bound, names, seen)
+		}
+	case ast.StructKind:
+		for _, field := range e.AsStruct().Fields() {
+			collectDerivedReferences(field.AsStructField().Value(), bound, names, seen)
+		}
+	case ast.ComprehensionKind:
+		comp := e.AsComprehension()
+		collectDerivedReferences(comp.IterRange(), bound, names, seen)
+		collectDerivedReferences(comp.AccuInit(), bound, names, seen)
+
+		// The accumulator is in scope for the loop and result, the iteration
+		// variables for the loop only
+		withAccu := append(slices.Clip(bound), comp.AccuVar())
+		collectDerivedReferences(comp.Result(), withAccu, names, seen)
+		inLoop := append(slices.Clip(withAccu), comp.IterVar())
+		if comp.HasIterVar2() {
+			inLoop = append(inLoop, comp.IterVar2())
+		}
+		collectDerivedReferences(comp.LoopCondition(), inLoop, names, seen)
+		collectDerivedReferences(comp.LoopStep(), inLoop, names, seen)
+	}
+}
+
+// declareDerivedNames extends env with every derived field declared as dyn,
+// so expressions can be checked before the fields' types are known
+func declareDerivedNames(env *cel.Env, names map[string]bool) (*cel.Env, error) {
+	if env == nil {
+		return nil, nil
+	}
+	vars := make([]cel.EnvOption, 0, len(names))
+	for name := range names {
+		vars = append(vars, cel.Variable(name, cel.DynType))
+	}
+	return env.Extend(vars...)
+}
+
+// orderDerivedFields sorts fields so every field follows its dependencies
+// Returns an error naming the cycle if the dependency graph is not acyclic
+func orderDerivedFields(fields []*DerivedField) ([]*DerivedField, error) {
+	byName := make(map[string]*DerivedField, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		byName[field.Name] = field
+		names = append(names, field.Name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(fields))
+	ordered := make([]*DerivedField, 0, len(fields))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("derived field cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range byName[name].Dependencies {
+			if _, ok := byName[dep]; !ok {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		ordered = append(ordered, byName[name])
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// buildDerivedState resolves dependencies, orders the fields and compiles each
+// against an environment declaring the fields before it. Fields that only
+// compile against fallbackEnv are declared as dyn; strict names the field being
+// changed, which must compile against the primary environment.
+func buildDerivedState(env, fallbackEnv *cel.Env, fields []*DerivedField, strict string, costLimit uint64) (*derivedState, error) {
+	names := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		names[field.Name] = true
+	}
+
+	depsEnv, err := declareDerivedNames(env, names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to declare derived fields: %w", err)
+	}
+	depsFallbackEnv, err := declareDerivedNames(fallbackEnv, names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to declare derived fields: %w", err)
+	}
+
+	for _, field := range fields {
+		deps, err := derivedDependencies(depsEnv, depsFallbackEnv, field.Expression, names)
+		if err != nil {
+			return nil, fmt.Errorf("derived field %s: %w", field.Name, err)
+		}
+		field.Dependencies = deps
+	}
+
+	ordered, err := orderDerivedFields(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &derivedState{
+		env:         env,
+		fallbackEnv: fallbackEnv,
+		fields:      make([]*compiledDerivedField, 0, len(ordered)),
+	}
+
+	expanded := make(map[string][]string, len(ordered))
+	for _, field := range ordered {
+		program, checked, err := compileDerivedProgram(state.env, field.Expression, costLimit)
+		outputType := cel.DynType
+		if err == nil {
+			outputType = checked.OutputType()
+		} else if state.fallbackEnv != nil && field.Name != strict {
+			program, checked, err = compileDerivedProgram(state.fallbackEnv, field.Expression, costLimit)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("derived field %s: %w", field.Name, err)
+		}
+		expanded[field.Name] = expandDerivedFields(referencedFields(checked), expanded)
+
+		state.env, err = state.env.Extend(cel.Variable(field.Name, outputType))
+		if err != nil {
+			return nil, fmt.Errorf("failed to declare derived field %s: %w", field.Name, err)
+		}
+		if state.fallbackEnv != nil {
+			state.fallbackEnv, err = state.fallbackEnv.Extend(cel.Variable(field.Name, cel.DynType))
+			if err != nil {
+				return nil, fmt.Errorf("failed to declare derived field %s: %w", field.Name, err)
+			}
+		}
+
+		state.fields = append(state.fields, &compiledDerivedField{
+			field:   field,
+			program: program,
+			fields:  expanded[field.Name],
+		})
+	}
+
+	return state, nil
+}
+
+// compileDerivedProgram compiles a derived field expression, returning the
+// program and the checked AST, whose output type the field is declared with
+func compileDerivedProgram(env *cel.Env, expression string, costLimit uint64) (cel.Program, *cel.Ast, error) {
+	checked, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, nil, newCompileError(expression, issues)
+	}
+
+	prog, err := env.Program(checked,
+		cel.CostLimit(costLimit),
+		cel.InterruptCheckFrequency(interruptCheckFrequency),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("program creation error: %w", err)
+	}
+
+	return prog, checked, nil
+}
+
+// computeDerivedFields returns a copy of facts with each derived field set
+// Fields are computed in dependency order so later fields can read earlier
+// ones; a field whose evaluation fails is left unset, so rules referencing it
+// report the missing attribute as an evaluation error
+func computeDerivedFields(ctx context.Context, fields []*compiledDerivedField, facts map[string]any) map[string]any {
+	if len(fields) == 0 {
+		return facts
+	}
+
+	activation := make(map[string]any, len(facts)+len(fields))
+	for k, v := range facts {
+		activation[k] = v
+	}
+
+	for _, compiled := range fields {
+		if ctx.Err() != nil {
+			break
+		}
+		out, _, err := compiled.program.ContextEval(ctx, activation)
+		if err != nil {
+			continue
+		}
+		activation[compiled.field.Name] = out
+	}
+
+	return activation
+}
+
+// DerivedFields returns the engine's derived fields in evaluation order
+func (en *Engine) DerivedFields() []*DerivedField {
+	derived := en.snapshot.Load().derived
+	fields := make([]*DerivedField, 0, len(derived))
+	for _, compiled := range derived {
+		fields = append(fields, compiled.field)
+	}
+	return fields
+}
+
+// AddDerivedField validates, compiles and stores a new derived field
+// Every active rule is recompiled against the new environment before the
+// field is stored, so the change is rejected if it would break a rule
+func (en *Engine) AddDerivedField(field *DerivedField) error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	if err := validateDerivedFieldName(en.baseEnv, field.Name); err != nil {
+		return err
+	}
+
+	fields, err := en.derivedStore.List()
+	if err != nil {
+		return err
+	}
+	for _, existing := range fields {
+		if existing.Name == field.Name {
+			return fmt.Errorf("derived field %s already exists", field.Name)
+		}
+	}
+
+	candidate := append(cloneDerivedFields(fields), field)
+	return en.applyDerivedFields(candidate, field.Name, func() error {
+		return en.derivedStore.Add(field)
+	})
+}
+
+// UpdateDerivedField replaces the expression of an existing derived field
+func (en *Engine) UpdateDerivedField(field *DerivedField) error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	fields, err := en.derivedStore.List()
+	if err != nil {
+		return err
+	}
+
+	candidate := cloneDerivedFields(fields)
+	found := false
+	for i, existing := range candidate {
+		if existing.Name == field.Name {
+			candidate[i] = field
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("derived field %s not found", field.Name)
+	}
+
+	return en.applyDerivedFields(candidate, field.Name, func() error {
+		return en.derivedStore.Update(field)
+	})
+}
+
+// DeleteDerivedField removes a derived field
+// Deletion is rejected while a rule or another derived field references it
+func (en *Engine) DeleteDerivedField(name string) error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	fields, err := en.derivedStore.List()
+	if err != nil {
+		return err
+	}
+
+	candidate := make([]*DerivedField, 0, len(fields))
+	for _, existing := range cloneDerivedFields(fields) {
+		if existing.Name != name {
+			candidate = append(candidate, existing)
+		}
+	}
+	if len(candidate) == len(fields) {
+		return fmt.Errorf("derived field %s not found", name)
+	}
+
+	return en.applyDerivedFields(candidate, "", func() error {
+		return en.derivedStore.Delete(name)
+	})
+}
+
+// ReloadDerivedFields brings the engine's derived fields up to date with the
+// derived field store, e.g. after another replica changed them, recompiling
+// active rules against them
+func (en *Engine) ReloadDerivedFields() error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	fields, err := en.derivedStore.List()
+	if err != nil {
+		return fmt.Errorf("failed to load derived fields: %w", err)
+	}
+
+	return en.applyDerivedFields(cloneDerivedFields(fields), "", func() error {
+		return nil
+	})
+}
+
+// applyDerivedFields compiles the candidate derived fields and every active
+// rule against them, persists the change, then swaps in the new state
+// Callers must hold writeMu
+func (en *Engine) applyDerivedFields(fields []*DerivedField, strict string, persist func() error) error {
+	return en.rebuildEnv(envChange{
+		baseEnv:         en.baseEnv,
+		baseFallbackEnv: en.baseFallbackEnv,
+		lists:           en.snapshot.Load().lists,
+		fields:          fields,
+		strict:          strict,
+		description:     "derived field change",
+		persist:         persist,
+	})
+}
+
+// envChange is a change to the variables rules are compiled against
+type envChange struct {
+	baseEnv         *cel.Env // schema and reference list variables
+	baseFallbackEnv *cel.Env
+	lists           map[string]ref.Val
+	fields          []*DerivedField // derived fields declared on top of baseEnv
+	strict          string          // derived field that must compile against baseEnv
+	description     string          // e.g. "derived field change", used in errors
+	persist         func() error
+}
+
+// rebuildEnv compiles the derived fields of change and every active rule
+// against its environment, persists the change, then swaps in the new state
+// Callers must hold writeMu
+func (en *Engine) rebuildEnv(change envChange) error {
+	state, err := buildDerivedState(change.baseEnv, change.baseFallbackEnv, change.fields, change.strict, en.options.costLimit())
+	if err != nil {
+		return err
+	}
+
+	rules, err := en.store.ListActive()
+	if err != nil {
+		return err
+	}
+
+	refs := ruleReferences(rules)
+	ruleEnv, ruleFallbackEnv, err := declareRuleReferences(state.env, state.fallbackEnv, refs)
+	if err != nil {
+		return err
+	}
+
+	programs := make(map[string]*compiledRule, len(rules))
+	legacy := make(map[string]bool)
+	for _, rule := range rules {
+		compiled, err := compileProgram(ruleEnv, rule.Expression, rule.OutputType, en.options)
+		if err != nil && ruleFallbackEnv != nil && en.IsLegacyRule(rule.ID) {
+			compiled, err = compileProgram(ruleFallbackEnv, rule.Expression, rule.OutputType, en.options)
+			legacy[rule.ID] = true
+		}
+		if err != nil {
+			return fmt.Errorf("%s breaks rule %s: %w", change.description, rule.ID, err)
+		}
+		programs[rule.ID] = compiled
+	}
+
+	if err := change.persist(); err != nil {
+		return err
+	}
+
+	en.publish(func(next *ruleSnapshot) {
+		en.baseEnv = change.baseEnv
+		en.baseFallbackEnv = change.baseFallbackEnv
+		en.env = state.env
+		en.fallbackEnv = state.fallbackEnv
+		en.ruleEnv = ruleEnv
+		en.ruleFallbackEnv = ruleFallbackEnv
+		next.lists = change.lists
+		next.derived = state.fields
+		next.refs = refs
+		next.setPrograms(programs, legacy)
+		next.setRules(rules)
+	})
+
+	return nil
+}
+
+// cloneDerivedFields copies fields so dependency resolution does not mutate
+// values owned by the store
+func cloneDerivedFields(fields []*DerivedField) []*DerivedField {
+	clones := make([]*DerivedField, 0, len(fields))
+	for _, field := range fields {
+		clone := *field
+		clones = append(clones, &clone)
+	}
+	return clones
+}
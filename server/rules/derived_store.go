@@ -0,0 +1,109 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DerivedFieldStore manages derived field persistence and retrieval
+// Derived fields are identified by name, which is unique per tenant
+type DerivedFieldStore interface {
+	// Add a new derived field
+	Add(field *DerivedField) error
+
+	// Get a derived field by name
+	Get(name string) (*DerivedField, error)
+
+	// List all derived fields
+	List() ([]*DerivedField, error)
+
+	// Update an existing derived field
+	Update(field *DerivedField) error
+
+	// Delete a derived field
+	Delete(name string) error
+}
+
+// InMemoryDerivedFieldStore implements DerivedFieldStore using an in-memory map
+type InMemoryDerivedFieldStore struct {
+	fields map[string]*DerivedField
+	mu     sync.RWMutex
+}
+
+// NewInMemoryDerivedFieldStore creates a new in-memory derived field store
+func NewInMemoryDerivedFieldStore() *InMemoryDerivedFieldStore {
+	return &InMemoryDerivedFieldStore{
+		fields: make(map[string]*DerivedField),
+	}
+}
+
+// Add adds a new derived field to the store
+func (s *InMemoryDerivedFieldStore) Add(field *DerivedField) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.fields[field.Name]; exists {
+		return fmt.Errorf("derived field %s already exists", field.Name)
+	}
+
+	field.CreatedAt = time.Now()
+	s.fields[field.Name] = field
+	return nil
+}
+
+// Get retrieves a derived field by name
+func (s *InMemoryDerivedFieldStore) Get(name string) (*DerivedField, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	field, exists := s.fields[name]
+	if !exists {
+		return nil, fmt.Errorf("derived field %s not found", name)
+	}
+	return field, nil
+}
+
+// List returns all derived fields ordered by name
+func (s *InMemoryDerivedFieldStore) List() ([]*DerivedField, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fields := make([]*DerivedField, 0, len(s.fields))
+	for _, field := range s.fields {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+	return fields, nil
+}
+
+// Update updates an existing derived field, preserving CreatedAt
+func (s *InMemoryDerivedFieldStore) Update(field *DerivedField) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.fields[field.Name]
+	if !exists {
+		return fmt.Errorf("derived field %s not found", field.Name)
+	}
+
+	field.CreatedAt = existing.CreatedAt
+	s.fields[field.Name] = field
+	return nil
+}
+
+// Delete removes a derived field from the store
+func (s *InMemoryDerivedFieldStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.fields[name]; !exists {
+		return fmt.Errorf("derived field %s not found", name)
+	}
+
+	delete(s.fields, name)
+	return nil
+}
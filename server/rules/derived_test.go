@@ -0,0 +1,320 @@
+package rules
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+)
+
+// TestDerivedFieldsInRules verifies rules can reference derived fields as variables
+func TestDerivedFieldsInRules(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	for _, field := range []*DerivedField{
+		{Name: "isAdult", Expression: `User.Age >= 18`},
+		{Name: "isLargeTransaction", Expression: `Transaction.Amount > 1000`},
+	} {
+		if err := engine.AddDerivedField(field); err != nil {
+			t.Fatalf("AddDerivedField(%s) failed: %v", field.Name, err)
+		}
+	}
+
+	rule := &Rule{ID: "r1", Name: "Adult large", Expression: `isAdult && isLargeTransaction`, Active: true}
+	if err := engine.AddRule(rule); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	testCases := []struct {
+		name  string
+		facts map[string]any
+		want  bool
+	}{
+		{"Both true", map[string]any{"User": map[string]any{"Age": 30}, "Transaction": map[string]any{"Amount": 5000}}, true},
+		{"Minor", map[string]any{"User": map[string]any{"Age": 15}, "Transaction": map[string]any{"Amount": 5000}}, false},
+		{"Small transaction", map[string]any{"User": map[string]any{"Age": 30}, "Transaction": map[string]any{"Amount": 10}}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := engine.Evaluate("r1", tc.facts)
+			if err != nil {
+				t.Fatalf("Evaluate() failed: %v", err)
+			}
+			if result.Matched != tc.want {
+				t.Errorf("Matched = %v, want %v", result.Matched, tc.want)
+			}
+
+			results, err := engine.EvaluateAll(tc.facts)
+			if err != nil {
+				t.Fatalf("EvaluateAll() failed: %v", err)
+			}
+			if len(results) != 1 || results[0].Matched != tc.want {
+				t.Errorf("EvaluateAll() Matched = %v, want %v", results[0].Matched, tc.want)
+			}
+		})
+	}
+}
+
+// TestDerivedFieldsDependencyOrder verifies derived fields may build on each other
+// regardless of the order they were added in the store
+func TestDerivedFieldsDependencyOrder(t *testing.T) {
+	store := NewInMemoryDerivedFieldStore()
+	for _, field := range []*DerivedField{
+		{Name: "a_risky", Expression: `b_adult && Transaction.Amount > 1000`},
+		{Name: "b_adult", Expression: `User.Age >= 18`},
+	} {
+		if err := store.Add(field); err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("User", cel.DynType),
+		cel.Variable("Transaction", cel.DynType),
+	)
+	if err != nil {
+		t.Fatalf("cel.NewEnv() failed: %v", err)
+	}
+
+	engine, err := NewEngineWithConfig(EngineConfig{
+		Env:          env,
+		Store:        NewInMemoryRuleStore(),
+		DerivedStore: store,
+	})
+	if err != nil {
+		t.Fatalf("NewEngineWithConfig() failed: %v", err)
+	}
+
+	var order []string
+	for _, field := range engine.DerivedFields() {
+		order = append(order, field.Name)
+	}
+	if want := []string{"b_adult", "a_risky"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("derived field order = %v, want %v", order, want)
+	}
+
+	if err := engine.AddRule(&Rule{ID: "r1", Name: "Risky", Expression: `a_risky`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	result, err := engine.Evaluate("r1", map[string]any{
+		"User":        map[string]any{"Age": 30},
+		"Transaction": map[string]any{"Amount": 5000},
+	})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if !result.Matched {
+		t.Error("expected rule using chained derived fields to match")
+	}
+}
+
+// TestDerivedFieldsCycleDetection verifies cyclic derived fields are rejected
+func TestDerivedFieldsCycleDetection(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	for _, field := range []*DerivedField{
+		{Name: "a", Expression: `User.Age >= 18`},
+		{Name: "b", Expression: `a && User.Age < 65`},
+	} {
+		if err := engine.AddDerivedField(field); err != nil {
+			t.Fatalf("AddDerivedField(%s) failed: %v", field.Name, err)
+		}
+	}
+
+	err := engine.UpdateDerivedField(&DerivedField{Name: "a", Expression: `b || User.Age >= 18`})
+	if err == nil {
+		t.Fatal("expected cycle to be rejected")
+	}
+	if !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("error = %v, want cycle error", err)
+	}
+
+	err = engine.AddDerivedField(&DerivedField{Name: "self", Expression: `self`})
+	if err == nil {
+		t.Fatal("expected self reference to be rejected")
+	}
+}
+
+// TestDerivedFieldsCycleThroughMemberAccess verifies cycles are found when a
+// field is read through member access or inside a comprehension
+func TestDerivedFieldsCycleThroughMemberAccess(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	for _, field := range []*DerivedField{
+		{Name: "profile", Expression: `{"age": User.Age}`},
+		{Name: "adult", Expression: `profile.age >= 18`},
+	} {
+		if err := engine.AddDerivedField(field); err != nil {
+			t.Fatalf("AddDerivedField(%s) failed: %v", field.Name, err)
+		}
+	}
+
+	for _, expression := range []string{
+		`{"age": User.Age, "adult": adult}`,
+		`{"age": User.Age, "adult": [1].exists(x, adult)}`,
+	} {
+		err := engine.UpdateDerivedField(&DerivedField{Name: "profile", Expression: expression})
+		if err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Errorf("UpdateDerivedField(%s) error = %v, want cycle error", expression, err)
+		}
+	}
+}
+
+// TestDerivedFieldsShadowedByComprehension verifies a comprehension variable
+// named like a derived field is not a dependency on it
+func TestDerivedFieldsShadowedByComprehension(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	for _, field := range []*DerivedField{
+		{Name: "vip", Expression: `User.Tags.exists(adult, adult == "vip")`},
+		{Name: "adult", Expression: `vip || User.Age >= 18`},
+	} {
+		if err := engine.AddDerivedField(field); err != nil {
+			t.Fatalf("AddDerivedField(%s) failed: %v", field.Name, err)
+		}
+	}
+
+	var order []string
+	for _, field := range engine.DerivedFields() {
+		order = append(order, field.Name)
+	}
+	if want := []string{"vip", "adult"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("derived field order = %v, want %v", order, want)
+	}
+
+	if err := engine.AddRule(&Rule{ID: "r1", Name: "Adult", Expression: `adult`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	result, err := engine.Evaluate("r1", map[string]any{"User": map[string]any{"Age": 10, "Tags": []any{"vip"}}})
+	if err != nil || !result.Matched {
+		t.Errorf("Evaluate() = %+v, %v; want matched", result, err)
+	}
+}
+
+// TestDerivedFieldsCompileErrors verifies typos are caught when fields and rules are compiled
+func TestDerivedFieldsCompileErrors(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	if err := engine.AddDerivedField(&DerivedField{Name: "isAdult", Expression: `User.Age >= 18`}); err != nil {
+		t.Fatalf("AddDerivedField() failed: %v", err)
+	}
+
+	if err := engine.AddRule(&Rule{ID: "r1", Name: "Typo", Expression: `isAdlt`, Active: true}); err == nil {
+		t.Error("expected rule referencing unknown derived field to fail compilation")
+	}
+	if err := engine.AddDerivedField(&DerivedField{Name: "isSenior", Expression: `isAdlt && User.Age >= 65`}); err == nil {
+		t.Error("expected derived field referencing unknown derived field to fail compilation")
+	}
+}
+
+// TestDerivedFieldNames verifies derived fields cannot shadow variables or use invalid names
+func TestDerivedFieldNames(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+
+	testCases := []struct {
+		name      string
+		fieldName string
+	}{
+		{"Schema object", "User"},
+		{"Builtin type", "int"},
+		{"Reserved word", "true"},
+		{"Invalid identifier", "is-adult"},
+		{"Empty", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := engine.AddDerivedField(&DerivedField{Name: tc.fieldName, Expression: `true`})
+			if err == nil {
+				t.Errorf("expected name %q to be rejected", tc.fieldName)
+			}
+		})
+	}
+}
+
+// TestDeleteDerivedFieldInUse verifies fields referenced by rules cannot be deleted
+func TestDeleteDerivedFieldInUse(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	if err := engine.AddDerivedField(&DerivedField{Name: "isAdult", Expression: `User.Age >= 18`}); err != nil {
+		t.Fatalf("AddDerivedField() failed: %v", err)
+	}
+	if err := engine.AddRule(&Rule{ID: "r1", Name: "Adult", Expression: `isAdult`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	if err := engine.DeleteDerivedField("isAdult"); err == nil {
+		t.Fatal("expected deleting a referenced derived field to fail")
+	}
+	if len(engine.DerivedFields()) != 1 {
+		t.Error("derived field should remain after rejected delete")
+	}
+
+	if err := engine.DeleteRule("r1"); err != nil {
+		t.Fatalf("DeleteRule() failed: %v", err)
+	}
+	if err := engine.DeleteDerivedField("isAdult"); err != nil {
+		t.Fatalf("DeleteDerivedField() failed: %v", err)
+	}
+	if len(engine.DerivedFields()) != 0 {
+		t.Error("derived field should be removed")
+	}
+}
+
+// TestUpdateDerivedFieldBreakingRule verifies type changes that break rules are rejected
+func TestUpdateDerivedFieldBreakingRule(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	if err := engine.AddDerivedField(&DerivedField{Name: "isAdult", Expression: `User.Age >= 18`}); err != nil {
+		t.Fatalf("AddDerivedField() failed: %v", err)
+	}
+	if err := engine.AddRule(&Rule{ID: "r1", Name: "Adult", Expression: `isAdult && true`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	err := engine.UpdateDerivedField(&DerivedField{Name: "isAdult", Expression: `"yes"`})
+	if err == nil {
+		t.Fatal("expected update that breaks a rule to fail")
+	}
+
+	result, err := engine.Evaluate("r1", map[string]any{"User": map[string]any{"Age": 30}})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if !result.Matched {
+		t.Error("rule should still evaluate against the original derived field")
+	}
+}
+
+// TestReloadDerivedFields verifies an engine picks up derived fields changed
+// through another engine over the same stores
+func TestReloadDerivedFields(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("User", cel.DynType))
+	if err != nil {
+		t.Fatalf("cel.NewEnv() failed: %v", err)
+	}
+	store := NewInMemoryRuleStore()
+	derivedStore := NewInMemoryDerivedFieldStore()
+	newEngine := func() *Engine {
+		engine, err := NewEngineWithConfig(EngineConfig{Env: env, Store: store, DerivedStore: derivedStore})
+		if err != nil {
+			t.Fatalf("NewEngineWithConfig() failed: %v", err)
+		}
+		return engine
+	}
+
+	writer := newEngine()
+	reader := newEngine()
+	if err := writer.AddDerivedField(&DerivedField{Name: "isAdult", Expression: `User.Age >= 18`}); err != nil {
+		t.Fatalf("AddDerivedField() failed: %v", err)
+	}
+	if err := writer.AddRule(&Rule{ID: "r1", Name: "r1", Expression: `isAdult`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	if err := reader.ReloadDerivedFields(); err != nil {
+		t.Fatalf("ReloadDerivedFields() failed: %v", err)
+	}
+	if len(reader.DerivedFields()) != 1 {
+		t.Errorf("DerivedFields() = %d fields, want 1", len(reader.DerivedFields()))
+	}
+	if result, err := reader.Evaluate("r1", map[string]any{"User": map[string]any{"Age": 30}}); err != nil || !result.Matched {
+		t.Errorf("Evaluate() = %+v, %v; want matched", result, err)
+	}
+}
@@ -0,0 +1,89 @@
+package rules
+
+import (
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+)
+
+// Severity is how serious a compile diagnostic is
+type Severity string
+
+const (
+	// SeverityError means the expression cannot be compiled
+	SeverityError Severity = "error"
+)
+
+// Diagnostic is a problem found while compiling an expression, located in
+// its source
+// Line and Column are 1-based and Offset is the 0-based character offset, so
+// an editor can underline the problem; all three are 0 when the problem has no
+// position. Snippet is the source line the problem is on.
+type Diagnostic struct {
+	Line     int
+	Column   int
+	Offset   int
+	Severity Severity
+	Message  string
+	Snippet  string
+}
+
+// CompileError is returned when an expression does not compile
+// AddRule and UpdateRule wrap it, so use errors.As to extract it. Expression is
+// the source the diagnostics refer to; when a change breaks another rule that
+// references it, this is the other rule's expression.
+type CompileError struct {
+	Expression  string
+	Diagnostics []Diagnostic
+	err         error
+}
+
+// Error keeps the format of CEL's issue report, prefixed with "compile error"
+func (e *CompileError) Error() string {
+	return "compile error: " + e.err.Error()
+}
+
+// Unwrap returns the underlying CEL error
+func (e *CompileError) Unwrap() error {
+	return e.err
+}
+
+// newCompileError converts the issues reported by CEL for expression
+func newCompileError(expression string, issues *cel.Issues) *CompileError {
+	source := common.NewTextSource(expression)
+	errs := issues.Errors()
+	diagnostics := make([]Diagnostic, 0, len(errs))
+	for _, issue := range errs {
+		diagnostics = append(diagnostics, newDiagnostic(source, issue.Location, issue.Message))
+	}
+	return &CompileError{Expression: expression, Diagnostics: diagnostics, err: issues.Err()}
+}
+
+// newOutputTypeError reports that a checked expression does not produce its
+// rule's declared output type, located at the expression's root
+func newOutputTypeError(expression string, checked *cel.Ast, err error) *CompileError {
+	native := checked.NativeRep()
+	location := native.SourceInfo().GetStartLocation(native.Expr().ID())
+	diagnostic := newDiagnostic(common.NewTextSource(expression), location, err.Error())
+	return &CompileError{Expression: expression, Diagnostics: []Diagnostic{diagnostic}, err: err}
+}
+
+// newDiagnostic locates an error message in source
+// CEL reports 1-based lines and 0-based columns, counted in characters
+func newDiagnostic(source common.Source, location common.Location, message string) Diagnostic {
+	diagnostic := Diagnostic{Severity: SeverityError, Message: message}
+	if location == nil || location.Line() < 1 {
+		return diagnostic
+	}
+
+	diagnostic.Line = location.Line()
+	diagnostic.Column = location.Column() + 1
+	if offset, ok := source.LocationOffset(location); ok {
+		diagnostic.Offset = int(offset)
+	}
+	if snippet, ok := source.Snippet(location.Line()); ok {
+		diagnostic.Snippet = strings.TrimRight(snippet, "\r")
+	}
+	return diagnostic
+}
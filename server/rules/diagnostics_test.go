@@ -0,0 +1,93 @@
+package rules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestCompileDiagnostics verifies compile errors locate each issue in the
+// expression
+func TestCompileDiagnostics(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+
+	testCases := []struct {
+		name       string
+		expression string
+		outputType string
+		want       Diagnostic
+	}{
+		{
+			name:       "Undeclared reference",
+			expression: "User.Age > 18 &&\n  Usr.Name == 'a'",
+			want:       Diagnostic{Line: 2, Column: 3, Offset: 19, Snippet: "  Usr.Name == 'a'"},
+		},
+		{
+			name:       "Syntax error",
+			expression: "User.Age > ",
+			want:       Diagnostic{Line: 1, Column: 12, Offset: 11, Snippet: "User.Age > "},
+		},
+		{
+			name:       "Offsets count characters",
+			expression: `"é" + 1`,
+			want:       Diagnostic{Line: 1, Column: 5, Offset: 4, Snippet: `"é" + 1`},
+		},
+		{
+			name:       "Output type mismatch",
+			expression: "1 + 2",
+			outputType: "bool",
+			want:       Diagnostic{Line: 1, Column: 3, Offset: 2, Snippet: "1 + 2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := engine.AddRule(&Rule{ID: "r1", Name: "r1", Expression: tc.expression, OutputType: tc.outputType, Active: true})
+
+			var compileErr *CompileError
+			if !errors.As(err, &compileErr) {
+				t.Fatalf("AddRule() error = %v, want a CompileError", err)
+			}
+			if !strings.Contains(err.Error(), "compile error") {
+				t.Errorf("AddRule() error = %q, want it to mention the compile error", err)
+			}
+			if compileErr.Expression != tc.expression {
+				t.Errorf("Expression = %q, want %q", compileErr.Expression, tc.expression)
+			}
+			if len(compileErr.Diagnostics) != 1 {
+				t.Fatalf("Diagnostics = %+v, want one", compileErr.Diagnostics)
+			}
+
+			got := compileErr.Diagnostics[0]
+			if got.Line != tc.want.Line || got.Column != tc.want.Column || got.Offset != tc.want.Offset || got.Snippet != tc.want.Snippet {
+				t.Errorf("Diagnostic = %+v, want position %+v", got, tc.want)
+			}
+			if got.Severity != SeverityError || got.Message == "" {
+				t.Errorf("Diagnostic = %+v, want an error with a message", got)
+			}
+		})
+	}
+}
+
+// TestCompileDiagnosticsDependentRule verifies a change that breaks another
+// rule reports diagnostics for that rule's expression
+func TestCompileDiagnosticsDependentRule(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.AddRule(&Rule{ID: "adult", Name: "adult", Expression: `User.Age >= 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule(adult) failed: %v", err)
+	}
+	dependent := `rules.adult && User.Country == "CA"`
+	if err := engine.AddRule(&Rule{ID: "dependent", Name: "dependent", Expression: dependent, Active: true}); err != nil {
+		t.Fatalf("AddRule(dependent) failed: %v", err)
+	}
+
+	err := engine.UpdateRule(&Rule{ID: "adult", Name: "adult", Expression: `User.Age`, OutputType: "int", Active: true})
+
+	var compileErr *CompileError
+	if !errors.As(err, &compileErr) {
+		t.Fatalf("UpdateRule() error = %v, want a CompileError", err)
+	}
+	if compileErr.Expression != dependent {
+		t.Errorf("Expression = %q, want the dependent rule's expression", compileErr.Expression)
+	}
+}
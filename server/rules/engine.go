@@ -1,24 +1,117 @@
 package rules
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types/ref"
 )
 
+// ErrRuleNotActive is returned when evaluating a rule that is inactive or does
+// not exist
+var ErrRuleNotActive = errors.New("rule not found or not active")
+
+// ErrInvalidFacts is returned when facts do not match the types the engine's
+// environment declares for them
+var ErrInvalidFacts = errors.New("invalid facts")
+
 // Engine manages CEL environment and rule compilation/evaluation
-// Satisfies REQ-CONCUR-002: Thread-safe for concurrent reads (RWMutex)
+// Satisfies REQ-CONCUR-002: Thread-safe for concurrent reads (lock-free snapshots)
 // Satisfies REQ-CONCUR-003: Thread-safe for concurrent compilation
-// Satisfies REQ-CONCUR-004: Uses RWMutex for concurrent reads
+// Satisfies REQ-CONCUR-004: Evaluations read an immutable snapshot published atomically
 type Engine struct {
-	env      *cel.Env
-	store    RuleStore
-	cache    RulesCache              // cache for active rules list
-	programs map[string]cel.Program // ruleID -> compiled program
-	mu       sync.RWMutex
+	env             *cel.Env // baseEnv extended with derived field variables
+	fallbackEnv     *cel.Env // optional; used only for stored rules that fail to compile against env
+	baseEnv         *cel.Env // coreEnv extended with lists.<name> variables
+	baseFallbackEnv *cel.Env
+	coreEnv         *cel.Env // the configured env, with the in operator for reference lists
+	coreFallbackEnv *cel.Env
+	store           RuleStore
+	derivedStore    DerivedFieldStore
+	listStore       ReferenceListStore
+	tests           RuleTestStore
+	ruleEnv         *cel.Env                     // env extended with rules.<name> references
+	ruleFallbackEnv *cel.Env                     // fallbackEnv extended with rules.<name> references
+	snapshot        atomic.Pointer[ruleSnapshot] // what evaluations read; see publish
+	maxBatchSize    int                          // 0 means DefaultMaxBatchSize
+	maxCost         uint64                       // worst-case cost budget for new rules; 0 means no limit
+	options         EngineOptions                // fixed for the engine's lifetime
+	clock           func() time.Time             // decides which rules are in their effective window
+	convertFacts    FactConverter                // optional; see EngineConfig.ConvertFacts
+	stats           *ruleStatsRecorder
+	shadowSlots     chan struct{}          // bounds the shadow evaluations running in the background
+	shadowRunning   sync.WaitGroup         // shadow evaluations running in the background
+	refreshing      atomic.Bool            // whether a background rebuild of stale rules is running
+	mu              sync.RWMutex           // guards the environments and settings; held to publish snapshots
+	writeMu         sync.Mutex             // serializes rule and derived field mutations
+	successor       atomic.Pointer[Engine] // engine that replaced this one; see PauseWrites
+}
+
+// EngineConfig configures a rules engine
+type EngineConfig struct {
+	// Env is the CEL environment rules are compiled against
+	Env *cel.Env
+
+	// FallbackEnv is optional; stored rules that fail to compile against Env
+	// are compiled against it instead
+	FallbackEnv *cel.Env
+
+	// Store holds the engine's rules
+	Store RuleStore
+
+	// DerivedStore holds the engine's derived fields; defaults to an
+	// in-memory store when nil
+	DerivedStore DerivedFieldStore
+
+	// ListStore holds the engine's reference lists; defaults to an in-memory
+	// store when nil
+	ListStore ReferenceListStore
+
+	// TestStore holds the test cases of the engine's rules; defaults to an
+	// in-memory store when nil
+	TestStore RuleTestStore
+
+	// Limits bounds evaluation time; the zero value imposes no limits
+	Limits EvaluationLimits
+
+	// Concurrency is the number of workers used to evaluate rules in
+	// parallel; 0 or 1 evaluates rules sequentially
+	Concurrency int
+
+	// MaxBatchSize is the largest batch EvaluateBatch accepts; 0 uses
+	// DefaultMaxBatchSize
+	MaxBatchSize int
+
+	// MaxCost is the largest estimated worst-case cost AddRule and UpdateRule
+	// accept for a rule; 0 means no limit
+	MaxCost uint64
+
+	// Options configures compilation and evaluation; the zero value uses the
+	// engine defaults
+	Options EngineOptions
+
+	// Clock returns the current time used to check rules' effective windows;
+	// defaults to time.Now
+	Clock func() time.Time
+
+	// ConvertFacts is optional; it converts facts before every evaluation
+	ConvertFacts FactConverter
+
+	// StatsStore holds the engine's rule stats; defaults to an in-memory
+	// store when nil
+	StatsStore RuleStatsStore
 }
 
+// FactConverter converts facts, e.g. decoded from JSON, to the types an
+// engine's environment declares for them, returning an error for facts that
+// do not match
+type FactConverter func(facts map[string]any) (map[string]any, error)
+
 // NewEngine creates a new rules engine with a default CEL environment
 // Satisfies REQ-ENGINE-001: Engine constructor
 // Satisfies REQ-COMPILE-001: Creates CEL environment
@@ -40,12 +133,125 @@ func NewEngine(store RuleStore) (*Engine, error) {
 // NewEngineWithEnv creates a new rules engine with a custom CEL environment
 // This allows multi-tenant deployments to use schema-specific environments
 func NewEngineWithEnv(env *cel.Env, store RuleStore) (*Engine, error) {
+	return NewEngineWithFallbackEnv(env, nil, store)
+}
+
+// NewEngineWithFallbackEnv creates a new rules engine with a custom CEL environment
+// and a fallback environment for stored rules that no longer compile against it.
+// This keeps rules created under a looser environment (e.g. dynamically typed
+// schemas) loading, while AddRule and UpdateRule are always checked against env.
+func NewEngineWithFallbackEnv(env, fallbackEnv *cel.Env, store RuleStore) (*Engine, error) {
+	return NewEngineWithConfig(EngineConfig{
+		Env:         env,
+		FallbackEnv: fallbackEnv,
+		Store:       store,
+	})
+}
+
+// NewEngineWithConfig creates a new rules engine from an EngineConfig
+// Derived fields are compiled first so rules can reference them as variables
+func NewEngineWithConfig(cfg EngineConfig) (*Engine, error) {
+	if err := cfg.Limits.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Concurrency < 0 {
+		return nil, fmt.Errorf("concurrency must not be negative")
+	}
+	if cfg.MaxBatchSize < 0 {
+		return nil, fmt.Errorf("max batch size must not be negative")
+	}
+	if err := cfg.Options.Validate(); err != nil {
+		return nil, err
+	}
+
+	derivedStore := cfg.DerivedStore
+	if derivedStore == nil {
+		derivedStore = NewInMemoryDerivedFieldStore()
+	}
+
+	listStore := cfg.ListStore
+	if listStore == nil {
+		listStore = NewInMemoryReferenceListStore()
+	}
+
+	testStore := cfg.TestStore
+	if testStore == nil {
+		testStore = NewInMemoryRuleTestStore()
+	}
+
+	statsStore := cfg.StatsStore
+	if statsStore == nil {
+		statsStore = NewInMemoryRuleStatsStore()
+	}
+
+	clock := cfg.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+
+	fields, err := derivedStore.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load derived fields: %w", err)
+	}
+
+	stored, err := listStore.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reference lists: %w", err)
+	}
+	lists := compileLists(stored)
+
+	coreEnv, coreFallbackEnv, err := cfg.Options.extendEnv(cfg.Env, cfg.FallbackEnv)
+	if err != nil {
+		return nil, err
+	}
+	coreEnv, coreFallbackEnv, err = trackMacroCalls(coreEnv, coreFallbackEnv)
+	if err != nil {
+		return nil, err
+	}
+	coreEnv, coreFallbackEnv, err = declareListFunctions(coreEnv, coreFallbackEnv)
+	if err != nil {
+		return nil, err
+	}
+	baseEnv, baseFallbackEnv, err := declareLists(coreEnv, coreFallbackEnv, lists)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := buildDerivedState(baseEnv, baseFallbackEnv, cloneDerivedFields(fields), "", cfg.Options.costLimit())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile derived fields: %w", err)
+	}
+
 	en := &Engine{
-		env:      env,
-		store:    store,
-		cache:    NewInMemoryRulesCache(DefaultCacheConfig()),
-		programs: make(map[string]cel.Program),
+		env:             state.env,
+		fallbackEnv:     state.fallbackEnv,
+		ruleEnv:         state.env,
+		ruleFallbackEnv: state.fallbackEnv,
+		baseEnv:         baseEnv,
+		baseFallbackEnv: baseFallbackEnv,
+		coreEnv:         coreEnv,
+		coreFallbackEnv: coreFallbackEnv,
+		store:           cfg.Store,
+		derivedStore:    derivedStore,
+		listStore:       listStore,
+		tests:           testStore,
+		maxBatchSize:    cfg.MaxBatchSize,
+		maxCost:         cfg.MaxCost,
+		options:         cfg.Options,
+		clock:           clock,
+		convertFacts:    cfg.ConvertFacts,
+		stats:           newRuleStatsRecorder(statsStore),
+		shadowSlots:     make(chan struct{}, maxShadowEvaluations),
 	}
+	en.snapshot.Store(&ruleSnapshot{
+		programs:    make(map[string]*compiledRule),
+		legacy:      make(map[string]bool),
+		refs:        make(map[string]*Rule),
+		derived:     state.fields,
+		lists:       lists,
+		limits:      cfg.Limits,
+		concurrency: cfg.Concurrency,
+	})
 
 	if err := en.CompileAllRules(); err != nil {
 		return nil, fmt.Errorf("failed to compile rules: %w", err)
@@ -57,31 +263,108 @@ func NewEngineWithEnv(env *cel.Env, store RuleStore) (*Engine, error) {
 // CompileRule compiles a single rule expression to a CEL program
 // Satisfies REQ-COMPILE-002: Compiles CEL expressions
 // Satisfies REQ-COMPILE-003: Returns descriptive compilation errors
+// Expressions that do not compile return a *CompileError locating each issue
 // Satisfies REQ-COMPILE-004: Performs type checking
 // Satisfies REQ-COMPILE-005: Caches compiled programs
 // Satisfies REQ-COMPILE-007: Enables tracing with OptTrackState
 // Satisfies REQ-SEC-001: Applies cost limit to prevent runaway expressions
 func (en *Engine) CompileRule(ruleID, expression string) error {
-	ast, issues := en.env.Compile(expression)
+	return en.compileRule(ruleID, expression, "")
+}
+
+// compileRule compiles an expression against the primary environment, checking
+// it against the declared output type, and caches the program
+func (en *Engine) compileRule(ruleID, expression, outputType string) error {
+	en.mu.RLock()
+	env := en.ruleEnv
+	en.mu.RUnlock()
+
+	compiled, err := compileProgram(env, expression, outputType, en.options)
+	if err != nil {
+		return err
+	}
+
+	en.publish(func(next *ruleSnapshot) {
+		programs, legacy := next.copyPrograms()
+		programs[ruleID] = compiled
+		delete(legacy, ruleID)
+		next.setPrograms(programs, legacy)
+	})
+
+	return nil
+}
+
+// compiledRule holds a rule's compiled program and the metadata needed to evaluate it
+type compiledRule struct {
+	program      cel.Program
+	outputType   *cel.Type // nil when the rule does not declare an output type
+	dependencies []string  // names of the rules referenced through rules.<name>
+	checked      *cel.Ast  // type-checked AST, walked to explain results
+	env          *cel.Env  // environment the rule was checked against
+	cost         CostEstimate
+	fields       []string // variable and field paths read, e.g. "User.Age"
+	costLimit    uint64
+	tracked      bool        // whether the program records state for explanations
+	guard        cel.Program // cost-limited program run before a tracked one, if needed
+	partial      partialProgram
+}
+
+// compileProgram type-checks an expression and builds its CEL program with
+// the engine's options
+func compileProgram(env *cel.Env, expression, outputType string, options EngineOptions) (*compiledRule, error) {
+	declared, err := ParseOutputType(outputType)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expression)
 	if issues != nil && issues.Err() != nil {
-		return fmt.Errorf("compile error: %w", issues.Err())
+		return nil, newCompileError(expression, issues)
+	}
+
+	if err := checkOutputType(declared, ast.OutputType()); err != nil {
+		return nil, newOutputTypeError(expression, ast, err)
+	}
+
+	cost, err := estimateCost(env, ast)
+	if err != nil {
+		return nil, err
 	}
 
 	// REQ-SEC-001: Apply cost limit and enable tracking
-	// Cost limit of 1,000,000 prevents resource exhaustion from malicious/complex expressions
-	prog, err := en.env.Program(ast,
-		cel.EvalOptions(cel.OptTrackState),
-		cel.CostLimit(1000000),
-	)
+	prog, err := env.Program(ast, options.programOptions()...)
 	if err != nil {
-		return fmt.Errorf("program creation error: %w", err)
+		return nil, fmt.Errorf("program creation error: %w", err)
 	}
 
-	en.mu.Lock()
-	en.programs[ruleID] = prog
-	en.mu.Unlock()
+	// A tracked program does not enforce the cost limit, so rules that might
+	// exceed it are first run by a cost-limited guard
+	var guard cel.Program
+	if !options.DisableStateTracking && cost.Max > options.costLimit() {
+		guard, err = env.Program(ast, options.guardOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("program creation error: %w", err)
+		}
+	}
 
-	return nil
+	return &compiledRule{
+		program:      prog,
+		guard:        guard,
+		outputType:   declared,
+		dependencies: ruleDependencies(ast),
+		checked:      ast,
+		env:          env,
+		cost:         cost,
+		fields:       referencedFields(ast),
+		costLimit:    options.costLimit(),
+		tracked:      !options.DisableStateTracking,
+	}, nil
+}
+
+// IsLegacyRule reports whether a rule was compiled against the fallback environment
+// because it no longer compiles against the engine's primary environment
+func (en *Engine) IsLegacyRule(ruleID string) bool {
+	return en.snapshot.Load().legacy[ruleID]
 }
 
 // Evaluate evaluates a single rule against the provided facts
@@ -90,58 +373,221 @@ func (en *Engine) CompileRule(ruleID, expression string) error {
 // Satisfies REQ-EVAL-005: Non-boolean expressions treated as false
 // Satisfies REQ-EVAL-006: Evaluation errors are captured
 func (en *Engine) Evaluate(ruleID string, facts map[string]any) (*EvaluationResult, error) {
-	rule, err := en.store.Get(ruleID)
+	return en.EvaluateContext(context.Background(), ruleID, facts)
+}
+
+// EvaluateContext evaluates a single rule, stopping early if ctx is done or
+// the engine's time budget runs out
+// A rule that is cut off reports ErrEvaluationTimeout or ErrEvaluationCanceled
+// The rule is read from the engine's snapshot, not the store, so a rule that
+// is inactive or deleted returns ErrRuleNotActive, as does a rule outside its
+// effective window on the engine's clock
+func (en *Engine) EvaluateContext(ctx context.Context, ruleID string, facts map[string]any) (*EvaluationResult, error) {
+	snap, err := en.currentSnapshot()
 	if err != nil {
 		return nil, err
 	}
+	now := en.clock()
 
-	en.mu.RLock()
-	prog, exists := en.programs[ruleID]
-	en.mu.RUnlock()
-
+	rule, exists := snap.active[ruleID]
+	if !exists {
+		return nil, fmt.Errorf("rule %s: %w", ruleID, ErrRuleNotActive)
+	}
+	if status := rule.StatusAt(now); status != RuleStatusLive {
+		return nil, fmt.Errorf("rule %s is %s: %w", ruleID, status, ErrRuleNotActive)
+	}
+	compiled, exists := snap.programs[ruleID]
 	if !exists {
 		return nil, fmt.Errorf("rule %s is not compiled", ruleID)
 	}
+	limits := snap.limits
 
-	out, details, err := prog.Eval(facts)
+	ctx, cancel := limits.requestContext(ctx)
+	defer cancel()
+
+	facts, err = en.prepareFacts(ctx, snap, facts)
 	if err != nil {
+		return nil, err
+	}
+	if chain := snap.newRuleChain(ctx, facts, now); chain != nil {
+		result := chain.evaluate(rule, compiled)
+		return result, result.Error
+	}
+	result := evaluateProgram(ctx, rule, compiled, facts, limits)
+	return result, result.Error
+}
+
+// evaluateCompiled evaluates a rule's compiled program, reporting an error
+// result if the rule has no compiled program
+// vars is the facts map or a ruleChain wrapping it
+func evaluateCompiled(ctx context.Context, rule *Rule, compiled *compiledRule, vars any, limits EvaluationLimits) *EvaluationResult {
+	if compiled == nil {
 		return &EvaluationResult{
-			RuleID:   ruleID,
+			RuleID:   rule.ID,
 			RuleName: rule.Name,
 			Matched:  false,
-			Error:    err,
-		}, err
+			Error:    fmt.Errorf("rule %s is not compiled", rule.ID),
+		}
+	}
+	return evaluateProgram(ctx, rule, compiled, vars, limits)
+}
+
+// evaluateProgram runs a compiled program for a rule and converts the output
+// Evaluation errors are captured in the result rather than returned
+func evaluateProgram(ctx context.Context, rule *Rule, compiled *compiledRule, vars any, limits EvaluationLimits) *EvaluationResult {
+	result, _ := runProgram(ctx, rule, compiled, vars, limits)
+	return result
+}
+
+// runProgram is evaluateProgram that also returns the raw output, which is
+// nil when the result has an error
+func runProgram(ctx context.Context, rule *Rule, compiled *compiledRule, vars any, limits EvaluationLimits) (*EvaluationResult, ref.Val) {
+	if err := ctx.Err(); err != nil {
+		return interruptedResult(rule, err), nil
+	}
+
+	ruleCtx, cancel := limits.ruleContext(ctx)
+	defer cancel()
+
+	if compiled.guard != nil {
+		if _, _, err := compiled.guard.ContextEval(ruleCtx, vars); err != nil {
+			if ruleCtx.Err() != nil {
+				return interruptedResult(rule, ruleCtx.Err()), nil
+			}
+			return &EvaluationResult{
+				RuleID:   rule.ID,
+				RuleName: rule.Name,
+				Matched:  false,
+				Error:    err,
+			}, nil
+		}
 	}
 
-	matched := false
-	if boolVal, ok := out.Value().(bool); ok {
-		matched = boolVal
+	out, details, err := compiled.program.ContextEval(ruleCtx, vars)
+	if err != nil && ruleCtx.Err() != nil {
+		return interruptedResult(rule, ruleCtx.Err()), nil
+	}
+	if err == nil && compiled.outputType != nil && !compiled.outputType.IsAssignableRuntimeType(out) {
+		// Expressions typed as dyn are only checked against the declared type here
+		err = fmt.Errorf("rule output of type %s does not match declared output type %s",
+			out.Type().TypeName(), compiled.outputType)
 	}
 
-	return &EvaluationResult{
-		RuleID:   ruleID,
+	var output any
+	if err == nil {
+		output, err = outputValue(out)
+	}
+
+	if err != nil {
+		return &EvaluationResult{
+			RuleID:   rule.ID,
+			RuleName: rule.Name,
+			Matched:  false,
+			Error:    err,
+		}, nil
+	}
+
+	result := &EvaluationResult{
+		RuleID:   rule.ID,
 		RuleName: rule.Name,
-		Matched:  matched,
-		Trace:    details.State(),
-	}, nil
+		Matched:  outputMatched(out, compiled.outputType),
+		Output:   output,
+	}
+	if compiled.tracked {
+		result.Trace = details.State()
+	}
+	return result, out
+}
+
+// PauseWrites blocks rule, list, derived field and test case changes on the
+// engine until resume is called
+// Callers replacing the engine pass its replacement to resume; changes that
+// were blocked, and any made through the old engine afterwards, are then
+// applied to the replacement so they are not lost with the old engine. A nil
+// replacement lets them proceed on this engine.
+func (en *Engine) PauseWrites() (resume func(replacement *Engine)) {
+	en.writeMu.Lock()
+	return func(replacement *Engine) {
+		if replacement != nil && replacement != en {
+			en.successor.Store(replacement)
+		}
+		en.writeMu.Unlock()
+	}
+}
+
+// lockWrites locks writeMu on the engine changes should be applied to,
+// following replacements recorded by PauseWrites, and returns that engine
+func (en *Engine) lockWrites() *Engine {
+	for {
+		en.writeMu.Lock()
+		next := en.successor.Load()
+		if next == nil {
+			return en
+		}
+		en.writeMu.Unlock()
+		en = next
+	}
 }
 
 // CompileAllRules compiles all active rules from the store
-// Also populates the cache with the active rules list
+// Also publishes the active rules list with their programs. It is serialized
+// with rule changes, so it can be called to pick up rules changed elsewhere;
+// the engine calls it when its rules are older than the cache TTL.
+// Rules may reference other active rules as rules.<name>; a dependency cycle
+// is reported as an error
 func (en *Engine) CompileAllRules() error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	return en.compileAllRules()
+}
+
+// compileAllRules is CompileAllRules for callers holding writeMu
+func (en *Engine) compileAllRules() error {
 	rules, err := en.store.ListActive()
 	if err != nil {
 		return err
 	}
 
+	refs := ruleReferences(rules)
+	en.mu.RLock()
+	env, fallbackEnv := en.env, en.fallbackEnv
+	en.mu.RUnlock()
+	ruleEnv, ruleFallbackEnv, err := declareRuleReferences(env, fallbackEnv, refs)
+	if err != nil {
+		return err
+	}
+
+	programs := make(map[string]*compiledRule, len(rules))
+	legacy := make(map[string]bool)
 	for _, rule := range rules {
-		if err := en.CompileRule(rule.ID, rule.Expression); err != nil {
+		compiled, err := compileProgram(ruleEnv, rule.Expression, rule.OutputType, en.options)
+		if err != nil && ruleFallbackEnv != nil {
+			// Keep rules stored under a looser environment loading
+			compiled, err = compileProgram(ruleFallbackEnv, rule.Expression, rule.OutputType, en.options)
+			legacy[rule.ID] = true
+		}
+		if err != nil {
 			return fmt.Errorf("failed to compile rule %s: %w", rule.ID, err)
 		}
+		programs[rule.ID] = compiled
 	}
 
-	// Populate cache with active rules
-	en.cache.Set(rules)
+	err = checkRuleCycles(refs, func(rule *Rule) []string {
+		return programs[rule.ID].dependencies
+	})
+	if err != nil {
+		return err
+	}
+
+	// Publish the active rules with their programs
+	en.publish(func(next *ruleSnapshot) {
+		en.ruleEnv = ruleEnv
+		en.ruleFallbackEnv = ruleFallbackEnv
+		next.refs = refs
+		next.setPrograms(programs, legacy)
+		next.setRules(rules)
+	})
 
 	return nil
 }
@@ -149,31 +595,50 @@ func (en *Engine) CompileAllRules() error {
 // AddRule adds a new rule to the store and compiles it
 // Satisfies REQ-ENGINE-002: Validates, compiles, and stores rule
 // Satisfies REQ-ENGINE-003: Validates rule compiles before adding to store
-// Satisfies REQ-ENGINE-004: Removes compiled program if store fails (atomicity)
+// Satisfies REQ-ENGINE-004: Compiled program is only installed once the store succeeds (atomicity)
+// A rule that would complete a dependency cycle, or whose name makes an
+// existing reference ambiguous, is rejected
 func (en *Engine) AddRule(r *Rule) error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
 	// Check if rule already exists before compiling (to avoid overwriting existing programs)
 	_, err := en.store.Get(r.ID)
 	if err == nil {
 		// Rule already exists
 		return fmt.Errorf("rule with ID %s already exists", r.ID)
 	}
+	if err := validateSchedule(r); err != nil {
+		return err
+	}
 
-	// Validate that the rule compiles
-	if err := en.CompileRule(r.ID, r.Expression); err != nil {
-		return fmt.Errorf("rule validation failed: %w", err)
+	active, err := en.activeRules()
+	if err != nil {
+		return err
+	}
+	if r.Active {
+		active = append(active, r)
 	}
 
-	// Then add to store
-	if err := en.store.Add(r); err != nil {
-		// Remove from compiled programs if store fails
-		en.mu.Lock()
-		delete(en.programs, r.ID)
-		en.mu.Unlock()
+	// Validate that the rule compiles and passes its test cases
+	plan, err := en.planRuleChange(active, r)
+	if err != nil {
+		return err
+	}
+	if err := en.checkTests(plan, r); err != nil {
 		return err
 	}
 
-	// Invalidate cache since rules list changed
-	en.cache.Invalidate()
+	// Then add to store, with the fields the rule reads
+	r.ReferencedFields = plan.programs[r.ID].fields
+	if err := en.store.Add(r); err != nil {
+		return err
+	}
+	var inactive []string
+	if !r.Active {
+		inactive = []string{r.ID}
+	}
+	en.applyRulePlan(plan, inactive)
 
 	return nil
 }
@@ -181,36 +646,89 @@ func (en *Engine) AddRule(r *Rule) error {
 // UpdateRule updates an existing rule and recompiles it
 // Satisfies REQ-ENGINE-005: Recompiles rule on update
 // Satisfies REQ-ENGINE-006: Validates new expression before updating
+// Rules that reference the updated rule are recompiled, and the update is
+// rejected if any of them would break or a dependency cycle would form
 func (en *Engine) UpdateRule(r *Rule) error {
-	// Compile the new expression to validate it
-	if err := en.CompileRule(r.ID, r.Expression); err != nil {
-		return fmt.Errorf("rule validation failed: %w", err)
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	if err := validateSchedule(r); err != nil {
+		return err
 	}
 
-	// Update in store
-	if err := en.store.Update(r); err != nil {
+	current, err := en.activeRules()
+	if err != nil {
+		return err
+	}
+	active := make([]*Rule, 0, len(current)+1)
+	for _, rule := range current {
+		if rule.ID != r.ID {
+			active = append(active, rule)
+		}
+	}
+	if r.Active {
+		active = append(active, r)
+	}
+
+	// Compile the new expression to validate it, then run the test cases of
+	// the rule and of the rules depending on it
+	plan, err := en.planRuleChange(active, r)
+	if err != nil {
+		return err
+	}
+	if err := en.checkTests(plan, r); err != nil {
 		return err
 	}
 
-	// Invalidate cache since rule metadata might have changed
-	en.cache.Invalidate()
+	// Update in store, with the fields the rule reads
+	r.ReferencedFields = plan.programs[r.ID].fields
+	if err := en.store.Update(r); err != nil {
+		return err
+	}
+	// Inactive rules have no program, as when the rules are loaded
+	var inactive []string
+	if !r.Active {
+		inactive = []string{r.ID}
+	}
+	en.applyRulePlan(plan, inactive)
 
 	return nil
 }
 
 // DeleteRule removes a rule from the store and compiled programs
 // Satisfies REQ-ENGINE-007: Removes rule from store and cache
+// A rule that other active rules reference cannot be deleted
 func (en *Engine) DeleteRule(ruleID string) error {
-	if err := en.store.Delete(ruleID); err != nil {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	current, err := en.activeRules()
+	if err != nil {
 		return err
 	}
+	active := make([]*Rule, 0, len(current))
+	for _, rule := range current {
+		if rule.ID != ruleID {
+			active = append(active, rule)
+		}
+	}
 
-	en.mu.Lock()
-	delete(en.programs, ruleID)
-	en.mu.Unlock()
+	plan, err := en.planRuleChange(active, nil)
+	if err != nil {
+		return fmt.Errorf("cannot delete rule %s: %w: %w", ruleID, ErrRuleInUse, err)
+	}
 
-	// Invalidate cache since rules list changed
-	en.cache.Invalidate()
+	if err := en.store.Delete(ruleID); err != nil {
+		return err
+	}
+	en.applyRulePlan(plan, []string{ruleID})
+	if err := en.stats.forget(ruleID); err != nil {
+		return err
+	}
+
+	if err := en.tests.DeleteByRule(ruleID); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -220,59 +738,165 @@ func (en *Engine) DeleteRule(ruleID string) error {
 // Satisfies REQ-EVAL-007: Continues evaluating even if some rules fail
 // Uses cache to avoid database query on every evaluation
 func (en *Engine) EvaluateAll(facts map[string]any) ([]*EvaluationResult, error) {
-	// Try to get rules from cache first
-	rules := en.cache.Get()
+	return en.EvaluateAllContext(context.Background(), facts, EvaluateOptions{})
+}
 
-	// If cache miss, fetch from database and populate cache
-	if rules == nil {
-		var err error
-		rules, err = en.store.ListActive()
-		if err != nil {
+// EvaluateAllWithOptions evaluates active rules in priority order, stopping
+// early when the evaluation mode is satisfied
+// Rules that are not run because evaluation stopped are omitted from the results
+func (en *Engine) EvaluateAllWithOptions(facts map[string]any, opts EvaluateOptions) ([]*EvaluationResult, error) {
+	return en.EvaluateAllContext(context.Background(), facts, opts)
+}
+
+// EvaluateAllContext evaluates active rules in priority order like
+// EvaluateAllWithOptions, stopping early if ctx is done or the engine's time
+// budget runs out. Rules that are cut off are still reported, with
+// ErrEvaluationTimeout or ErrEvaluationCanceled as their error.
+// Derived fields are computed once from the facts before any rule runs
+// Shadow rules are evaluated but left out of the results; see RuleStats
+// Only rules inside their effective window on the engine's clock are run
+func (en *Engine) EvaluateAllContext(ctx context.Context, facts map[string]any, opts EvaluateOptions) ([]*EvaluationResult, error) {
+	if err := en.checkOptions(opts); err != nil {
+		return nil, err
+	}
+
+	snap, rules, now, err := en.liveRules()
+	if err != nil {
+		return nil, err
+	}
+
+	return en.evaluateRules(ctx, snap, now, rules, facts, opts)
+}
+
+// checkOptions validates opts for an evaluation on this engine
+// Explanations are refused with ErrExplainUnavailable when the engine does
+// not track evaluation state.
+func (en *Engine) checkOptions(opts EvaluateOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.Explain && en.options.DisableStateTracking {
+		return ErrExplainUnavailable
+	}
+	return nil
+}
+
+// activeRules returns the active rules for a rule change, first rebuilding
+// the snapshot when they are not loaded or older than the cache TTL
+// The caller holds writeMu.
+func (en *Engine) activeRules() ([]*Rule, error) {
+	if en.snapshot.Load().stale(en.options.CacheTTL, time.Now()) {
+		if err := en.compileAllRules(); err != nil {
 			return nil, err
 		}
-		en.cache.Set(rules)
 	}
+	return en.snapshot.Load().rules, nil
+}
 
-	results := make([]*EvaluationResult, 0, len(rules))
-	for _, rule := range rules {
-		// Use cached rule data instead of fetching from DB
-		// This eliminates 10-100 DB queries per evaluation request
-		en.mu.RLock()
-		prog, exists := en.programs[rule.ID]
-		en.mu.RUnlock()
+// EvaluateRulesContext evaluates the given rules in the order given, applying
+// the same mode, cancellation and time budgets as EvaluateAllContext
+// Rules that do not exist, are inactive or are outside their effective window
+// are skipped; like EvaluateContext the rules are read from the engine's
+// snapshot, not the store
+func (en *Engine) EvaluateRulesContext(ctx context.Context, ruleIDs []string, facts map[string]any, opts EvaluateOptions) ([]*EvaluationResult, error) {
+	if err := en.checkOptions(opts); err != nil {
+		return nil, err
+	}
 
-		if !exists {
-			results = append(results, &EvaluationResult{
-				RuleID:   rule.ID,
-				RuleName: rule.Name,
-				Matched:  false,
-				Error:    fmt.Errorf("rule %s is not compiled", rule.ID),
-			})
-			continue
-		}
+	snap, err := en.currentSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	now := en.clock()
 
-		out, details, err := prog.Eval(facts)
-		if err != nil {
-			results = append(results, &EvaluationResult{
-				RuleID:   rule.ID,
-				RuleName: rule.Name,
-				Matched:  false,
-				Error:    err,
-			})
-			continue
+	rules := make([]*Rule, 0, len(ruleIDs))
+	for _, ruleID := range ruleIDs {
+		if rule, ok := snap.active[ruleID]; ok && rule.EffectiveAt(now) {
+			rules = append(rules, rule)
 		}
+	}
+
+	return en.evaluateRules(ctx, snap, now, rules, facts, opts)
+}
+
+// prepareFacts converts facts to their declared types and adds the reference
+// lists and derived fields of snap
+func (en *Engine) prepareFacts(ctx context.Context, snap *ruleSnapshot, facts map[string]any) (map[string]any, error) {
+	facts, err := convertFacts(en.convertFacts, facts)
+	if err != nil {
+		return nil, err
+	}
+	return computeDerivedFields(ctx, snap.derived, bindLists(snap.lists, facts)), nil
+}
+
+// convertFacts applies an optional fact conversion, wrapping its errors in
+// ErrInvalidFacts
+func convertFacts(convert FactConverter, facts map[string]any) (map[string]any, error) {
+	if convert == nil {
+		return facts, nil
+	}
+	converted, err := convert(facts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFacts, err)
+	}
+	return converted, nil
+}
+
+// evaluateRules runs rules in order against facts with derived fields applied,
+// using the programs and settings of snap throughout; references to other
+// rules are resolved with their effective windows at now
+// Facts that do not match their declared types return ErrInvalidFacts.
+// Once the request budget is spent the remaining rules are reported as cut off
+// Shadow rules run in the background once the live rules are done, whatever
+// the evaluation mode, and only their stats are recorded; see evaluateShadow
+func (en *Engine) evaluateRules(ctx context.Context, snap *ruleSnapshot, now time.Time, rules []*Rule, facts map[string]any, opts EvaluateOptions) ([]*EvaluationResult, error) {
+	limits := snap.limits
+	concurrency := snap.concurrency
+
+	ctx, cancel := limits.requestContext(ctx)
+	defer cancel()
+
+	opts = en.options.withDefaultMode(opts)
+	rules, shadow := splitShadowRules(opts.selectRules(rules))
+	facts, err := en.prepareFacts(ctx, snap, facts)
+	if err != nil {
+		return nil, err
+	}
 
-		matched := false
-		if boolVal, ok := out.Value().(bool); ok {
-			matched = boolVal
+	// Rules referenced by others are shared through the chain, so each runs once
+	chain := snap.newRuleChain(ctx, facts, now)
+	evaluate := func(rule *Rule, compiled *compiledRule) *EvaluationResult {
+		result := en.evaluateRecorded(ctx, chain, rule, compiled, facts, limits)
+		if opts.Explain && compiled != nil {
+			result.Explanation = explainResult(compiled, result)
 		}
+		return result
+	}
 
-		results = append(results, &EvaluationResult{
-			RuleID:   rule.ID,
-			RuleName: rule.Name,
-			Matched:  matched,
-			Trace:    details.State(),
-		})
+	// Deferred so shadow rules start once the live results are complete
+	if len(shadow) > 0 {
+		defer en.evaluateShadow(ctx, snap, now, shadow, facts)
+	}
+
+	// Stopping modes run in order so rules past the stopping point never run
+	if concurrency > 1 && len(rules) > 1 && !opts.stops() {
+		return evaluateRulesParallel(snap, rules, evaluate), nil
+	}
+
+	results := make([]*EvaluationResult, 0, len(rules))
+	matches := 0
+	for _, rule := range rules {
+		// Use cached rule data instead of fetching from DB
+		// This eliminates 10-100 DB queries per evaluation request
+		result := evaluate(rule, snap.programs[rule.ID])
+		results = append(results, result)
+
+		if result.Matched {
+			matches++
+			if opts.Done(matches) {
+				break
+			}
+		}
 	}
 
 	return results, nil
@@ -1,11 +1,50 @@
 package rules
 
 import (
+	"context"
+	"errors"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/google/cel-go/cel"
 )
 
+// newTestEngine creates an engine from config over an in-memory store and
+// adds rules to it
+// Unless config sets an environment, the engine declares User and Transaction
+// as NewEngine does.
+func newTestEngine(tb testing.TB, config EngineConfig, rules ...*Rule) *Engine {
+	tb.Helper()
+
+	if config.Env == nil {
+		env, err := cel.NewEnv(
+			cel.Variable("User", cel.DynType),
+			cel.Variable("Transaction", cel.DynType),
+		)
+		if err != nil {
+			tb.Fatalf("cel.NewEnv() failed: %v", err)
+		}
+		config.Env = env
+	}
+	if config.Store == nil {
+		config.Store = NewInMemoryRuleStore()
+	}
+
+	engine, err := NewEngineWithConfig(config)
+	if err != nil {
+		tb.Fatalf("NewEngineWithConfig() failed: %v", err)
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			tb.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+	return engine
+}
+
 // TestNewEngine verifies REQ-ENGINE-001: Engine constructor SHALL exist
 func TestNewEngine(t *testing.T) {
 	store := NewInMemoryRuleStore()
@@ -983,3 +1022,153 @@ func TestEngineConcurrentReadWrite(t *testing.T) {
 	wg.Wait()
 	t.Log("Concurrent read/write test completed successfully")
 }
+
+// countingRuleStore counts the queries that read rules from the store
+type countingRuleStore struct {
+	RuleStore
+	reads atomic.Int64
+}
+
+func (s *countingRuleStore) Get(id string) (*Rule, error) {
+	s.reads.Add(1)
+	return s.RuleStore.Get(id)
+}
+
+func (s *countingRuleStore) ListActive() ([]*Rule, error) {
+	s.reads.Add(1)
+	return s.RuleStore.ListActive()
+}
+
+func (s *countingRuleStore) ListByTag(tag string) ([]*Rule, error) {
+	s.reads.Add(1)
+	return s.RuleStore.ListByTag(tag)
+}
+
+// TestEvaluateIssuesNoStoreQueries verifies evaluating rules, singly, by ID or
+// all together, is served from compiled state without reading the store
+func TestEvaluateIssuesNoStoreQueries(t *testing.T) {
+	store := &countingRuleStore{RuleStore: NewInMemoryRuleStore()}
+	store.Add(&Rule{ID: "adult", Name: "adult", Expression: `User.Age >= 18`, Active: true})
+	store.Add(&Rule{ID: "senior", Name: "senior", Expression: `User.Age >= 65`, Active: true})
+	engine, err := NewEngine(store)
+	if err != nil {
+		t.Fatalf("NewEngine() failed: %v", err)
+	}
+	if err := engine.CompileAllRules(); err != nil {
+		t.Fatalf("CompileAllRules() failed: %v", err)
+	}
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	store.reads.Store(0)
+	for i := 0; i < 10; i++ {
+		if result, err := engine.Evaluate("adult", facts); err != nil || !result.Matched {
+			t.Fatalf("Evaluate() = %+v, %v; want matched", result, err)
+		}
+		results, err := engine.EvaluateRulesContext(context.Background(), []string{"senior", "adult"}, facts, EvaluateOptions{})
+		if err != nil || len(results) != 2 {
+			t.Fatalf("EvaluateRulesContext() = %d results, %v; want 2", len(results), err)
+		}
+		if _, err := engine.EvaluateAll(facts); err != nil {
+			t.Fatalf("EvaluateAll() failed: %v", err)
+		}
+	}
+	if reads := store.reads.Load(); reads != 0 {
+		t.Errorf("evaluation read the store %d times, want 0", reads)
+	}
+}
+
+// TestEvaluateInactiveAndDeletedRules verifies rules that are inactive, were
+// deactivated or were deleted are not evaluated, without reading the store
+func TestEvaluateInactiveAndDeletedRules(t *testing.T) {
+	store := &countingRuleStore{RuleStore: NewInMemoryRuleStore()}
+	engine, _ := NewEngine(store)
+	for _, rule := range []*Rule{
+		{ID: "active", Name: "active", Expression: `User.Age >= 18`, Active: true},
+		{ID: "inactive", Name: "inactive", Expression: `User.Age >= 18`, Active: false},
+		{ID: "deactivated", Name: "deactivated", Expression: `User.Age >= 18`, Active: true},
+		{ID: "deleted", Name: "deleted", Expression: `User.Age >= 18`, Active: true},
+	} {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+	if err := engine.UpdateRule(&Rule{ID: "deactivated", Name: "deactivated", Expression: `User.Age >= 18`, Active: false}); err != nil {
+		t.Fatalf("UpdateRule() failed: %v", err)
+	}
+	if err := engine.DeleteRule("deleted"); err != nil {
+		t.Fatalf("DeleteRule() failed: %v", err)
+	}
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	store.reads.Store(0)
+	for _, ruleID := range []string{"inactive", "deactivated", "deleted", "missing"} {
+		if _, err := engine.Evaluate(ruleID, facts); !errors.Is(err, ErrRuleNotActive) {
+			t.Errorf("Evaluate(%s) error = %v, want ErrRuleNotActive", ruleID, err)
+		}
+	}
+
+	ruleIDs := []string{"inactive", "deactivated", "active", "deleted", "missing"}
+	results, err := engine.EvaluateRulesContext(context.Background(), ruleIDs, facts, EvaluateOptions{})
+	if err != nil {
+		t.Fatalf("EvaluateRulesContext() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].RuleID != "active" || !results[0].Matched {
+		t.Errorf("EvaluateRulesContext() = %+v, want only the active rule", results)
+	}
+	if reads := store.reads.Load(); reads != 0 {
+		t.Errorf("evaluation read the store %d times, want 0", reads)
+	}
+
+	// Reactivating the rule makes it evaluable again
+	if err := engine.UpdateRule(&Rule{ID: "deactivated", Name: "deactivated", Expression: `User.Age >= 18`, Active: true}); err != nil {
+		t.Fatalf("UpdateRule() failed: %v", err)
+	}
+	if result, err := engine.Evaluate("deactivated", facts); err != nil || !result.Matched {
+		t.Errorf("Evaluate(deactivated) = %+v, %v; want matched", result, err)
+	}
+}
+
+// TestPauseWritesForwardsToReplacement verifies rule changes made while an
+// engine is paused are applied to the engine that replaces it
+func TestPauseWritesForwardsToReplacement(t *testing.T) {
+	oldStore, newStore := NewInMemoryRuleStore(), NewInMemoryRuleStore()
+	engine, err := NewEngine(oldStore)
+	if err != nil {
+		t.Fatalf("NewEngine() failed: %v", err)
+	}
+	replacement, err := NewEngine(newStore)
+	if err != nil {
+		t.Fatalf("NewEngine() failed: %v", err)
+	}
+
+	resume := engine.PauseWrites()
+	added := make(chan error, 1)
+	go func() {
+		added <- engine.AddRule(&Rule{ID: "paused", Name: "paused", Expression: `User.Age >= 18`, Active: true})
+	}()
+	select {
+	case err := <-added:
+		t.Fatalf("AddRule() returned %v while writes were paused", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	resume(replacement)
+
+	if err := <-added; err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	if err := engine.AddRule(&Rule{ID: "after", Name: "after", Expression: `User.Age >= 21`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	for _, ruleID := range []string{"paused", "after"} {
+		if _, err := newStore.Get(ruleID); err != nil {
+			t.Errorf("replacement store is missing %s: %v", ruleID, err)
+		}
+		if _, err := oldStore.Get(ruleID); err == nil {
+			t.Errorf("replaced engine's store has %s", ruleID)
+		}
+		if _, err := replacement.Evaluate(ruleID, map[string]any{"User": map[string]any{"Age": 30}}); err != nil {
+			t.Errorf("replacement Evaluate(%s) failed: %v", ruleID, err)
+		}
+	}
+}
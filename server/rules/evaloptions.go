@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"fmt"
+	"slices"
+)
+
+// EvaluationMode controls how many active rules EvaluateAll runs
+type EvaluationMode string
+
+const (
+	// EvaluationModeAll evaluates every active rule (default)
+	EvaluationModeAll EvaluationMode = "all"
+
+	// EvaluationModeFirstMatch stops after the first matching rule
+	EvaluationModeFirstMatch EvaluationMode = "first-match"
+
+	// EvaluationModeStopAfterMatches stops once MaxMatches rules have matched
+	EvaluationModeStopAfterMatches EvaluationMode = "stop-after-N-matches"
+)
+
+// EvaluateOptions configures a call to EvaluateAllWithOptions
+// The zero value evaluates every active rule
+type EvaluateOptions struct {
+	Mode       EvaluationMode
+	MaxMatches int // required for EvaluationModeStopAfterMatches
+
+	// Tags restricts evaluation to rules carrying at least one of the tags
+	Tags []string
+
+	// RuleSet restricts evaluation to rules in the named rule set
+	RuleSet string
+
+	// Explain attaches an Explanation of each sub-expression to every result
+	// Engines with state tracking disabled refuse it with ErrExplainUnavailable.
+	Explain bool
+}
+
+// Validate checks that the mode is known and its parameters are consistent
+func (o EvaluateOptions) Validate() error {
+	switch o.Mode {
+	case "", EvaluationModeAll, EvaluationModeFirstMatch:
+		return nil
+	case EvaluationModeStopAfterMatches:
+		if o.MaxMatches < 1 {
+			return fmt.Errorf("mode %q requires maxMatches of at least 1", o.Mode)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown evaluation mode %q (must be one of: %s, %s, %s)",
+			o.Mode, EvaluationModeAll, EvaluationModeFirstMatch, EvaluationModeStopAfterMatches)
+	}
+}
+
+// Selects reports whether a rule passes the tag and rule set selectors
+// Rules must satisfy both selectors when both are given
+func (o EvaluateOptions) Selects(rule *Rule) bool {
+	if o.RuleSet != "" && !slices.Contains(rule.RuleSets, o.RuleSet) {
+		return false
+	}
+	if len(o.Tags) > 0 && !slices.ContainsFunc(o.Tags, func(tag string) bool {
+		return slices.Contains(rule.Tags, tag)
+	}) {
+		return false
+	}
+	return true
+}
+
+// selectRules filters rules by the tag and rule set selectors, preserving order
+func (o EvaluateOptions) selectRules(rules []*Rule) []*Rule {
+	if len(o.Tags) == 0 && o.RuleSet == "" {
+		return rules
+	}
+
+	selected := make([]*Rule, 0, len(rules))
+	for _, rule := range rules {
+		if o.Selects(rule) {
+			selected = append(selected, rule)
+		}
+	}
+	return selected
+}
+
+// stops reports whether the mode can stop evaluation before every rule has run
+func (o EvaluateOptions) stops() bool {
+	return o.Mode == EvaluationModeFirstMatch || o.Mode == EvaluationModeStopAfterMatches
+}
+
+// Done reports whether evaluation should stop once the given number of rules have matched
+// Rules are evaluated in priority order, so lower-priority rules are never run
+// after a stopping mode is satisfied
+func (o EvaluateOptions) Done(matches int) bool {
+	switch o.Mode {
+	case EvaluationModeFirstMatch:
+		return matches >= 1
+	case EvaluationModeStopAfterMatches:
+		return matches >= o.MaxMatches
+	default:
+		return false
+	}
+}
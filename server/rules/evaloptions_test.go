@@ -0,0 +1,102 @@
+package rules
+
+import "testing"
+
+// TestEvaluateOptionsValidate verifies evaluation modes and their parameters are validated
+func TestEvaluateOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		opts    EvaluateOptions
+		wantErr bool
+	}{
+		{"Zero value", EvaluateOptions{}, false},
+		{"All", EvaluateOptions{Mode: EvaluationModeAll}, false},
+		{"First match", EvaluateOptions{Mode: EvaluationModeFirstMatch}, false},
+		{"Stop after matches", EvaluateOptions{Mode: EvaluationModeStopAfterMatches, MaxMatches: 2}, false},
+		{"Stop after matches without limit", EvaluateOptions{Mode: EvaluationModeStopAfterMatches}, true},
+		{"Unknown mode", EvaluateOptions{Mode: "some"}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// priorityRules returns rules of differing priority that all match adults but one
+func priorityRules() []*Rule {
+	return []*Rule{
+		{ID: "low", Name: "Low", Expression: `User.Age >= 18`, Active: true, Priority: 1},
+		{ID: "high", Name: "High", Expression: `User.Age >= 18`, Active: true, Priority: 100},
+		{ID: "no-match", Name: "No Match", Expression: `User.Age < 18`, Active: true, Priority: 200},
+		{ID: "mid", Name: "Mid", Expression: `User.Age >= 18`, Active: true, Priority: 50},
+	}
+}
+
+// TestEvaluateAllPriorityOrder verifies rules are evaluated highest priority first
+func TestEvaluateAllPriorityOrder(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, priorityRules()...)
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	results, err := engine.EvaluateAll(facts)
+	if err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+
+	want := []string{"no-match", "high", "mid", "low"}
+	if len(results) != len(want) {
+		t.Fatalf("EvaluateAll() returned %d results, want %d", len(results), len(want))
+	}
+	for i, id := range want {
+		if results[i].RuleID != id {
+			t.Errorf("results[%d].RuleID = %s, want %s", i, results[i].RuleID, id)
+		}
+	}
+}
+
+// TestEvaluateAllFirstMatch verifies lower-priority rules are not run after the first match
+func TestEvaluateAllFirstMatch(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, priorityRules()...)
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	results, err := engine.EvaluateAllWithOptions(facts, EvaluateOptions{Mode: EvaluationModeFirstMatch})
+	if err != nil {
+		t.Fatalf("EvaluateAllWithOptions() failed: %v", err)
+	}
+
+	// The non-matching rule is evaluated first, then evaluation stops at "high"
+	if len(results) != 2 {
+		t.Fatalf("EvaluateAllWithOptions() returned %d results, want 2", len(results))
+	}
+	if results[1].RuleID != "high" || !results[1].Matched {
+		t.Errorf("Expected last result to be matching rule \"high\", got %+v", results[1])
+	}
+}
+
+// TestEvaluateAllStopAfterMatches verifies evaluation stops after N matches
+func TestEvaluateAllStopAfterMatches(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, priorityRules()...)
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	opts := EvaluateOptions{Mode: EvaluationModeStopAfterMatches, MaxMatches: 2}
+	results, err := engine.EvaluateAllWithOptions(facts, opts)
+	if err != nil {
+		t.Fatalf("EvaluateAllWithOptions() failed: %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("EvaluateAllWithOptions() returned %d results, want 3", len(results))
+	}
+	if results[2].RuleID != "mid" {
+		t.Errorf("Expected evaluation to stop at \"mid\", got %s", results[2].RuleID)
+	}
+
+	// Invalid options are rejected before any rule runs
+	if _, err := engine.EvaluateAllWithOptions(facts, EvaluateOptions{Mode: "bogus"}); err == nil {
+		t.Error("EvaluateAllWithOptions() should reject unknown mode")
+	}
+}
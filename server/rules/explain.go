@@ -0,0 +1,196 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter"
+	"github.com/google/cel-go/parser"
+)
+
+// ErrExplainUnavailable is returned when explanations are requested from an
+// engine with state tracking disabled, which they are built from
+var ErrExplainUnavailable = errors.New("explanations need state tracking, which is disabled")
+
+// Explanation describes how one sub-expression of a rule evaluated
+// The root node is the whole rule; function and operator calls have their
+// operands as children. Field selections, literals, lists, maps and
+// comprehensions such as all() are leaves.
+type Explanation struct {
+	Expression     string         // source text of the sub-expression
+	Value          any            // observed value, converted to a JSON-compatible value
+	Evaluated      bool           // false when the sub-expression was never run
+	ShortCircuited bool           // skipped because an earlier operand of &&, || or ?: decided the result
+	Error          string         // evaluation error, if the sub-expression failed
+	Summary        string         // e.g. "Transaction.Amount (1500.5) > 1000 → true"
+	Children       []*Explanation // operands, in source order
+}
+
+// explainResult builds the explanation of a result from the program's checked
+// AST and the state tracked while it ran
+// Returns nil when the rule produced no tracked state, e.g. it was cut off
+func explainResult(compiled *compiledRule, result *EvaluationResult) *Explanation {
+	state, ok := result.Trace.(interpreter.EvalState)
+	if !ok || compiled.checked == nil {
+		return nil
+	}
+
+	native := compiled.checked.NativeRep()
+	return explainExpr(native.Expr(), native.SourceInfo(), state, false)
+}
+
+// explainExpr explains e and its operands; skipped is true when e's parent
+// short-circuited past it
+func explainExpr(e ast.Expr, info *ast.SourceInfo, state interpreter.EvalState, skipped bool) *Explanation {
+	node := &Explanation{
+		Expression:     unparseExpr(e, info),
+		ShortCircuited: skipped,
+	}
+
+	if !skipped {
+		if val, ok := observedValue(e, state); ok {
+			node.Evaluated = true
+			if types.IsError(val) {
+				node.Error = fmt.Sprint(val.Value())
+			} else if native, err := outputValue(val); err == nil {
+				node.Value = native
+			} else {
+				node.Value = fmt.Sprint(val.Value())
+			}
+		}
+	}
+
+	if e.Kind() == ast.CallKind {
+		call := e.AsCall()
+		operands := call.Args()
+		if call.IsMemberFunction() {
+			operands = append([]ast.Expr{call.Target()}, operands...)
+		}
+
+		for i, operand := range operands {
+			childSkipped := skipped || shortCircuited(call.FunctionName(), i, operands, state)
+			node.Children = append(node.Children, explainExpr(operand, info, state, childSkipped))
+		}
+	}
+
+	node.Summary = summarize(e, node)
+	return node
+}
+
+// observedValue returns the value tracked for e, falling back to the literal
+// itself for constants
+func observedValue(e ast.Expr, state interpreter.EvalState) (ref.Val, bool) {
+	if val, ok := state.Value(e.ID()); ok {
+		return val, true
+	}
+	if e.Kind() == ast.LiteralKind {
+		return e.AsLiteral(), true
+	}
+	return nil, false
+}
+
+// shortCircuited reports whether the operand at index of a logical or
+// conditional call was skipped because an earlier operand decided the result
+func shortCircuited(function string, index int, operands []ast.Expr, state interpreter.EvalState) bool {
+	if index == 0 {
+		return false
+	}
+	if _, ok := state.Value(operands[index].ID()); ok {
+		return false
+	}
+
+	switch function {
+	case operators.LogicalAnd, operators.LogicalOr:
+		return true
+	case operators.Conditional:
+		// Only one branch of a ?: runs
+		cond, ok := state.Value(operands[0].ID())
+		if !ok {
+			return false
+		}
+		return (index == 1 && cond == types.False) || (index == 2 && cond == types.True)
+	}
+	return false
+}
+
+// summarize renders a node as a single line, annotating the operands of
+// binary operators with their values
+func summarize(e ast.Expr, node *Explanation) string {
+	outcome := describeOutcome(node)
+
+	if e.Kind() == ast.CallKind && len(node.Children) == 2 {
+		if display, ok := operators.FindReverseBinaryOperator(e.AsCall().FunctionName()); ok && display != "" {
+			return fmt.Sprintf("%s %s %s → %s",
+				annotateOperand(e.AsCall().Args()[0], node.Children[0]),
+				display,
+				annotateOperand(e.AsCall().Args()[1], node.Children[1]),
+				outcome)
+		}
+	}
+
+	return fmt.Sprintf("%s → %s", node.Expression, outcome)
+}
+
+// annotateOperand renders an operand with its observed value, leaving
+// literals as they are written
+func annotateOperand(e ast.Expr, node *Explanation) string {
+	text := node.Expression
+	if len(node.Children) > 0 {
+		text = "(" + text + ")"
+	}
+	if e.Kind() == ast.LiteralKind {
+		return text
+	}
+	return fmt.Sprintf("%s (%s)", text, describeOutcome(node))
+}
+
+// describeOutcome renders the value, error or skipped status of a node
+func describeOutcome(node *Explanation) string {
+	switch {
+	case node.ShortCircuited:
+		return "short-circuited"
+	case node.Error != "":
+		return "error: " + node.Error
+	case !node.Evaluated:
+		return "not evaluated"
+	}
+
+	encoded, err := json.Marshal(node.Value)
+	if err != nil {
+		return fmt.Sprint(node.Value)
+	}
+	return string(encoded)
+}
+
+// trackMacroCalls extends env and fallbackEnv to record macro calls in the
+// ASTs they check, so explanations can render macros as they were written
+func trackMacroCalls(env, fallbackEnv *cel.Env) (*cel.Env, *cel.Env, error) {
+	tracked, err := env.Extend(cel.EnableMacroCallTracking())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to extend CEL environment: %w", err)
+	}
+	if fallbackEnv == nil {
+		return tracked, nil, nil
+	}
+	trackedFallback, err := fallbackEnv.Extend(cel.EnableMacroCallTracking())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to extend CEL environment: %w", err)
+	}
+	return tracked, trackedFallback, nil
+}
+
+// unparseExpr renders a sub-expression back to CEL source
+// Macros such as all() and has() are rendered as they were written
+func unparseExpr(e ast.Expr, info *ast.SourceInfo) string {
+	text, err := parser.Unparse(e, info)
+	if err != nil {
+		return fmt.Sprintf("<expression %d>", e.ID())
+	}
+	return text
+}
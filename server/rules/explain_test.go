@@ -0,0 +1,126 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+// findExplanation returns the first node in the tree with the given expression
+func findExplanation(node *Explanation, expression string) *Explanation {
+	if node == nil {
+		return nil
+	}
+	if node.Expression == expression {
+		return node
+	}
+	for _, child := range node.Children {
+		if found := findExplanation(child, expression); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// TestExplain verifies explanations report each sub-expression's value
+func TestExplain(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	rule := &Rule{
+		ID:         "large-ca",
+		Name:       "Large CA Transaction",
+		Expression: `Transaction.Amount > 1000.0 && (User.Country == "CA" || User.Age > 18)`,
+		Active:     true,
+	}
+	if err := engine.AddRule(rule); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	facts := map[string]any{
+		"User":        map[string]any{"Country": "CA", "Age": 20},
+		"Transaction": map[string]any{"Amount": 1500.5},
+	}
+	results, err := engine.EvaluateAllWithOptions(facts, EvaluateOptions{Explain: true})
+	if err != nil {
+		t.Fatalf("EvaluateAllWithOptions() failed: %v", err)
+	}
+
+	root := results[0].Explanation
+	if root == nil {
+		t.Fatal("Explanation should be set when Explain is requested")
+	}
+	if root.Expression != rule.Expression || root.Value != true || len(root.Children) != 2 {
+		t.Errorf("root = %+v, want the whole rule evaluating to true with 2 operands", root)
+	}
+
+	amount := findExplanation(root, "Transaction.Amount > 1000.0")
+	if amount == nil {
+		t.Fatal("explanation is missing Transaction.Amount > 1000.0")
+	}
+	if want := "Transaction.Amount (1500.5) > 1000.0 → true"; amount.Summary != want {
+		t.Errorf("Summary = %q, want %q", amount.Summary, want)
+	}
+
+	age := findExplanation(root, "User.Age > 18")
+	if age == nil || !age.ShortCircuited || age.Evaluated {
+		t.Errorf("User.Age > 18 = %+v, want short-circuited", age)
+	}
+	if age != nil && !strings.HasSuffix(age.Summary, "→ short-circuited") {
+		t.Errorf("Summary = %q, want short-circuited outcome", age.Summary)
+	}
+}
+
+// TestExplainOmittedByDefault verifies explanations are only built on request
+func TestExplainOmittedByDefault(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.AddRule(&Rule{ID: "r", Name: "R", Expression: `User.Age > 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	results, err := engine.EvaluateAll(map[string]any{"User": map[string]any{"Age": 30}})
+	if err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+	if results[0].Explanation != nil {
+		t.Error("Explanation should be nil unless requested")
+	}
+}
+
+// TestExplainErrorsAndMacros verifies failing operands, conditionals and
+// macros are described
+func TestExplainErrorsAndMacros(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.SetConcurrency(4); err != nil {
+		t.Fatalf("SetConcurrency() failed: %v", err)
+	}
+	rules := []*Rule{
+		{ID: "missing", Name: "Missing", Expression: `User.Missing > 1 || User.Age > 18`, Active: true, Priority: 20},
+		{ID: "macro", Name: "Macro", Expression: `User.Tags.all(t, t != "blocked") ? true : false`, Active: true, Priority: 10},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	facts := map[string]any{"User": map[string]any{"Age": 30, "Tags": []any{"vip"}}}
+	results, err := engine.EvaluateAllWithOptions(facts, EvaluateOptions{Explain: true})
+	if err != nil {
+		t.Fatalf("EvaluateAllWithOptions() failed: %v", err)
+	}
+
+	missing := findExplanation(results[0].Explanation, "User.Missing > 1")
+	if missing == nil || missing.Error == "" {
+		t.Errorf("User.Missing > 1 = %+v, want an error", missing)
+	}
+	if !results[0].Matched {
+		t.Error("rule should match through the second operand")
+	}
+
+	all := findExplanation(results[1].Explanation, `User.Tags.all(t, t != "blocked")`)
+	if all == nil || all.Value != true {
+		t.Errorf("all() node = %+v, want true", all)
+	}
+	elseBranch := findExplanation(results[1].Explanation, "false")
+	if elseBranch == nil || !elseBranch.ShortCircuited {
+		t.Errorf("else branch = %+v, want short-circuited", elseBranch)
+	}
+}
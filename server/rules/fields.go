@@ -0,0 +1,129 @@
+package rules
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// FieldUsage lists the rules and derived fields that read a field path
+type FieldUsage struct {
+	Path          string   // e.g. "User.Age"
+	Rules         []string // IDs of the rules reading the path, directly or through derived fields
+	DerivedFields []string // names of the derived fields reading the path
+}
+
+// referencedFields returns the sorted variable and field paths a checked
+// expression reads, e.g. "User.Age"
+// A path stops at the first index or function call, so User.Tags[0].Name is
+// recorded as User.Tags, and a has() test records the tested field.
+// Comprehension variables and references to other rules and to reference
+// lists are left out; derived fields are recorded by name.
+func referencedFields(checked *cel.Ast) []string {
+	scoped := make(map[string]bool)
+	paths := make(map[string][]string)
+	collectPaths(checked.NativeRep().Expr(), true, scoped, paths)
+
+	fields := make([]string, 0, len(paths))
+	for key, path := range paths {
+		if scoped[path[0]] || strings.HasPrefix(key, ruleRefPrefix) || strings.HasPrefix(key, listRefPrefix) {
+			continue
+		}
+		fields = append(fields, key)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// expandDerivedFields replaces paths rooted at a derived field with the
+// fields that derived field reads, as given by expanded
+func expandDerivedFields(fields []string, expanded map[string][]string) []string {
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		root, _, _ := strings.Cut(field, ".")
+		if derived, ok := expanded[root]; ok {
+			for _, path := range derived {
+				seen[path] = true
+			}
+			continue
+		}
+		seen[field] = true
+	}
+
+	result := make([]string, 0, len(seen))
+	for field := range seen {
+		result = append(result, field)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ReferencedFields returns the variable and field paths a rule reads, e.g.
+// "User.Age", compiling it against the engine's environment when it has no
+// compiled program, e.g. because it is inactive
+// Derived fields the rule reads are returned by name.
+func (en *Engine) ReferencedFields(rule *Rule) ([]string, error) {
+	compiled := en.snapshot.Load().programs[rule.ID]
+	en.mu.RLock()
+	env := en.ruleEnv
+	en.mu.RUnlock()
+
+	if compiled != nil {
+		return compiled.fields, nil
+	}
+
+	compiled, err := compileProgram(env, rule.Expression, rule.OutputType, en.options)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.fields, nil
+}
+
+// FieldUsage reports which of rules, and which of the engine's derived fields,
+// read each field path, sorted by path
+// A rule reading a derived field counts as reading the fields the derived
+// field reads. Rules that no longer compile are reported with the fields
+// stored with them.
+func (en *Engine) FieldUsage(rules []*Rule) []*FieldUsage {
+	derived := en.snapshot.Load().derived
+
+	usage := make(map[string]*FieldUsage)
+	entry := func(path string) *FieldUsage {
+		if _, ok := usage[path]; !ok {
+			usage[path] = &FieldUsage{Path: path}
+		}
+		return usage[path]
+	}
+
+	expanded := make(map[string][]string, len(derived))
+	for _, field := range derived {
+		expanded[field.field.Name] = field.fields
+		for _, path := range field.fields {
+			u := entry(path)
+			u.DerivedFields = append(u.DerivedFields, field.field.Name)
+		}
+	}
+
+	for _, rule := range rules {
+		fields, err := en.ReferencedFields(rule)
+		if err != nil {
+			fields = rule.ReferencedFields
+		}
+		for _, path := range expandDerivedFields(fields, expanded) {
+			u := entry(path)
+			u.Rules = append(u.Rules, rule.ID)
+		}
+	}
+
+	result := make([]*FieldUsage, 0, len(usage))
+	for _, u := range usage {
+		sort.Strings(u.Rules)
+		sort.Strings(u.DerivedFields)
+		result = append(result, u)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
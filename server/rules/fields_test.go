@@ -0,0 +1,97 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestReferencedFields verifies the field paths recorded for an expression
+func TestReferencedFields(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.AddRule(&Rule{ID: "adult", Name: "adult", Expression: `User.Age >= 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule(adult) failed: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		expression string
+		want       []string
+	}{
+		{"Field paths", `User.Age > 18 && User.Address.Country == "CA"`, []string{"User.Address.Country", "User.Age"}},
+		{"Repeated paths", `User.Age > 18 && User.Age < 65`, []string{"User.Age"}},
+		{"Index stops the path", `User.Tags[0] == "vip"`, []string{"User.Tags"}},
+		{"Function call stops the path", `size(User.Name) > 0`, []string{"User.Name"}},
+		{"Has test", `has(User.Email)`, []string{"User.Email"}},
+		{"Comprehension variables", `User.Tags.exists(t, t.Name == "vip")`, []string{"User.Tags"}},
+		{"Whole object", `User != null`, []string{"User"}},
+		{"Rule references", `rules.adult && Transaction.Amount > 100`, []string{"Transaction.Amount"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := engine.ReferencedFields(&Rule{ID: "r1", Expression: tc.expression})
+			if err != nil {
+				t.Fatalf("ReferencedFields() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ReferencedFields() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestReferencedFieldsStored verifies AddRule and UpdateRule record the fields
+// a rule reads
+func TestReferencedFieldsStored(t *testing.T) {
+	store := NewInMemoryRuleStore()
+	engine, _ := NewEngine(store)
+
+	if err := engine.AddRule(&Rule{ID: "r1", Name: "r1", Expression: `User.Age > 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	stored, _ := store.Get("r1")
+	if want := []string{"User.Age"}; !reflect.DeepEqual(stored.ReferencedFields, want) {
+		t.Errorf("ReferencedFields after AddRule = %v, want %v", stored.ReferencedFields, want)
+	}
+
+	if err := engine.UpdateRule(&Rule{ID: "r1", Name: "r1", Expression: `User.Country == "CA"`, Active: false}); err != nil {
+		t.Fatalf("UpdateRule() failed: %v", err)
+	}
+	stored, _ = store.Get("r1")
+	if want := []string{"User.Country"}; !reflect.DeepEqual(stored.ReferencedFields, want) {
+		t.Errorf("ReferencedFields after UpdateRule = %v, want %v", stored.ReferencedFields, want)
+	}
+}
+
+// TestFieldUsage verifies usage is reported per path, with rules reading
+// derived fields counted against the fields those read
+func TestFieldUsage(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	if err := engine.AddDerivedField(&DerivedField{Name: "isAdult", Expression: `User.Age >= 18`}); err != nil {
+		t.Fatalf("AddDerivedField() failed: %v", err)
+	}
+	rules := []*Rule{
+		{ID: "r1", Name: "r1", Expression: `isAdult && User.Country == "CA"`, Active: true},
+		{ID: "r2", Name: "r2", Expression: `User.Age > 65`, Active: false},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	got := engine.FieldUsage(rules)
+	want := []*FieldUsage{
+		{Path: "User.Age", Rules: []string{"r1", "r2"}, DerivedFields: []string{"isAdult"}},
+		{Path: "User.Country", Rules: []string{"r1"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("FieldUsage() = %d paths, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Path != want[i].Path || !reflect.DeepEqual(got[i].Rules, want[i].Rules) ||
+			len(got[i].DerivedFields) != len(want[i].DerivedFields) {
+			t.Errorf("FieldUsage()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -6,12 +6,14 @@ package rules_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/google/cel-go/cel"
 	"github.com/google/uuid"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -71,19 +73,25 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 		t.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Run migrations
-	migrationSQL, err := os.ReadFile(filepath.Join("..", "migrations", "000001_initial_schema.up.sql"))
-	if err != nil {
+	// Run migrations in version order
+	migrationFiles, _ := filepath.Glob(filepath.Join("..", "migrations", "*.up.sql"))
+	if len(migrationFiles) == 0 {
 		// Try without the ../ prefix
-		migrationSQL, err = os.ReadFile(filepath.Join("migrations", "000001_initial_schema.up.sql"))
+		migrationFiles, _ = filepath.Glob(filepath.Join("migrations", "*.up.sql"))
+	}
+	if len(migrationFiles) == 0 {
+		t.Fatal("Failed to find migration files")
+	}
+
+	for _, file := range migrationFiles {
+		migrationSQL, err := os.ReadFile(file)
 		if err != nil {
-			t.Fatalf("Failed to read migration file: %v", err)
+			t.Fatalf("Failed to read migration file %s: %v", file, err)
 		}
-	}
 
-	_, err = db.Exec(string(migrationSQL))
-	if err != nil {
-		t.Fatalf("Failed to run migrations: %v", err)
+		if _, err := db.Exec(string(migrationSQL)); err != nil {
+			t.Fatalf("Failed to run migration %s: %v", file, err)
+		}
 	}
 
 	cleanup := func() {
@@ -509,3 +517,594 @@ func TestRuleOrdering(t *testing.T) {
 		}
 	}
 }
+
+func TestPostgresRuleStore_Versions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := createTenant(t, db, "test-tenant")
+	store := rules.NewPostgresRuleStore(db, tenantID)
+
+	ruleID := uuid.New().String()
+	rule := &rules.Rule{
+		ID:         ruleID,
+		Name:       "test-rule",
+		Expression: "User.Age >= 18",
+		Active:     true,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	if err := store.Add(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	rule.Expression = "User.Age >= 21"
+	if err := store.Update(rule); err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	if rule.Version != 2 {
+		t.Errorf("Expected version 2 after update, got %d", rule.Version)
+	}
+
+	versions, err := store.ListVersions(ruleID)
+	if err != nil {
+		t.Fatalf("Failed to list versions: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 versions, got %d", len(versions))
+	}
+	if versions[0].Version != 2 || versions[0].Expression != "User.Age >= 21" {
+		t.Errorf("Expected newest version first, got %+v", versions[0])
+	}
+
+	v1, err := store.GetVersion(ruleID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get version 1: %v", err)
+	}
+	if v1.Expression != "User.Age >= 18" {
+		t.Errorf("Expected original expression in version 1, got %q", v1.Expression)
+	}
+
+	// Rolling back through the engine records a third revision
+	engine, err := rules.NewEngine(store)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	if _, err := engine.RollbackRule(ruleID, 1); err != nil {
+		t.Fatalf("Failed to roll back rule: %v", err)
+	}
+
+	current, err := store.Get(ruleID)
+	if err != nil {
+		t.Fatalf("Failed to get rule: %v", err)
+	}
+	if current.Version != 3 || current.Expression != "User.Age >= 18" {
+		t.Errorf("Expected rollback to version 3 with original expression, got %+v", current)
+	}
+}
+
+func TestRuleOrdering_Priority(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := createTenant(t, db, "test-tenant")
+	store := rules.NewPostgresRuleStore(db, tenantID)
+
+	// Add rules with ascending priority so creation order differs from priority order
+	for i := 1; i <= 3; i++ {
+		rule := &rules.Rule{
+			ID:         uuid.New().String(),
+			Name:       fmt.Sprintf("rule-%d", i),
+			Expression: "User.Age >= 18",
+			Active:     true,
+			Priority:   i * 10,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		}
+		if err := store.Add(rule); err != nil {
+			t.Fatalf("Failed to add rule %d: %v", i, err)
+		}
+	}
+
+	rulesList, err := store.ListActive()
+	if err != nil {
+		t.Fatalf("Failed to list rules: %v", err)
+	}
+
+	for i := 0; i < len(rulesList)-1; i++ {
+		if rulesList[i].Priority < rulesList[i+1].Priority {
+			t.Error("Rules are not ordered by priority descending")
+		}
+	}
+}
+
+// TestPostgresDerivedFieldStore tests derived field CRUD and engine reload from the database
+func TestPostgresDerivedFieldStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := createTenant(t, db, "test-tenant")
+	derivedStore := rules.NewPostgresDerivedFieldStore(db, tenantID)
+
+	engine, err := rules.NewEngineWithConfig(rules.EngineConfig{
+		Env:          newTestEnv(t),
+		Store:        rules.NewPostgresRuleStore(db, tenantID),
+		DerivedStore: derivedStore,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	if err := engine.AddDerivedField(&rules.DerivedField{Name: "isAdult", Expression: "User.Age >= 18"}); err != nil {
+		t.Fatalf("Failed to add derived field: %v", err)
+	}
+	if err := engine.AddDerivedField(&rules.DerivedField{Name: "isSenior", Expression: "isAdult && User.Age >= 65"}); err != nil {
+		t.Fatalf("Failed to add derived field: %v", err)
+	}
+
+	field, err := derivedStore.Get("isSenior")
+	if err != nil {
+		t.Fatalf("Failed to get derived field: %v", err)
+	}
+	if len(field.Dependencies) != 1 || field.Dependencies[0] != "isAdult" {
+		t.Errorf("Expected dependencies [isAdult], got %v", field.Dependencies)
+	}
+
+	if err := derivedStore.Add(&rules.DerivedField{Name: "isAdult", Expression: "true"}); err == nil {
+		t.Error("Expected error adding duplicate derived field")
+	}
+
+	ruleID := uuid.New().String()
+	if err := engine.AddRule(&rules.Rule{
+		ID:         ruleID,
+		Name:       "senior",
+		Expression: "isSenior",
+		Active:     true,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	// A fresh engine must load and order the stored fields before compiling rules
+	reloaded, err := rules.NewEngineWithConfig(rules.EngineConfig{
+		Env:          newTestEnv(t),
+		Store:        rules.NewPostgresRuleStore(db, tenantID),
+		DerivedStore: derivedStore,
+	})
+	if err != nil {
+		t.Fatalf("Failed to reload engine: %v", err)
+	}
+
+	result, err := reloaded.Evaluate(ruleID, map[string]any{"User": map[string]any{"Age": 70}})
+	if err != nil {
+		t.Fatalf("Failed to evaluate rule: %v", err)
+	}
+	if !result.Matched {
+		t.Error("Expected rule to match using stored derived fields")
+	}
+
+	if err := engine.DeleteDerivedField("isAdult"); err == nil {
+		t.Error("Expected error deleting derived field referenced by another field")
+	}
+
+	fields, err := derivedStore.List()
+	if err != nil {
+		t.Fatalf("Failed to list derived fields: %v", err)
+	}
+	if len(fields) != 2 {
+		t.Errorf("Expected 2 derived fields, got %d", len(fields))
+	}
+}
+
+// newTestEnv creates a CEL environment with dynamic User and Transaction variables
+func newTestEnv(t *testing.T) *cel.Env {
+	t.Helper()
+
+	env, err := cel.NewEnv(
+		cel.Variable("User", cel.DynType),
+		cel.Variable("Transaction", cel.DynType),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create CEL environment: %v", err)
+	}
+	return env
+}
+
+// TestPostgresRuleStore_ListByTag tests tags and rule sets round-trip and tag lookups
+func TestPostgresRuleStore_ListByTag(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := createTenant(t, db, "test-tenant")
+	store := rules.NewPostgresRuleStore(db, tenantID)
+
+	testRules := []*rules.Rule{
+		{ID: uuid.New().String(), Name: "kyc", Expression: "User.Age >= 18", Active: true, Priority: 10,
+			Tags: []string{"kyc"}, RuleSets: []string{"onboarding"}},
+		{ID: uuid.New().String(), Name: "kyc-fraud", Expression: "User.Age >= 21", Active: false,
+			Tags: []string{"kyc", "fraud"}},
+		{ID: uuid.New().String(), Name: "untagged", Expression: "true", Active: true},
+	}
+	for _, rule := range testRules {
+		rule.CreatedAt = time.Now()
+		rule.UpdatedAt = time.Now()
+		if err := store.Add(rule); err != nil {
+			t.Fatalf("Failed to add rule: %v", err)
+		}
+	}
+
+	tagged, err := store.ListByTag("kyc")
+	if err != nil {
+		t.Fatalf("Failed to list rules by tag: %v", err)
+	}
+	if len(tagged) != 2 || tagged[0].Name != "kyc" || tagged[1].Name != "kyc-fraud" {
+		t.Errorf("Expected [kyc kyc-fraud], got %v", tagged)
+	}
+
+	got, err := store.Get(testRules[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to get rule: %v", err)
+	}
+	if len(got.RuleSets) != 1 || got.RuleSets[0] != "onboarding" {
+		t.Errorf("Expected rule sets [onboarding], got %v", got.RuleSets)
+	}
+
+	untagged, err := store.Get(testRules[2].ID)
+	if err != nil {
+		t.Fatalf("Failed to get rule: %v", err)
+	}
+	if len(untagged.Tags) != 0 {
+		t.Errorf("Expected no tags, got %v", untagged.Tags)
+	}
+}
+
+// TestPostgresRuleTestStore tests rule test case CRUD and that the cases gate rule updates
+func TestPostgresRuleTestStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := createTenant(t, db, "test-tenant")
+	testStore := rules.NewPostgresRuleTestStore(db, tenantID)
+
+	engine, err := rules.NewEngineWithConfig(rules.EngineConfig{
+		Env:       newTestEnv(t),
+		Store:     rules.NewPostgresRuleStore(db, tenantID),
+		TestStore: testStore,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	ruleID := uuid.New().String()
+	if err := engine.AddRule(&rules.Rule{
+		ID:         ruleID,
+		Name:       "adult",
+		Expression: "User.Age >= 18",
+		Active:     true,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	matched := false
+	tc := &rules.RuleTestCase{
+		ID:              uuid.New().String(),
+		RuleID:          ruleID,
+		Name:            "minor",
+		Facts:           map[string]any{"User": map[string]any{"Age": 12}},
+		ExpectedMatched: &matched,
+	}
+	if err := engine.AddTestCase(tc); err != nil {
+		t.Fatalf("Failed to add test case: %v", err)
+	}
+
+	got, err := testStore.Get(tc.ID)
+	if err != nil {
+		t.Fatalf("Failed to get test case: %v", err)
+	}
+	if got.ExpectedMatched == nil || *got.ExpectedMatched || got.ExpectedOutput != nil {
+		t.Errorf("Expected matched false and no output, got %+v", got)
+	}
+
+	err = engine.UpdateRule(&rules.Rule{ID: ruleID, Name: "adult", Expression: "User.Age >= 10", Active: true})
+	var failure *rules.TestFailureError
+	if !errors.As(err, &failure) {
+		t.Errorf("Expected TestFailureError, got %v", err)
+	}
+
+	// Test cases are isolated per tenant
+	otherStore := rules.NewPostgresRuleTestStore(db, createTenant(t, db, "other-tenant"))
+	if _, err := otherStore.Get(tc.ID); err == nil {
+		t.Error("Expected error getting another tenant's test case")
+	}
+
+	if err := engine.DeleteRule(ruleID); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	cases, err := testStore.List()
+	if err != nil {
+		t.Fatalf("Failed to list test cases: %v", err)
+	}
+	if len(cases) != 0 {
+		t.Errorf("Expected test cases to be deleted with the rule, got %d", len(cases))
+	}
+}
+
+// TestPostgresRuleStore_Shadow tests the shadow flag round-trips through rules and versions
+func TestPostgresRuleStore_Shadow(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := createTenant(t, db, "test-tenant")
+	store := rules.NewPostgresRuleStore(db, tenantID)
+
+	rule := &rules.Rule{
+		ID:         uuid.New().String(),
+		Name:       "newFraud",
+		Expression: "Transaction.Amount > 500.0",
+		Active:     true,
+		Shadow:     true,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	if err := store.Add(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	got, err := store.Get(rule.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rule: %v", err)
+	}
+	if !got.Shadow {
+		t.Error("Expected rule to be stored as shadow")
+	}
+
+	rule.Shadow = false
+	if err := store.Update(rule); err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+
+	v1, err := store.GetVersion(rule.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+	v2, err := store.GetVersion(rule.ID, 2)
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+	diff, err := rules.DiffRuleVersions(v1, v2)
+	if err != nil {
+		t.Fatalf("Failed to diff versions: %v", err)
+	}
+	if len(diff.Changes) != 1 || diff.Changes[0].Field != "Shadow" {
+		t.Errorf("Expected only Shadow to change, got %+v", diff.Changes)
+	}
+}
+
+// TestPostgresRuleStore_EffectiveWindow tests effective windows round-trip through rules and versions
+func TestPostgresRuleStore_EffectiveWindow(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := createTenant(t, db, "test-tenant")
+	store := rules.NewPostgresRuleStore(db, tenantID)
+
+	from := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+	rule := &rules.Rule{
+		ID:            uuid.New().String(),
+		Name:          "blackFriday",
+		Expression:    "true",
+		Active:        true,
+		EffectiveFrom: &from,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if err := store.Add(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	got, err := store.Get(rule.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rule: %v", err)
+	}
+	if got.EffectiveFrom == nil || !got.EffectiveFrom.Equal(from) || got.EffectiveUntil != nil {
+		t.Errorf("Expected window from %v with no end, got %v to %v", from, got.EffectiveFrom, got.EffectiveUntil)
+	}
+
+	// The database rejects windows that end before they start
+	until := from.Add(-time.Hour)
+	rule.EffectiveUntil = &until
+	if err := store.Update(rule); err == nil {
+		t.Error("Expected error storing an empty effective window")
+	}
+
+	version, err := store.GetVersion(rule.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+	if version.EffectiveFrom == nil || !version.EffectiveFrom.Equal(from) {
+		t.Errorf("Expected version window from %v, got %v", from, version.EffectiveFrom)
+	}
+}
+
+// TestPostgresRuleStore_ReferencedFields tests the fields a rule reads round-trip through the store
+func TestPostgresRuleStore_ReferencedFields(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := createTenant(t, db, "test-tenant")
+	store := rules.NewPostgresRuleStore(db, tenantID)
+	engine, err := rules.NewEngine(store)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	rule := &rules.Rule{ID: uuid.New().String(), Name: "adultInCanada", Expression: `User.Age >= 18 && User.Country == "CA"`, Active: true}
+	if err := engine.AddRule(rule); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	got, err := store.Get(rule.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rule: %v", err)
+	}
+	if len(got.ReferencedFields) != 2 || got.ReferencedFields[0] != "User.Age" || got.ReferencedFields[1] != "User.Country" {
+		t.Errorf("Expected [User.Age User.Country], got %v", got.ReferencedFields)
+	}
+
+	rule.Expression = "true"
+	if err := engine.UpdateRule(rule); err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	got, err = store.Get(rule.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rule: %v", err)
+	}
+	if len(got.ReferencedFields) != 0 {
+		t.Errorf("Expected no referenced fields, got %v", got.ReferencedFields)
+	}
+}
+
+// TestPostgresReferenceListStore tests reference lists round-trip and reload into a new engine
+func TestPostgresReferenceListStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := createTenant(t, db, "test-tenant")
+	listStore := rules.NewPostgresReferenceListStore(db, tenantID)
+	ruleStore := rules.NewPostgresRuleStore(db, tenantID)
+
+	engine, err := rules.NewEngineWithConfig(rules.EngineConfig{
+		Env:       newTestEnv(t),
+		Store:     ruleStore,
+		ListStore: listStore,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	if err := engine.AddReferenceList(&rules.ReferenceList{
+		Name:   "bands",
+		Kind:   rules.ListRanges,
+		Ranges: []rules.NumericRange{{Min: 100, Max: 200}},
+	}); err != nil {
+		t.Fatalf("Failed to add list: %v", err)
+	}
+	if err := engine.AddReferenceList(&rules.ReferenceList{Name: "bands", Kind: rules.ListRanges}); err == nil {
+		t.Error("Expected error adding a duplicate list")
+	}
+
+	got, err := listStore.Get("bands")
+	if err != nil {
+		t.Fatalf("Failed to get list: %v", err)
+	}
+	if got.Kind != rules.ListRanges || len(got.Ranges) != 1 || got.Ranges[0].Max != 200 {
+		t.Errorf("Expected one range 100-200, got %+v", got)
+	}
+
+	ruleID := uuid.New().String()
+	if err := engine.AddRule(&rules.Rule{
+		ID:         ruleID,
+		Name:       "band",
+		Expression: "Transaction.Amount in lists.bands",
+		Active:     true,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	if err := engine.UpdateReferenceList(&rules.ReferenceList{
+		Name:   "bands",
+		Kind:   rules.ListRanges,
+		Ranges: []rules.NumericRange{{Min: 100, Max: 500}},
+	}); err != nil {
+		t.Fatalf("Failed to update list: %v", err)
+	}
+
+	// A new engine loads the updated list from the database
+	reloaded, err := rules.NewEngineWithConfig(rules.EngineConfig{
+		Env:       newTestEnv(t),
+		Store:     ruleStore,
+		ListStore: listStore,
+	})
+	if err != nil {
+		t.Fatalf("Failed to reload engine: %v", err)
+	}
+	result, err := reloaded.Evaluate(ruleID, map[string]any{"Transaction": map[string]any{"Amount": 300}})
+	if err != nil || !result.Matched {
+		t.Errorf("Expected amount 300 to match after the update, got %+v, %v", result, err)
+	}
+
+	// Lists are isolated per tenant
+	otherStore := rules.NewPostgresReferenceListStore(db, createTenant(t, db, "other-tenant"))
+	if _, err := otherStore.Get("bands"); err == nil {
+		t.Error("Expected error getting another tenant's list")
+	}
+
+	if err := engine.DeleteRule(ruleID); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	if err := engine.DeleteReferenceList("bands"); err != nil {
+		t.Fatalf("Failed to delete list: %v", err)
+	}
+	if err := listStore.Delete("bands"); err == nil {
+		t.Error("Expected error deleting a missing list")
+	}
+}
+
+// readCountingStore counts the rule reads that reach Postgres
+type readCountingStore struct {
+	*rules.PostgresRuleStore
+	reads int
+}
+
+func (s *readCountingStore) Get(id string) (*rules.Rule, error) {
+	s.reads++
+	return s.PostgresRuleStore.Get(id)
+}
+
+func (s *readCountingStore) ListActive() ([]*rules.Rule, error) {
+	s.reads++
+	return s.PostgresRuleStore.ListActive()
+}
+
+func TestPostgresRuleStore_EvaluateWithoutQueries(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := createTenant(t, db, "test-tenant")
+	store := &readCountingStore{PostgresRuleStore: rules.NewPostgresRuleStore(db, tenantID)}
+	engine, err := rules.NewEngine(store)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	activeID := uuid.New().String()
+	inactiveID := uuid.New().String()
+	if err := engine.AddRule(&rules.Rule{ID: activeID, Name: "adult", Expression: `User.Age >= 18`, Active: true}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	if err := engine.AddRule(&rules.Rule{ID: inactiveID, Name: "senior", Expression: `User.Age >= 65`, Active: false}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	facts := map[string]any{"User": map[string]any{"Age": 70}}
+
+	store.reads = 0
+	result, err := engine.Evaluate(activeID, facts)
+	if err != nil || !result.Matched {
+		t.Errorf("Expected the active rule to match, got %+v, %v", result, err)
+	}
+	if _, err := engine.Evaluate(inactiveID, facts); !errors.Is(err, rules.ErrRuleNotActive) {
+		t.Errorf("Expected ErrRuleNotActive for the inactive rule, got %v", err)
+	}
+	results, err := engine.EvaluateRulesContext(context.Background(), []string{inactiveID, activeID}, facts, rules.EvaluateOptions{})
+	if err != nil || len(results) != 1 || results[0].RuleID != activeID {
+		t.Errorf("Expected only the active rule to be evaluated, got %+v, %v", results, err)
+	}
+	if store.reads != 0 {
+		t.Errorf("Expected evaluation to issue no queries, got %d", store.reads)
+	}
+}
@@ -0,0 +1,99 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrEvaluationTimeout is reported in the EvaluationResult of a rule that was
+// cut off because its time budget, or the request's, ran out
+var ErrEvaluationTimeout = errors.New("evaluation timed out")
+
+// ErrEvaluationCanceled is reported in the EvaluationResult of a rule that was
+// cut off because the caller's context was canceled
+var ErrEvaluationCanceled = errors.New("evaluation canceled")
+
+// interruptCheckFrequency is how many comprehension iterations CEL runs
+// between checks of the evaluation context, so long loops can be cut off
+const interruptCheckFrequency = 100
+
+// EvaluationLimits bounds how long evaluation may run
+// A zero duration means no limit
+type EvaluationLimits struct {
+	RuleTimeout    time.Duration // budget for each rule
+	RequestTimeout time.Duration // budget for a whole evaluation call
+}
+
+// Validate checks that the limits are not negative
+func (l EvaluationLimits) Validate() error {
+	if l.RuleTimeout < 0 {
+		return fmt.Errorf("rule timeout must not be negative")
+	}
+	if l.RequestTimeout < 0 {
+		return fmt.Errorf("request timeout must not be negative")
+	}
+	return nil
+}
+
+// requestContext applies the request budget to ctx
+func (l EvaluationLimits) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.RequestTimeout > 0 {
+		return context.WithTimeout(ctx, l.RequestTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// ruleContext applies the per-rule budget to ctx
+func (l EvaluationLimits) ruleContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.RuleTimeout > 0 {
+		return context.WithTimeout(ctx, l.RuleTimeout)
+	}
+	return ctx, func() {}
+}
+
+// interruptedError converts a finished context's error into the error
+// recorded for a rule that was cut off
+func interruptedError(ruleID string, err error) error {
+	return fmt.Errorf("rule %s: %w", ruleID, contextError(err))
+}
+
+// contextError maps a finished context's error to ErrEvaluationTimeout or
+// ErrEvaluationCanceled
+func contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrEvaluationTimeout
+	}
+	return ErrEvaluationCanceled
+}
+
+// interruptedResult builds the result for a rule that was cut off
+func interruptedResult(rule *Rule, err error) *EvaluationResult {
+	return &EvaluationResult{
+		RuleID:   rule.ID,
+		RuleName: rule.Name,
+		Matched:  false,
+		Error:    interruptedError(rule.ID, err),
+		TimedOut: true,
+	}
+}
+
+// EvaluationLimits returns the engine's evaluation time budgets
+func (en *Engine) EvaluationLimits() EvaluationLimits {
+	return en.snapshot.Load().limits
+}
+
+// SetEvaluationLimits replaces the engine's evaluation time budgets
+// The new limits apply to evaluations started after the call
+func (en *Engine) SetEvaluationLimits(limits EvaluationLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	en.publish(func(next *ruleSnapshot) {
+		next.limits = limits
+	})
+
+	return nil
+}
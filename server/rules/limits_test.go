@@ -0,0 +1,137 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowExpression runs a nested comprehension long enough to hit interrupt checks
+const slowExpression = `User.Items.all(x, User.Items.all(y, x + y >= 0))`
+
+// slowFacts returns facts for slowExpression
+func slowFacts() map[string]any {
+	items := make([]any, 300)
+	for i := range items {
+		items[i] = i
+	}
+	return map[string]any{"User": map[string]any{"Age": 30, "Items": items}}
+}
+
+// limitsRules returns a slow rule and a fast rule, evaluated in that order
+func limitsRules() []*Rule {
+	return []*Rule{
+		{ID: "slow", Name: "Slow", Expression: slowExpression, Active: true, Priority: 10},
+		{ID: "fast", Name: "Fast", Expression: `User.Age >= 18`, Active: true},
+	}
+}
+
+// TestEvaluateRuleTimeout verifies a rule exceeding its budget is cut off with a timeout error
+// while the remaining rules still run
+func TestEvaluateRuleTimeout(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, limitsRules()...)
+	if err := engine.SetEvaluationLimits(EvaluationLimits{RuleTimeout: time.Nanosecond}); err != nil {
+		t.Fatalf("SetEvaluationLimits() failed: %v", err)
+	}
+
+	results, err := engine.EvaluateAllContext(context.Background(), slowFacts(), EvaluateOptions{})
+	if err != nil {
+		t.Fatalf("EvaluateAllContext() failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	slow := results[0]
+	if !slow.TimedOut || !errors.Is(slow.Error, ErrEvaluationTimeout) {
+		t.Errorf("slow rule: TimedOut = %v, Error = %v, want timeout", slow.TimedOut, slow.Error)
+	}
+	if slow.Matched {
+		t.Error("slow rule should not match when cut off")
+	}
+
+	fast := results[1]
+	if fast.TimedOut || fast.Error != nil || !fast.Matched {
+		t.Errorf("fast rule: TimedOut = %v, Error = %v, Matched = %v, want match", fast.TimedOut, fast.Error, fast.Matched)
+	}
+}
+
+// TestEvaluateWithinBudget verifies generous budgets do not affect results
+func TestEvaluateWithinBudget(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, limitsRules()...)
+	limits := EvaluationLimits{RuleTimeout: time.Minute, RequestTimeout: time.Minute}
+	if err := engine.SetEvaluationLimits(limits); err != nil {
+		t.Fatalf("SetEvaluationLimits() failed: %v", err)
+	}
+
+	results, err := engine.EvaluateAll(slowFacts())
+	if err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+	for _, result := range results {
+		if result.Error != nil || !result.Matched {
+			t.Errorf("rule %s: Error = %v, Matched = %v, want match", result.RuleID, result.Error, result.Matched)
+		}
+	}
+}
+
+// TestEvaluateContextCanceled verifies a canceled context cuts off every rule
+func TestEvaluateContextCanceled(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, limitsRules()...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := engine.EvaluateAllContext(ctx, slowFacts(), EvaluateOptions{})
+	if err != nil {
+		t.Fatalf("EvaluateAllContext() failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected cut off rules to be reported, got %d results", len(results))
+	}
+	for _, result := range results {
+		if !result.TimedOut || !errors.Is(result.Error, ErrEvaluationCanceled) {
+			t.Errorf("rule %s: TimedOut = %v, Error = %v, want canceled", result.RuleID, result.TimedOut, result.Error)
+		}
+	}
+
+	_, err = engine.EvaluateContext(ctx, "fast", slowFacts())
+	if !errors.Is(err, ErrEvaluationCanceled) {
+		t.Errorf("EvaluateContext() error = %v, want canceled", err)
+	}
+}
+
+// TestEvaluateContextDeadline verifies an expired caller deadline is reported as a timeout
+func TestEvaluateContextDeadline(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, limitsRules()...)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	results, err := engine.EvaluateRulesContext(ctx, []string{"fast", "missing"}, slowFacts(), EvaluateOptions{})
+	if err != nil {
+		t.Fatalf("EvaluateRulesContext() failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if !errors.Is(results[0].Error, ErrEvaluationTimeout) {
+		t.Errorf("Error = %v, want timeout", results[0].Error)
+	}
+}
+
+// TestEvaluationLimitsValidate verifies negative budgets are rejected
+func TestEvaluationLimitsValidate(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, limitsRules()...)
+
+	if err := engine.SetEvaluationLimits(EvaluationLimits{RuleTimeout: -time.Second}); err == nil {
+		t.Error("expected negative rule timeout to be rejected")
+	}
+	if err := engine.SetEvaluationLimits(EvaluationLimits{RequestTimeout: -time.Second}); err == nil {
+		t.Error("expected negative request timeout to be rejected")
+	}
+	if got := engine.EvaluationLimits(); got != (EvaluationLimits{}) {
+		t.Errorf("EvaluationLimits() = %+v, want unchanged zero value", got)
+	}
+}
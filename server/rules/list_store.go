@@ -0,0 +1,110 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReferenceListStore manages reference list persistence and retrieval
+// Lists are identified by name, which is unique per tenant
+type ReferenceListStore interface {
+	// Add a new list
+	Add(list *ReferenceList) error
+
+	// Get a list by name
+	Get(name string) (*ReferenceList, error)
+
+	// List all lists
+	List() ([]*ReferenceList, error)
+
+	// Update the members of an existing list
+	Update(list *ReferenceList) error
+
+	// Delete a list
+	Delete(name string) error
+}
+
+// InMemoryReferenceListStore implements ReferenceListStore using an in-memory map
+type InMemoryReferenceListStore struct {
+	lists map[string]*ReferenceList
+	mu    sync.RWMutex
+}
+
+// NewInMemoryReferenceListStore creates a new in-memory reference list store
+func NewInMemoryReferenceListStore() *InMemoryReferenceListStore {
+	return &InMemoryReferenceListStore{
+		lists: make(map[string]*ReferenceList),
+	}
+}
+
+// Add adds a new list to the store
+func (s *InMemoryReferenceListStore) Add(list *ReferenceList) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.lists[list.Name]; exists {
+		return fmt.Errorf("list %s already exists", list.Name)
+	}
+
+	list.CreatedAt = time.Now()
+	list.UpdatedAt = list.CreatedAt
+	s.lists[list.Name] = list
+	return nil
+}
+
+// Get retrieves a list by name
+func (s *InMemoryReferenceListStore) Get(name string) (*ReferenceList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list, exists := s.lists[name]
+	if !exists {
+		return nil, fmt.Errorf("list %s not found", name)
+	}
+	return list, nil
+}
+
+// List returns all lists ordered by name
+func (s *InMemoryReferenceListStore) List() ([]*ReferenceList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lists := make([]*ReferenceList, 0, len(s.lists))
+	for _, list := range s.lists {
+		lists = append(lists, list)
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].Name < lists[j].Name
+	})
+	return lists, nil
+}
+
+// Update replaces the members of an existing list
+func (s *InMemoryReferenceListStore) Update(list *ReferenceList) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.lists[list.Name]
+	if !exists {
+		return fmt.Errorf("list %s not found", list.Name)
+	}
+
+	list.CreatedAt = existing.CreatedAt
+	list.UpdatedAt = time.Now()
+	s.lists[list.Name] = list
+	return nil
+}
+
+// Delete removes a list from the store
+func (s *InMemoryReferenceListStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.lists[name]; !exists {
+		return fmt.Errorf("list %s not found", name)
+	}
+	delete(s.lists, name)
+	return nil
+}
@@ -0,0 +1,543 @@
+package rules
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// listRefPrefix qualifies the variables reference lists are bound to,
+// e.g. lists.sanctionedCountries
+const listRefPrefix = "lists."
+
+// ListKind is the kind of values a reference list holds
+type ListKind string
+
+const (
+	// ListStrings is a set of strings, e.g. country codes
+	ListStrings ListKind = "strings"
+	// ListNumbers is a set of numbers, e.g. merchant category codes
+	ListNumbers ListKind = "numbers"
+	// ListRanges is a set of inclusive numeric ranges, e.g. BIN ranges
+	ListRanges ListKind = "ranges"
+)
+
+// Reference lists are opaque containers in CEL, so the only operation on them
+// is the in operator. Membership is decided by the list value at evaluation
+// time, which is what lets a list's members change without recompiling rules.
+var (
+	stringSetType = cel.OpaqueType("lists.StringSet").WithTraits(traits.ContainerType)
+	numberSetType = cel.OpaqueType("lists.NumberSet").WithTraits(traits.ContainerType)
+	rangeSetType  = cel.OpaqueType("lists.RangeSet").WithTraits(traits.ContainerType)
+)
+
+// listFunctions declares the in operator for reference lists
+// The overloads have no bindings: the standard in operator already dispatches
+// to the Contains method of container values.
+func listFunctions() cel.EnvOption {
+	var overloads []cel.FunctionOpt
+	overloads = append(overloads,
+		cel.Overload("in_string_lists_stringset", []*cel.Type{cel.StringType, stringSetType}, cel.BoolType))
+	for _, set := range []*cel.Type{numberSetType, rangeSetType} {
+		name := strings.ToLower(strings.TrimPrefix(set.String(), "lists."))
+		for _, number := range []*cel.Type{cel.IntType, cel.UintType, cel.DoubleType} {
+			overloads = append(overloads,
+				cel.Overload(fmt.Sprintf("in_%s_lists_%s", number, name), []*cel.Type{number, set}, cel.BoolType))
+		}
+	}
+	return cel.Function(operators.In, overloads...)
+}
+
+// declareListFunctions extends env and fallbackEnv with the in operator for
+// reference lists
+func declareListFunctions(env, fallbackEnv *cel.Env) (*cel.Env, *cel.Env, error) {
+	extended, err := env.Extend(listFunctions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to declare reference list functions: %w", err)
+	}
+	if fallbackEnv == nil {
+		return extended, nil, nil
+	}
+	extendedFallback, err := fallbackEnv.Extend(listFunctions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to declare reference list functions: %w", err)
+	}
+	return extended, extendedFallback, nil
+}
+
+// declareLists extends env and fallbackEnv with a lists.<name> variable for
+// every list
+func declareLists(env, fallbackEnv *cel.Env, lists map[string]ref.Val) (*cel.Env, *cel.Env, error) {
+	if len(lists) == 0 {
+		return env, fallbackEnv, nil
+	}
+
+	names := make([]string, 0, len(lists))
+	for name := range lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	opts := make([]cel.EnvOption, 0, len(names))
+	for _, name := range names {
+		opts = append(opts, cel.Variable(name, lists[name].Type().(*types.Type)))
+	}
+
+	declared, err := env.Extend(opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to declare reference lists: %w", err)
+	}
+	declaredFallback := fallbackEnv
+	if fallbackEnv != nil {
+		declaredFallback, err = fallbackEnv.Extend(opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to declare reference lists: %w", err)
+		}
+	}
+
+	return declared, declaredFallback, nil
+}
+
+// validateReferenceList checks a list's name, kind and members
+// Only the members matching the list's kind may be set
+func validateReferenceList(list *ReferenceList) error {
+	if !derivedFieldNamePattern.MatchString(list.Name) {
+		return fmt.Errorf("invalid list name %q: must be a valid identifier", list.Name)
+	}
+
+	switch list.Kind {
+	case ListStrings:
+		if list.Numbers != nil || list.Ranges != nil {
+			return fmt.Errorf("list %s: a strings list may only have strings", list.Name)
+		}
+	case ListNumbers:
+		if list.Strings != nil || list.Ranges != nil {
+			return fmt.Errorf("list %s: a numbers list may only have numbers", list.Name)
+		}
+		for _, n := range list.Numbers {
+			if math.IsNaN(n) {
+				return fmt.Errorf("list %s: numbers must not be NaN", list.Name)
+			}
+		}
+	case ListRanges:
+		if list.Strings != nil || list.Numbers != nil {
+			return fmt.Errorf("list %s: a ranges list may only have ranges", list.Name)
+		}
+		for _, r := range list.Ranges {
+			if math.IsNaN(r.Min) || math.IsNaN(r.Max) {
+				return fmt.Errorf("list %s: range bounds must not be NaN", list.Name)
+			}
+			if r.Min > r.Max {
+				return fmt.Errorf("list %s: range min %v is greater than max %v", list.Name, r.Min, r.Max)
+			}
+		}
+	default:
+		return fmt.Errorf("list %s: unknown kind %q (must be one of: strings, numbers, ranges)", list.Name, list.Kind)
+	}
+
+	return nil
+}
+
+// listValue is the CEL value of a reference list
+// contains is a hash-set lookup for strings and numbers, and a binary search
+// over merged ranges for ranges
+type listValue struct {
+	list     *ReferenceList
+	typ      *types.Type
+	contains func(ref.Val) bool
+}
+
+// newListValue builds the lookup structure for a list
+// Int and uint facts are compared with the members exactly rather than as
+// float64, which cannot hold every integer above 2^53.
+func newListValue(list *ReferenceList) *listValue {
+	switch list.Kind {
+	case ListNumbers:
+		doubles := make(map[float64]struct{}, len(list.Numbers))
+		ints := make(map[int64]struct{}, len(list.Numbers))
+		uints := make(map[uint64]struct{})
+		for _, n := range list.Numbers {
+			doubles[n] = struct{}{}
+			if n != math.Trunc(n) {
+				continue
+			}
+			switch {
+			case n >= -twoTo63 && n < twoTo63:
+				ints[int64(n)] = struct{}{}
+			case n >= twoTo63 && n < twoTo64:
+				uints[uint64(n)] = struct{}{}
+			}
+		}
+		return &listValue{list: list, typ: numberSetType, contains: func(v ref.Val) bool {
+			var found bool
+			switch n := v.(type) {
+			case types.Int:
+				_, found = ints[int64(n)]
+			case types.Uint:
+				if n <= math.MaxInt64 {
+					_, found = ints[int64(n)]
+				} else {
+					_, found = uints[uint64(n)]
+				}
+			case types.Double:
+				_, found = doubles[float64(n)]
+			}
+			return found
+		}}
+	case ListRanges:
+		ranges := mergeRanges(list.Ranges)
+		return &listValue{list: list, typ: rangeSetType, contains: func(v ref.Val) bool {
+			if _, ok := compareNumber(v, 0); !ok {
+				return false
+			}
+			// The first range ending at or after v is the only one that can hold it
+			i := sort.Search(len(ranges), func(i int) bool {
+				c, _ := compareNumber(v, ranges[i].Max)
+				return c <= 0
+			})
+			if i == len(ranges) {
+				return false
+			}
+			c, _ := compareNumber(v, ranges[i].Min)
+			return c >= 0
+		}}
+	default:
+		set := make(map[string]struct{}, len(list.Strings))
+		for _, s := range list.Strings {
+			set[s] = struct{}{}
+		}
+		return &listValue{list: list, typ: stringSetType, contains: func(v ref.Val) bool {
+			s, ok := v.(types.String)
+			if !ok {
+				return false
+			}
+			_, found := set[string(s)]
+			return found
+		}}
+	}
+}
+
+// twoTo63 and twoTo64 bound the float64 values that convert to int64 and
+// uint64 exactly
+const (
+	twoTo63 = float64(1 << 63)
+	twoTo64 = float64(1 << 64)
+)
+
+// compareNumber compares a CEL number with f, returning -1, 0 or 1 as v is
+// less than, equal to or greater than f
+// Ints and uints are compared exactly; ok is false when v is not a number.
+func compareNumber(v ref.Val, f float64) (c int, ok bool) {
+	switch n := v.(type) {
+	case types.Int:
+		switch {
+		case f >= twoTo63:
+			return -1, true
+		case f < -twoTo63:
+			return 1, true
+		}
+		return compareTruncated(cmp.Compare(int64(n), int64(f)), f), true
+	case types.Uint:
+		switch {
+		case f >= twoTo64:
+			return -1, true
+		case f < 0:
+			return 1, true
+		}
+		return compareTruncated(cmp.Compare(uint64(n), uint64(f)), f), true
+	case types.Double:
+		return cmp.Compare(float64(n), f), true
+	}
+	return 0, false
+}
+
+// compareTruncated turns c, the comparison of an integer with f truncated
+// toward zero, into its comparison with f itself
+func compareTruncated(c int, f float64) int {
+	if c != 0 {
+		return c
+	}
+	switch t := math.Trunc(f); {
+	case f > t:
+		return -1
+	case f < t:
+		return 1
+	}
+	return 0
+}
+
+// mergeRanges sorts ranges by their lower bound and merges overlapping ones,
+// so at most one range can contain any number
+func mergeRanges(ranges []NumericRange) []NumericRange {
+	sorted := append([]NumericRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Min < sorted[j].Min })
+
+	merged := make([]NumericRange, 0, len(sorted))
+	for _, r := range sorted {
+		if n := len(merged); n > 0 && r.Min <= merged[n-1].Max {
+			merged[n-1].Max = math.Max(merged[n-1].Max, r.Max)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Contains implements traits.Container, which the in operator calls
+func (l *listValue) Contains(v ref.Val) ref.Val {
+	if types.IsUnknownOrError(v) {
+		return v
+	}
+	return types.Bool(l.contains(v))
+}
+
+// ConvertToNative converts the list to a JSON value, used when explaining
+// results; other conversions are not supported
+func (l *listValue) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	if typeDesc != reflect.TypeOf(&structpb.Value{}) {
+		return nil, fmt.Errorf("type conversion error from %s to '%v'", l.typ, typeDesc)
+	}
+
+	var members []any
+	switch l.list.Kind {
+	case ListStrings:
+		for _, s := range l.list.Strings {
+			members = append(members, s)
+		}
+	case ListNumbers:
+		for _, n := range l.list.Numbers {
+			members = append(members, n)
+		}
+	case ListRanges:
+		for _, r := range l.list.Ranges {
+			members = append(members, map[string]any{"Min": r.Min, "Max": r.Max})
+		}
+	}
+	return structpb.NewValue(members)
+}
+
+// ConvertToType supports type(lists.<name>)
+func (l *listValue) ConvertToType(typeVal ref.Type) ref.Val {
+	if typeVal == types.TypeType {
+		return l.typ
+	}
+	return types.NewErr("type conversion error from '%s' to '%s'", l.typ, typeVal)
+}
+
+// Equal reports whether other is the same list value
+func (l *listValue) Equal(other ref.Val) ref.Val {
+	return types.Bool(l == other)
+}
+
+// Type returns the list's CEL type
+func (l *listValue) Type() ref.Type {
+	return l.typ
+}
+
+// Value returns the list
+func (l *listValue) Value() any {
+	return l.list
+}
+
+// compileLists builds the values of lists, keyed by their lists.<name> variable
+func compileLists(lists []*ReferenceList) map[string]ref.Val {
+	values := make(map[string]ref.Val, len(lists))
+	for _, list := range lists {
+		values[listRefPrefix+list.Name] = newListValue(list)
+	}
+	return values
+}
+
+// bindLists returns a copy of facts with every list bound to its lists.<name>
+// variable; facts are returned as they are when there are no lists
+func bindLists(lists map[string]ref.Val, facts map[string]any) map[string]any {
+	if len(lists) == 0 {
+		return facts
+	}
+
+	bound := make(map[string]any, len(facts)+len(lists))
+	for k, v := range facts {
+		bound[k] = v
+	}
+	for name, list := range lists {
+		bound[name] = list
+	}
+	return bound
+}
+
+// ReferenceLists returns the engine's reference lists ordered by name
+func (en *Engine) ReferenceLists() ([]*ReferenceList, error) {
+	return en.listStore.List()
+}
+
+// AddReferenceList validates and stores a new reference list, declaring it to
+// rules as lists.<name>
+func (en *Engine) AddReferenceList(list *ReferenceList) error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	if err := validateReferenceList(list); err != nil {
+		return err
+	}
+	// A schema object named lists would be shadowed by the list variables
+	if _, issues := en.coreEnv.Compile("lists"); issues == nil || issues.Err() == nil {
+		return fmt.Errorf("reference lists are unavailable: the schema already declares lists")
+	}
+
+	current := en.snapshot.Load().lists
+	if _, exists := current[listRefPrefix+list.Name]; exists {
+		return fmt.Errorf("list %s already exists", list.Name)
+	}
+
+	lists := make(map[string]ref.Val, len(current)+1)
+	for name, value := range current {
+		lists[name] = value
+	}
+	lists[listRefPrefix+list.Name] = newListValue(list)
+
+	return en.applyLists(lists, func() error {
+		return en.listStore.Add(list)
+	})
+}
+
+// UpdateReferenceList replaces the members of an existing reference list
+// The list's kind cannot change, so rules are not recompiled: the new members
+// take effect for evaluations that start after the update. An empty Kind
+// keeps the list's kind.
+func (en *Engine) UpdateReferenceList(list *ReferenceList) error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	current := en.snapshot.Load().lists
+	existing, exists := current[listRefPrefix+list.Name]
+	if !exists {
+		return fmt.Errorf("list %s not found", list.Name)
+	}
+	kind := existing.(*listValue).list.Kind
+	if list.Kind == "" {
+		list.Kind = kind
+	}
+	if list.Kind != kind {
+		return fmt.Errorf("list %s: kind cannot change from %s to %s", list.Name, kind, list.Kind)
+	}
+
+	if err := validateReferenceList(list); err != nil {
+		return err
+	}
+
+	if err := en.listStore.Update(list); err != nil {
+		return err
+	}
+
+	// Evaluations in flight keep the map they started with
+	lists := make(map[string]ref.Val, len(current))
+	for name, value := range current {
+		lists[name] = value
+	}
+	lists[listRefPrefix+list.Name] = newListValue(list)
+
+	en.publish(func(next *ruleSnapshot) {
+		next.lists = lists
+	})
+
+	return nil
+}
+
+// DeleteReferenceList removes a reference list
+// Deletion is rejected while a rule or derived field references it
+func (en *Engine) DeleteReferenceList(name string) error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	current := en.snapshot.Load().lists
+	if _, exists := current[listRefPrefix+name]; !exists {
+		return fmt.Errorf("list %s not found", name)
+	}
+
+	lists := make(map[string]ref.Val, len(current))
+	for key, value := range current {
+		if key != listRefPrefix+name {
+			lists[key] = value
+		}
+	}
+
+	return en.applyLists(lists, func() error {
+		return en.listStore.Delete(name)
+	})
+}
+
+// ReloadReferenceLists brings the engine's reference lists up to date with
+// the list store, e.g. after another replica changed them
+// When only members changed the new lists are swapped in without recompiling
+// rules, as with UpdateReferenceList; added, deleted or re-kinded lists
+// recompile derived fields and active rules against them.
+func (en *Engine) ReloadReferenceLists() error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	stored, err := en.listStore.List()
+	if err != nil {
+		return fmt.Errorf("failed to load reference lists: %w", err)
+	}
+	lists := compileLists(stored)
+
+	if sameListKinds(en.snapshot.Load().lists, lists) {
+		en.publish(func(next *ruleSnapshot) {
+			next.lists = lists
+		})
+		return nil
+	}
+
+	return en.applyLists(lists, func() error {
+		return nil
+	})
+}
+
+// sameListKinds reports whether a and b declare the same lists with the same
+// kinds, so rules compiled against one run unchanged against the other
+func sameListKinds(a, b map[string]ref.Val) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		other, ok := b[name]
+		if !ok || other.(*listValue).list.Kind != value.(*listValue).list.Kind {
+			return false
+		}
+	}
+	return true
+}
+
+// applyLists declares the candidate lists and recompiles derived fields and
+// active rules against them, then persists the change and swaps in the lists
+// Callers must hold writeMu
+func (en *Engine) applyLists(lists map[string]ref.Val, persist func() error) error {
+	baseEnv, baseFallbackEnv, err := declareLists(en.coreEnv, en.coreFallbackEnv, lists)
+	if err != nil {
+		return err
+	}
+
+	fields, err := en.derivedStore.List()
+	if err != nil {
+		return err
+	}
+
+	return en.rebuildEnv(envChange{
+		baseEnv:         baseEnv,
+		baseFallbackEnv: baseFallbackEnv,
+		lists:           lists,
+		fields:          cloneDerivedFields(fields),
+		description:     "list change",
+		persist:         persist,
+	})
+}
@@ -0,0 +1,322 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// TestReferenceListsInRules verifies rules can test membership of each kind of list
+func TestReferenceListsInRules(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	for _, list := range []*ReferenceList{
+		{Name: "sanctionedCountries", Kind: ListStrings, Strings: []string{"KP", "IR"}},
+		{Name: "blockedMerchants", Kind: ListNumbers, Numbers: []float64{5967, 7995}},
+		{Name: "reviewBands", Kind: ListRanges, Ranges: []NumericRange{{Min: 900, Max: 1000}, {Min: 100, Max: 200}, {Min: 150, Max: 300}}},
+	} {
+		if err := engine.AddReferenceList(list); err != nil {
+			t.Fatalf("AddReferenceList(%s) failed: %v", list.Name, err)
+		}
+	}
+
+	rules := []*Rule{
+		{ID: "country", Name: "country", Expression: `User.Country in lists.sanctionedCountries`, Active: true},
+		{ID: "merchant", Name: "merchant", Expression: `Transaction.MCC in lists.blockedMerchants`, Active: true},
+		{ID: "band", Name: "band", Expression: `Transaction.Amount in lists.reviewBands`, Active: true},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		ruleID string
+		facts  map[string]any
+		want   bool
+	}{
+		{"Sanctioned country", "country", map[string]any{"User": map[string]any{"Country": "KP"}}, true},
+		{"Other country", "country", map[string]any{"User": map[string]any{"Country": "CA"}}, false},
+		{"Blocked merchant as int", "merchant", map[string]any{"Transaction": map[string]any{"MCC": 7995}}, true},
+		{"Blocked merchant as float", "merchant", map[string]any{"Transaction": map[string]any{"MCC": 5967.0}}, true},
+		{"Other merchant", "merchant", map[string]any{"Transaction": map[string]any{"MCC": 5411}}, false},
+		{"Range lower bound", "band", map[string]any{"Transaction": map[string]any{"Amount": 100}}, true},
+		{"Merged ranges", "band", map[string]any{"Transaction": map[string]any{"Amount": 250.5}}, true},
+		{"Range upper bound", "band", map[string]any{"Transaction": map[string]any{"Amount": 1000}}, true},
+		{"Between ranges", "band", map[string]any{"Transaction": map[string]any{"Amount": 500}}, false},
+		{"Below ranges", "band", map[string]any{"Transaction": map[string]any{"Amount": 99.9}}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := engine.Evaluate(tc.ruleID, tc.facts)
+			if err != nil {
+				t.Fatalf("Evaluate() failed: %v", err)
+			}
+			if result.Matched != tc.want {
+				t.Errorf("Matched = %v, want %v", result.Matched, tc.want)
+			}
+		})
+	}
+}
+
+// TestReferenceListsTypeChecked verifies membership checks are type checked
+// against the list's kind
+func TestReferenceListsTypeChecked(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	if err := engine.AddReferenceList(&ReferenceList{Name: "countries", Kind: ListStrings, Strings: []string{"KP"}}); err != nil {
+		t.Fatalf("AddReferenceList() failed: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		expression string
+	}{
+		{"Number in strings list", `1 in lists.countries`},
+		{"Unknown list", `User.Country in lists.missing`},
+		{"List used as a value", `lists.countries == "KP"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := engine.AddRule(&Rule{ID: "r1", Name: "r1", Expression: tc.expression, Active: true})
+			if err == nil {
+				t.Errorf("AddRule(%s) should fail", tc.expression)
+			}
+		})
+	}
+}
+
+// TestReferenceListsLiveUpdate verifies updating a list's members changes
+// results without recompiling rules
+func TestReferenceListsLiveUpdate(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	if err := engine.AddReferenceList(&ReferenceList{Name: "countries", Kind: ListStrings, Strings: []string{"KP"}}); err != nil {
+		t.Fatalf("AddReferenceList() failed: %v", err)
+	}
+	if err := engine.AddRule(&Rule{ID: "r1", Name: "r1", Expression: `User.Country in lists.countries`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	engine.mu.RLock()
+	before := engine.snapshot.Load().programs["r1"]
+	engine.mu.RUnlock()
+
+	facts := map[string]any{"User": map[string]any{"Country": "RU"}}
+	if result, _ := engine.Evaluate("r1", facts); result.Matched {
+		t.Fatal("RU should not match before the update")
+	}
+
+	if err := engine.UpdateReferenceList(&ReferenceList{Name: "countries", Kind: ListStrings, Strings: []string{"KP", "RU"}}); err != nil {
+		t.Fatalf("UpdateReferenceList() failed: %v", err)
+	}
+
+	if result, _ := engine.Evaluate("r1", facts); !result.Matched {
+		t.Error("RU should match after the update")
+	}
+	results, _ := engine.EvaluateAll(facts)
+	if len(results) != 1 || !results[0].Matched {
+		t.Errorf("EvaluateAll() = %+v, want r1 matched", results)
+	}
+
+	engine.mu.RLock()
+	after := engine.snapshot.Load().programs["r1"]
+	engine.mu.RUnlock()
+	if before != after {
+		t.Error("updating a list should not recompile rules")
+	}
+
+	err := engine.UpdateReferenceList(&ReferenceList{Name: "countries", Kind: ListNumbers, Numbers: []float64{1}})
+	if err == nil || !strings.Contains(err.Error(), "kind cannot change") {
+		t.Errorf("UpdateReferenceList() error = %v, want kind change rejected", err)
+	}
+}
+
+// TestReferenceListsDelete verifies lists cannot be deleted while rules or
+// derived fields reference them
+func TestReferenceListsDelete(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	if err := engine.AddReferenceList(&ReferenceList{Name: "countries", Kind: ListStrings, Strings: []string{"KP"}}); err != nil {
+		t.Fatalf("AddReferenceList() failed: %v", err)
+	}
+	if err := engine.AddDerivedField(&DerivedField{Name: "sanctioned", Expression: `User.Country in lists.countries`}); err != nil {
+		t.Fatalf("AddDerivedField() failed: %v", err)
+	}
+	if err := engine.AddRule(&Rule{ID: "r1", Name: "r1", Expression: `sanctioned`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	if result, _ := engine.Evaluate("r1", map[string]any{"User": map[string]any{"Country": "KP"}}); !result.Matched {
+		t.Error("derived field should see the list")
+	}
+
+	if err := engine.DeleteReferenceList("countries"); err == nil {
+		t.Error("DeleteReferenceList() should fail while a derived field references the list")
+	}
+
+	if err := engine.DeleteRule("r1"); err != nil {
+		t.Fatalf("DeleteRule() failed: %v", err)
+	}
+	if err := engine.DeleteDerivedField("sanctioned"); err != nil {
+		t.Fatalf("DeleteDerivedField() failed: %v", err)
+	}
+	if err := engine.DeleteReferenceList("countries"); err != nil {
+		t.Fatalf("DeleteReferenceList() failed: %v", err)
+	}
+	if err := engine.DeleteReferenceList("countries"); err == nil {
+		t.Error("DeleteReferenceList() should fail for a missing list")
+	}
+}
+
+// TestReferenceListsValidation verifies invalid lists are rejected
+func TestReferenceListsValidation(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	if err := engine.AddReferenceList(&ReferenceList{Name: "countries", Kind: ListStrings, Strings: []string{"KP"}}); err != nil {
+		t.Fatalf("AddReferenceList() failed: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		list *ReferenceList
+	}{
+		{"Invalid name", &ReferenceList{Name: "bad-name", Kind: ListStrings}},
+		{"Unknown kind", &ReferenceList{Name: "x", Kind: "dates"}},
+		{"Mismatched members", &ReferenceList{Name: "x", Kind: ListStrings, Numbers: []float64{1}}},
+		{"Inverted range", &ReferenceList{Name: "x", Kind: ListRanges, Ranges: []NumericRange{{Min: 10, Max: 1}}}},
+		{"Duplicate name", &ReferenceList{Name: "countries", Kind: ListStrings}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := engine.AddReferenceList(tc.list); err == nil {
+				t.Error("AddReferenceList() should fail")
+			}
+		})
+	}
+}
+
+// TestReferenceListsReload verifies an engine loads its lists from the store
+func TestReferenceListsReload(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("User", cel.DynType))
+	if err != nil {
+		t.Fatalf("cel.NewEnv() failed: %v", err)
+	}
+	store := NewInMemoryRuleStore()
+	listStore := NewInMemoryReferenceListStore()
+	engine, err := NewEngineWithConfig(EngineConfig{Env: env, Store: store, ListStore: listStore})
+	if err != nil {
+		t.Fatalf("NewEngineWithConfig() failed: %v", err)
+	}
+	if err := engine.AddReferenceList(&ReferenceList{Name: "countries", Kind: ListStrings, Strings: []string{"KP"}}); err != nil {
+		t.Fatalf("AddReferenceList() failed: %v", err)
+	}
+	if err := engine.AddRule(&Rule{ID: "r1", Name: "r1", Expression: `User.Country in lists.countries`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	reloaded, err := NewEngineWithConfig(EngineConfig{Env: env, Store: store, ListStore: listStore})
+	if err != nil {
+		t.Fatalf("NewEngineWithConfig() failed: %v", err)
+	}
+	result, err := reloaded.Evaluate("r1", map[string]any{"User": map[string]any{"Country": "KP"}})
+	if err != nil || !result.Matched {
+		t.Errorf("Evaluate() = %+v, %v; want matched", result, err)
+	}
+}
+
+// TestReloadReferenceLists verifies an engine picks up lists changed through
+// another engine over the same stores, recompiling rules only when the
+// declared lists change
+func TestReloadReferenceLists(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("User", cel.DynType))
+	if err != nil {
+		t.Fatalf("cel.NewEnv() failed: %v", err)
+	}
+	store := NewInMemoryRuleStore()
+	listStore := NewInMemoryReferenceListStore()
+	newEngine := func() *Engine {
+		engine, err := NewEngineWithConfig(EngineConfig{Env: env, Store: store, ListStore: listStore})
+		if err != nil {
+			t.Fatalf("NewEngineWithConfig() failed: %v", err)
+		}
+		return engine
+	}
+
+	writer := newEngine()
+	if err := writer.AddReferenceList(&ReferenceList{Name: "countries", Kind: ListStrings, Strings: []string{"KP"}}); err != nil {
+		t.Fatalf("AddReferenceList() failed: %v", err)
+	}
+	if err := writer.AddRule(&Rule{ID: "r1", Name: "r1", Expression: `User.Country in lists.countries`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	reader := newEngine()
+	before := reader.snapshot.Load().programs["r1"]
+
+	// Changed members are swapped in without recompiling
+	if err := writer.UpdateReferenceList(&ReferenceList{Name: "countries", Strings: []string{"KP", "RU"}}); err != nil {
+		t.Fatalf("UpdateReferenceList() failed: %v", err)
+	}
+	if err := reader.ReloadReferenceLists(); err != nil {
+		t.Fatalf("ReloadReferenceLists() failed: %v", err)
+	}
+	if result, _ := reader.Evaluate("r1", map[string]any{"User": map[string]any{"Country": "RU"}}); !result.Matched {
+		t.Error("RU should match after the reload")
+	}
+	if reader.snapshot.Load().programs["r1"] != before {
+		t.Error("reloading changed members should not recompile rules")
+	}
+
+	// A new list is declared, and rules using it compile
+	if err := writer.AddReferenceList(&ReferenceList{Name: "codes", Kind: ListNumbers, Numbers: []float64{7995}}); err != nil {
+		t.Fatalf("AddReferenceList() failed: %v", err)
+	}
+	if err := writer.AddRule(&Rule{ID: "r2", Name: "r2", Expression: `User.Code in lists.codes`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	if err := reader.ReloadReferenceLists(); err != nil {
+		t.Fatalf("ReloadReferenceLists() failed: %v", err)
+	}
+	if result, err := reader.Evaluate("r2", map[string]any{"User": map[string]any{"Code": 7995}}); err != nil || !result.Matched {
+		t.Errorf("Evaluate() = %+v, %v; want matched", result, err)
+	}
+}
+
+// TestReferenceListsLargeIntegers verifies int and uint facts are compared
+// with list members exactly, not after rounding to float64
+func TestReferenceListsLargeIntegers(t *testing.T) {
+	const big = 1 << 53 // the last integer float64 holds before rounding
+	numbers := newListValue(&ReferenceList{Name: "ids", Kind: ListNumbers, Numbers: []float64{big, 1 << 63, 2.5}})
+	ranges := newListValue(&ReferenceList{Name: "bands", Kind: ListRanges, Ranges: []NumericRange{{Min: -1.5, Max: big}}})
+
+	testCases := []struct {
+		name  string
+		list  *listValue
+		value ref.Val
+		want  bool
+	}{
+		{"Member", numbers, types.Int(big), true},
+		{"Rounds to member", numbers, types.Int(big + 1), false},
+		{"Uint member above int64", numbers, types.Uint(1 << 63), true},
+		{"Uint rounding to member", numbers, types.Uint(1<<63 + 1), false},
+		{"Fractional member as int", numbers, types.Int(2), false},
+		{"Fractional member as double", numbers, types.Double(2.5), true},
+		{"Range upper bound", ranges, types.Int(big), true},
+		{"Rounds to range upper bound", ranges, types.Int(big + 1), false},
+		{"Uint rounding to range upper bound", ranges, types.Uint(big + 1), false},
+		{"Int above fractional lower bound", ranges, types.Int(-1), true},
+		{"Int below fractional lower bound", ranges, types.Int(-2), false},
+		{"Not a number", ranges, types.String("1"), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.list.contains(tc.value); got != tc.want {
+				t.Errorf("contains(%v) = %v, want %v", tc.value, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+)
+
+// DefaultCostLimit is the runtime cost limit of engines whose options do not
+// set one
+// REQ-SEC-001: prevents resource exhaustion from malicious/complex expressions
+const DefaultCostLimit = 1000000
+
+// extensionLibraries maps the CEL extension libraries an engine may enable to
+// their environment options
+var extensionLibraries = map[string]func() cel.EnvOption{
+	"bindings":       func() cel.EnvOption { return ext.Bindings() },
+	"comprehensions": func() cel.EnvOption { return ext.TwoVarComprehensions() },
+	"encoders":       func() cel.EnvOption { return ext.Encoders() },
+	"lists":          func() cel.EnvOption { return ext.Lists() },
+	"math":           func() cel.EnvOption { return ext.Math() },
+	"regex":          func() cel.EnvOption { return ext.Regex() },
+	"sets":           func() cel.EnvOption { return ext.Sets() },
+	"strings":        func() cel.EnvOption { return ext.Strings() },
+}
+
+// ExtensionLibraries returns the names of the CEL extension libraries an
+// engine may enable, sorted
+func ExtensionLibraries() []string {
+	names := make([]string, 0, len(extensionLibraries))
+	for name := range extensionLibraries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EngineOptions configures how an engine compiles and evaluates rules
+// The zero value matches the engine's defaults. Options are fixed for the
+// lifetime of an engine; changing them means building a new engine.
+type EngineOptions struct {
+	// CostLimit is the CEL cost at which a rule's evaluation is aborted;
+	// 0 uses DefaultCostLimit
+	CostLimit uint64
+
+	// DisableStateTracking stops recording the value of each sub-expression
+	// while rules run, which makes evaluation cheaper but leaves results
+	// without explanations
+	DisableStateTracking bool
+
+	// CacheTTL is how long the active rules and their programs are cached
+	// before they are rebuilt from the store in the background; 0 caches them
+	// until a rule changes
+	CacheTTL time.Duration
+
+	// Extensions names the CEL extension libraries rules may use, e.g.
+	// "strings" for "abc".upperAscii()
+	Extensions []string
+
+	// EvaluationMode is the mode used by evaluation requests that do not set
+	// one; MaxMatches applies to EvaluationModeStopAfterMatches
+	EvaluationMode EvaluationMode
+	MaxMatches     int
+}
+
+// Validate checks that the options can be applied to an engine
+func (o EngineOptions) Validate() error {
+	if o.CacheTTL < 0 {
+		return fmt.Errorf("cache TTL must not be negative")
+	}
+
+	seen := make(map[string]bool, len(o.Extensions))
+	for _, name := range o.Extensions {
+		if _, ok := extensionLibraries[name]; !ok {
+			return fmt.Errorf("unknown extension library %q (must be one of: %s)", name, strings.Join(ExtensionLibraries(), ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("extension library %q is listed more than once", name)
+		}
+		seen[name] = true
+	}
+
+	return EvaluateOptions{Mode: o.EvaluationMode, MaxMatches: o.MaxMatches}.Validate()
+}
+
+// costLimit returns the runtime cost limit
+func (o EngineOptions) costLimit() uint64 {
+	if o.CostLimit == 0 {
+		return DefaultCostLimit
+	}
+	return o.CostLimit
+}
+
+// programOptions returns the options rule programs are built with
+// Interrupt checks let a deadline or cancellation stop long comprehensions.
+// CEL attaches a single observer to each step of a program, so a program that
+// tracks state does not track cost; rules that might exceed the cost limit are
+// given a guard program built with guardOptions instead.
+func (o EngineOptions) programOptions() []cel.ProgramOption {
+	if o.DisableStateTracking {
+		return o.guardOptions()
+	}
+	return []cel.ProgramOption{
+		cel.EvalOptions(cel.OptTrackState),
+		cel.InterruptCheckFrequency(interruptCheckFrequency),
+	}
+}
+
+// guardOptions returns the options of programs that enforce the cost limit
+func (o EngineOptions) guardOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{
+		cel.CostLimit(o.costLimit()),
+		cel.InterruptCheckFrequency(interruptCheckFrequency),
+	}
+}
+
+// extendEnv enables the extension libraries on env and fallbackEnv
+func (o EngineOptions) extendEnv(env, fallbackEnv *cel.Env) (*cel.Env, *cel.Env, error) {
+	if len(o.Extensions) == 0 {
+		return env, fallbackEnv, nil
+	}
+
+	opts := make([]cel.EnvOption, 0, len(o.Extensions))
+	for _, name := range o.Extensions {
+		opts = append(opts, extensionLibraries[name]())
+	}
+
+	extended, err := env.Extend(opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to enable extension libraries: %w", err)
+	}
+	if fallbackEnv == nil {
+		return extended, nil, nil
+	}
+	extendedFallback, err := fallbackEnv.Extend(opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to enable extension libraries: %w", err)
+	}
+	return extended, extendedFallback, nil
+}
+
+// withDefaultMode applies the default evaluation mode to opts when they do not
+// set a mode of their own
+func (o EngineOptions) withDefaultMode(opts EvaluateOptions) EvaluateOptions {
+	if opts.Mode == "" {
+		opts.Mode = o.EvaluationMode
+		opts.MaxMatches = o.MaxMatches
+	}
+	return opts
+}
+
+// Options returns the options the engine was built with
+func (en *Engine) Options() EngineOptions {
+	options := en.options
+	options.Extensions = append([]string(nil), en.options.Extensions...)
+	return options
+}
@@ -0,0 +1,186 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEngineOptionsValidate verifies invalid options are rejected
+func TestEngineOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options EngineOptions
+		wantErr bool
+	}{
+		{"Defaults", EngineOptions{}, false},
+		{"All options", EngineOptions{CostLimit: 100, DisableStateTracking: true, CacheTTL: time.Minute, Extensions: []string{"strings", "math"}, EvaluationMode: EvaluationModeFirstMatch}, false},
+		{"Negative cache TTL", EngineOptions{CacheTTL: -time.Second}, true},
+		{"Unknown extension", EngineOptions{Extensions: []string{"protos"}}, true},
+		{"Duplicate extension", EngineOptions{Extensions: []string{"strings", "strings"}}, true},
+		{"Unknown mode", EngineOptions{EvaluationMode: "some"}, true},
+		{"Stop after matches without count", EngineOptions{EvaluationMode: EvaluationModeStopAfterMatches}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.options.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestEngineOptionsExtensions verifies rules can use enabled extension
+// libraries only
+func TestEngineOptionsExtensions(t *testing.T) {
+	rule := &Rule{ID: "r1", Name: "r1", Expression: `User.Name.upperAscii() == "ADA"`, Active: true}
+
+	if err := newTestEngine(t, EngineConfig{}).AddRule(rule); err == nil {
+		t.Error("engine without the strings library should fail to compile the rule")
+	}
+
+	engine := newTestEngine(t, EngineConfig{Options: EngineOptions{Extensions: []string{"strings"}}}, rule)
+	result, err := engine.Evaluate("r1", map[string]any{"User": map[string]any{"Name": "Ada"}})
+	if err != nil || !result.Matched {
+		t.Errorf("Evaluate() = %+v, %v; want matched", result, err)
+	}
+	if got := engine.Options().Extensions; len(got) != 1 || got[0] != "strings" {
+		t.Errorf("Options().Extensions = %v, want [strings]", got)
+	}
+}
+
+// TestEngineOptionsCostLimit verifies evaluation aborts at the configured
+// cost limit, with and without state tracking
+func TestEngineOptionsCostLimit(t *testing.T) {
+	rule := &Rule{ID: "r1", Name: "r1", Expression: `User.Tags.all(t, t != "")`, Active: true}
+
+	tags := make([]any, 50)
+	for i := range tags {
+		tags[i] = "tag"
+	}
+
+	for _, tracking := range []bool{true, false} {
+		engine := newTestEngine(t, EngineConfig{Options: EngineOptions{CostLimit: 10, DisableStateTracking: !tracking}}, rule)
+
+		_, err := engine.Evaluate("r1", map[string]any{"User": map[string]any{"Tags": tags}})
+		if err == nil || !strings.Contains(err.Error(), "cost limit") {
+			t.Errorf("tracking %v: Evaluate() error = %v, want cost limit exceeded", tracking, err)
+		}
+
+		result, err := engine.Evaluate("r1", map[string]any{"User": map[string]any{"Tags": []any{"a"}}})
+		if err != nil || !result.Matched {
+			t.Errorf("tracking %v: Evaluate() under the limit = %+v, %v; want matched", tracking, result, err)
+		}
+		if tracking && result.Trace == nil {
+			t.Error("tracked rule under the limit should have a trace")
+		}
+	}
+}
+
+// TestEngineOptionsStateTracking verifies disabling state tracking leaves
+// results without traces and refuses explanations
+func TestEngineOptionsStateTracking(t *testing.T) {
+	rule := &Rule{ID: "r1", Name: "r1", Expression: `User.Age > 18`, Active: true}
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+	opts := EvaluateOptions{Explain: true}
+
+	tracked := newTestEngine(t, EngineConfig{}, rule)
+	results, err := tracked.EvaluateAllContext(context.Background(), facts, opts)
+	if err != nil || len(results) != 1 || results[0].Explanation == nil {
+		t.Fatalf("EvaluateAllContext() = %+v, %v; want an explained result", results, err)
+	}
+
+	untracked := newTestEngine(t, EngineConfig{Options: EngineOptions{DisableStateTracking: true}}, rule)
+	if _, err := untracked.EvaluateAllContext(context.Background(), facts, opts); !errors.Is(err, ErrExplainUnavailable) {
+		t.Errorf("EvaluateAllContext() with explain error = %v, want ErrExplainUnavailable", err)
+	}
+	if _, err := untracked.EvaluateBatch([]BatchItem{{Facts: facts}}, opts); !errors.Is(err, ErrExplainUnavailable) {
+		t.Errorf("EvaluateBatch() with explain error = %v, want ErrExplainUnavailable", err)
+	}
+
+	results, err = untracked.EvaluateAllContext(context.Background(), facts, EvaluateOptions{})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("EvaluateAllContext() = %+v, %v", results, err)
+	}
+	if !results[0].Matched {
+		t.Error("rule should still match without state tracking")
+	}
+	if results[0].Trace != nil {
+		t.Error("results should have no trace without state tracking")
+	}
+}
+
+// TestEngineOptionsEvaluationMode verifies the default evaluation mode applies
+// to requests that do not set one
+func TestEngineOptionsEvaluationMode(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{Options: EngineOptions{EvaluationMode: EvaluationModeFirstMatch}},
+		&Rule{ID: "r1", Name: "r1", Expression: `User.Age > 18`, Priority: 2, Active: true},
+		&Rule{ID: "r2", Name: "r2", Expression: `User.Age > 21`, Priority: 1, Active: true},
+	)
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	results, err := engine.EvaluateAll(facts)
+	if err != nil || len(results) != 1 {
+		t.Errorf("EvaluateAll() = %d results, %v; want 1 under first-match", len(results), err)
+	}
+
+	results, err = engine.EvaluateAllContext(context.Background(), facts, EvaluateOptions{Mode: EvaluationModeAll})
+	if err != nil || len(results) != 2 {
+		t.Errorf("EvaluateAllContext(all) = %d results, %v; want 2", len(results), err)
+	}
+}
+
+// TestEngineOptionsCacheTTL verifies rules older than the configured TTL are
+// rebuilt from the store in the background, programs included
+func TestEngineOptionsCacheTTL(t *testing.T) {
+	rule := &Rule{ID: "adult", Name: "adult", Expression: `User.Age >= 18`, Active: true}
+	engine := newTestEngine(t, EngineConfig{Options: EngineOptions{CacheTTL: 10 * time.Millisecond}}, rule)
+	if err := engine.CompileAllRules(); err != nil {
+		t.Fatalf("CompileAllRules() failed: %v", err)
+	}
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	// Changed behind the engine's back, so only the TTL picks them up
+	updated := *rule
+	updated.Expression = `User.Age >= 40`
+	if err := engine.store.Update(&updated); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	added := &Rule{ID: "senior", Name: "senior", Expression: `User.Age >= 65`, Active: true}
+	if err := engine.store.Add(added); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	results, err := engine.EvaluateAll(facts)
+	if err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+	if len(results) != 1 || !results[0].Matched {
+		t.Fatalf("got %v before the TTL, want the cached rule", results)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for {
+		results, err = engine.EvaluateAll(facts)
+		if err != nil {
+			t.Fatalf("EvaluateAll() failed: %v", err)
+		}
+		if len(results) == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results after the TTL, want the rules rebuilt", len(results))
+	}
+	for _, result := range results {
+		if result.Error != nil || result.Matched {
+			t.Errorf("rule %s after the TTL: Matched = %v, error = %v; want the rebuilt program not matching", result.RuleID, result.Matched, result.Error)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// outputTypes maps the output type names a rule may declare to CEL types
+// Scalar names follow the tenant schema vocabulary
+var outputTypes = map[string]*cel.Type{
+	"bool":      cel.BoolType,
+	"int":       cel.IntType,
+	"int64":     cel.IntType,
+	"float64":   cel.DoubleType,
+	"string":    cel.StringType,
+	"bytes":     cel.BytesType,
+	"timestamp": cel.TimestampType,
+	"duration":  cel.DurationType,
+	"map":       cel.MapType(cel.DynType, cel.DynType),
+	"list":      cel.ListType(cel.DynType),
+}
+
+// ParseOutputType returns the CEL type for a declared rule output type
+// An empty name means the rule does not declare an output type and returns nil
+func ParseOutputType(name string) (*cel.Type, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	t, ok := outputTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output type %q (must be one of: bool, int, int64, float64, string, bytes, timestamp, duration, map, list)", name)
+	}
+	return t, nil
+}
+
+// checkOutputType verifies at compile time that an expression's type matches the declared output type
+// Expressions typed as dyn cannot be checked statically and are verified at evaluation time instead
+func checkOutputType(declared, actual *cel.Type) error {
+	if declared == nil || actual.Kind() == types.DynKind {
+		return nil
+	}
+
+	if !declared.IsAssignableType(actual) {
+		return fmt.Errorf("expression returns %s, but rule declares output type %s", actual, declared)
+	}
+	return nil
+}
+
+// outputValue converts a CEL result into a JSON-compatible Go value
+// Maps become map[string]any, lists []any, numbers float64 (or string when
+// outside the JSON-safe integer range), and timestamps/durations strings
+func outputValue(val ref.Val) (any, error) {
+	native, err := val.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, fmt.Errorf("rule output of type %s cannot be converted to JSON: %w", val.Type().TypeName(), err)
+	}
+	return native.(*structpb.Value).AsInterface(), nil
+}
+
+// outputMatched determines whether a rule matched based on its output
+// Rules without a declared non-boolean output type match only on true
+// (REQ-EVAL-005); rules declaring a payload type match whenever they produce a value
+func outputMatched(val ref.Val, declared *cel.Type) bool {
+	if declared == nil || declared.Kind() == types.BoolKind {
+		boolVal, ok := val.Value().(bool)
+		return ok && boolVal
+	}
+	return val != types.NullValue
+}
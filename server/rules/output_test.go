@@ -0,0 +1,131 @@
+package rules
+
+import "testing"
+
+// TestParseOutputType verifies declared output type names are validated
+func TestParseOutputType(t *testing.T) {
+	for _, name := range []string{"bool", "int", "int64", "float64", "string", "bytes", "timestamp", "duration", "map", "list"} {
+		if _, err := ParseOutputType(name); err != nil {
+			t.Errorf("ParseOutputType(%q) failed: %v", name, err)
+		}
+	}
+
+	if declared, err := ParseOutputType(""); err != nil || declared != nil {
+		t.Errorf("ParseOutputType(\"\") = %v, %v; want nil, nil", declared, err)
+	}
+
+	if _, err := ParseOutputType("object"); err == nil {
+		t.Error("ParseOutputType() should reject unknown type")
+	}
+}
+
+// TestEngineAddRuleOutputTypeMismatch verifies declared output types are checked at compile time
+func TestEngineAddRuleOutputTypeMismatch(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+
+	testCases := []struct {
+		name       string
+		expression string
+		outputType string
+		wantErr    bool
+	}{
+		{"Bool rule", `User.Age >= 18`, "bool", false},
+		{"Map payload", `{"action": "review"}`, "map", false},
+		{"String payload", `"review"`, "string", false},
+		{"Dynamic field deferred to runtime", `User.Name`, "string", false},
+		{"String declared as map", `"review"`, "map", true},
+		{"Bool declared as float64", `true`, "float64", true},
+		{"Unknown output type", `true`, "object", true},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := &Rule{ID: string(rune('a' + i)), Name: tc.name, Expression: tc.expression, OutputType: tc.outputType, Active: true}
+			err := engine.AddRule(rule)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("AddRule() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestEvaluateDecisionPayload verifies structured outputs are converted to JSON-compatible values
+func TestEvaluateDecisionPayload(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+
+	rule := &Rule{
+		ID:         "decision",
+		Name:       "Review Decision",
+		Expression: `{"action": "review", "score": Transaction.Amount / 100.0, "reasons": ["amount"]}`,
+		OutputType: "map",
+		Active:     true,
+	}
+	if err := engine.AddRule(rule); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	facts := map[string]any{
+		"Transaction": map[string]any{"Amount": 1500.0},
+	}
+
+	result, err := engine.Evaluate("decision", facts)
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+
+	if !result.Matched {
+		t.Error("Rule producing a payload should be reported as matched")
+	}
+
+	output, ok := result.Output.(map[string]any)
+	if !ok {
+		t.Fatalf("Output = %T, want map[string]any", result.Output)
+	}
+	if output["action"] != "review" {
+		t.Errorf("Output[action] = %v, want review", output["action"])
+	}
+	if output["score"] != 15.0 {
+		t.Errorf("Output[score] = %v, want 15", output["score"])
+	}
+	if reasons, ok := output["reasons"].([]any); !ok || len(reasons) != 1 {
+		t.Errorf("Output[reasons] = %v, want [amount]", output["reasons"])
+	}
+}
+
+// TestEvaluateOutputWithoutDeclaredType verifies undeclared non-boolean outputs are returned but do not match
+func TestEvaluateOutputWithoutDeclaredType(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+
+	if err := engine.AddRule(&Rule{ID: "score", Name: "Score", Expression: `User.Age * 2`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	result, err := engine.Evaluate("score", map[string]any{"User": map[string]any{"Age": 21}})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+
+	if result.Matched {
+		t.Error("Undeclared non-boolean output should not match (REQ-EVAL-005)")
+	}
+	if result.Output != 42.0 {
+		t.Errorf("Output = %v, want 42", result.Output)
+	}
+}
+
+// TestEvaluateOutputRuntimeTypeMismatch verifies dynamically typed outputs are checked at evaluation time
+func TestEvaluateOutputRuntimeTypeMismatch(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+
+	if err := engine.AddRule(&Rule{ID: "name", Name: "Name", Expression: `User.Name`, OutputType: "string", Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	result, err := engine.Evaluate("name", map[string]any{"User": map[string]any{"Name": 42}})
+	if err == nil {
+		t.Fatal("Evaluate() should fail when output does not match declared type")
+	}
+	if result.Matched || result.Error == nil {
+		t.Errorf("Expected unmatched result with error, got %+v", result)
+	}
+}
@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Concurrency returns the number of workers EvaluateAll uses
+// A value of 0 or 1 means rules are evaluated sequentially
+func (en *Engine) Concurrency() int {
+	return en.snapshot.Load().concurrency
+}
+
+// SetConcurrency sets the number of workers EvaluateAll uses to evaluate rules
+// in parallel; 0 or 1 restores sequential evaluation
+// Results are returned in the same order either way. Evaluations in a
+// stopping mode always run sequentially.
+func (en *Engine) SetConcurrency(concurrency int) error {
+	if concurrency < 0 {
+		return fmt.Errorf("concurrency must not be negative")
+	}
+
+	en.publish(func(next *ruleSnapshot) {
+		next.concurrency = concurrency
+	})
+
+	return nil
+}
+
+// evaluateRulesUnreported evaluates every rule for its stats only, on the
+// worker pool when the snapshot is concurrent
+func evaluateRulesUnreported(snap *ruleSnapshot, rules []*Rule, evaluate func(*Rule, *compiledRule) *EvaluationResult) {
+	if snap.concurrency > 1 && len(rules) > 1 {
+		evaluateRulesParallel(snap, rules, evaluate)
+		return
+	}
+
+	for _, rule := range rules {
+		evaluate(rule, snap.programs[rule.ID])
+	}
+}
+
+// evaluateRulesParallel evaluates rules on a bounded worker pool of the
+// snapshot's concurrency
+// Each result is written to the slot of its rule, so the order matches
+// sequential evaluation. Every rule runs, so stopping modes cannot be applied.
+func evaluateRulesParallel(snap *ruleSnapshot, rules []*Rule, evaluate func(*Rule, *compiledRule) *EvaluationResult) []*EvaluationResult {
+	workers := snap.concurrency
+	if workers > len(rules) {
+		workers = len(rules)
+	}
+
+	results := make([]*EvaluationResult, len(rules))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(rules) {
+					return
+				}
+				results[i] = evaluate(rules[i], snap.programs[rules[i].ID])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,212 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// parallelRules returns n rules with descending priority, alternating between
+// matching and not matching adults unless expression is set
+func parallelRules(n int, expression string) []*Rule {
+	rules := make([]*Rule, n)
+	for i := range rules {
+		expr := expression
+		if expr == "" {
+			expr = `User.Age >= 18`
+			if i%2 == 1 {
+				expr = `User.Age < 18`
+			}
+		}
+		rules[i] = &Rule{
+			ID:         fmt.Sprintf("rule-%04d", i),
+			Name:       fmt.Sprintf("Rule %d", i),
+			Expression: expr,
+			Active:     true,
+			Priority:   n - i,
+		}
+	}
+	return rules
+}
+
+// TestEvaluateAllParallelOrder verifies parallel evaluation returns the same results
+// in the same order as sequential evaluation
+func TestEvaluateAllParallelOrder(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, parallelRules(50, "")...)
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	sequential, err := engine.EvaluateAll(facts)
+	if err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+
+	if err := engine.SetConcurrency(8); err != nil {
+		t.Fatalf("SetConcurrency() failed: %v", err)
+	}
+	parallel, err := engine.EvaluateAll(facts)
+	if err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+
+	if len(parallel) != len(sequential) {
+		t.Fatalf("parallel returned %d results, sequential %d", len(parallel), len(sequential))
+	}
+	for i := range sequential {
+		if parallel[i].RuleID != sequential[i].RuleID || parallel[i].Matched != sequential[i].Matched {
+			t.Errorf("result %d: parallel (%s, %v), sequential (%s, %v)", i,
+				parallel[i].RuleID, parallel[i].Matched, sequential[i].RuleID, sequential[i].Matched)
+		}
+	}
+}
+
+// TestEvaluateAllParallelStoppingModes verifies stopping modes report the same rules in parallel
+func TestEvaluateAllParallelStoppingModes(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, parallelRules(20, "")...)
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	testCases := []struct {
+		name string
+		opts EvaluateOptions
+	}{
+		{"First match", EvaluateOptions{Mode: EvaluationModeFirstMatch}},
+		{"Stop after matches", EvaluateOptions{Mode: EvaluationModeStopAfterMatches, MaxMatches: 3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine.SetConcurrency(0)
+			sequential, err := engine.EvaluateAllWithOptions(facts, tc.opts)
+			if err != nil {
+				t.Fatalf("EvaluateAllWithOptions() failed: %v", err)
+			}
+
+			engine.SetConcurrency(4)
+			parallel, err := engine.EvaluateAllWithOptions(facts, tc.opts)
+			if err != nil {
+				t.Fatalf("EvaluateAllWithOptions() failed: %v", err)
+			}
+
+			if len(parallel) != len(sequential) {
+				t.Fatalf("parallel returned %d results, sequential %d", len(parallel), len(sequential))
+			}
+			for i := range sequential {
+				if parallel[i].RuleID != sequential[i].RuleID {
+					t.Errorf("result %d: parallel %s, sequential %s", i, parallel[i].RuleID, sequential[i].RuleID)
+				}
+			}
+		})
+	}
+}
+
+// TestEvaluateAllParallelStopsEarly verifies rules past the stopping point are
+// not run in parallel mode, so they record no stats
+func TestEvaluateAllParallelStopsEarly(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, parallelRules(20, "")...)
+	engine.SetConcurrency(4)
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	results, err := engine.EvaluateAllWithOptions(facts, EvaluateOptions{Mode: EvaluationModeFirstMatch})
+	if err != nil {
+		t.Fatalf("EvaluateAllWithOptions() failed: %v", err)
+	}
+
+	allStats, err := engine.RuleStats()
+	if err != nil {
+		t.Fatalf("RuleStats() failed: %v", err)
+	}
+	evaluated := 0
+	for _, stats := range allStats {
+		evaluated += int(stats.Evaluations)
+	}
+	if evaluated != len(results) {
+		t.Errorf("%d rules evaluated for %d results, want only the reported rules run", evaluated, len(results))
+	}
+}
+
+// TestEvaluateAllParallelCanceled verifies cut off rules are reported in parallel mode
+func TestEvaluateAllParallelCanceled(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, parallelRules(10, "")...)
+	engine.SetConcurrency(4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := engine.EvaluateAllContext(ctx, map[string]any{"User": map[string]any{"Age": 30}}, EvaluateOptions{})
+	if err != nil {
+		t.Fatalf("EvaluateAllContext() failed: %v", err)
+	}
+	if len(results) != 10 {
+		t.Fatalf("expected 10 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if !result.TimedOut {
+			t.Errorf("rule %s should be cut off", result.RuleID)
+		}
+	}
+}
+
+// TestSetConcurrencyValidation verifies negative concurrency is rejected
+func TestSetConcurrencyValidation(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.SetConcurrency(-1); err == nil {
+		t.Error("expected negative concurrency to be rejected")
+	}
+	if got := engine.Concurrency(); got != 0 {
+		t.Errorf("Concurrency() = %d, want 0", got)
+	}
+}
+
+// benchmarkEvaluateAll measures EvaluateAll over n rules with the given concurrency
+// Cheap rules favour the sequential path because goroutine scheduling dominates;
+// expensive rules (comprehensions over the facts) favour the worker pool
+func benchmarkEvaluateAll(b *testing.B, n, concurrency int, expression string) {
+	engine := newTestEngine(b, EngineConfig{}, parallelRules(n, expression)...)
+	engine.SetConcurrency(concurrency)
+
+	items := make([]any, 100)
+	for i := range items {
+		items[i] = i
+	}
+	facts := map[string]any{"User": map[string]any{"Age": 30, "Items": items}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.EvaluateAll(facts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+const expensiveExpression = `User.Items.filter(x, x % 3 == 0).size() > 10 && User.Items.exists(x, x > 90)`
+
+func BenchmarkEvaluateAll_Cheap_Sequential(b *testing.B) {
+	benchmarkEvaluateAll(b, 1000, 0, "")
+}
+
+func BenchmarkEvaluateAll_Cheap_Parallel4(b *testing.B) {
+	benchmarkEvaluateAll(b, 1000, 4, "")
+}
+
+func BenchmarkEvaluateAll_Cheap_Parallel16(b *testing.B) {
+	benchmarkEvaluateAll(b, 1000, 16, "")
+}
+
+func BenchmarkEvaluateAll_Expensive_Sequential(b *testing.B) {
+	benchmarkEvaluateAll(b, 1000, 0, expensiveExpression)
+}
+
+func BenchmarkEvaluateAll_Expensive_Parallel4(b *testing.B) {
+	benchmarkEvaluateAll(b, 1000, 4, expensiveExpression)
+}
+
+func BenchmarkEvaluateAll_Expensive_Parallel16(b *testing.B) {
+	benchmarkEvaluateAll(b, 1000, 16, expensiveExpression)
+}
+
+func BenchmarkEvaluateAll_Small_Sequential(b *testing.B) {
+	benchmarkEvaluateAll(b, 10, 0, expensiveExpression)
+}
+
+func BenchmarkEvaluateAll_Small_Parallel4(b *testing.B) {
+	benchmarkEvaluateAll(b, 10, 4, expensiveExpression)
+}
@@ -0,0 +1,394 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter"
+)
+
+// PartialResult is the outcome of evaluating a rule against incomplete facts
+// A decided rule has the same Matched and Output it would have with any
+// values for the missing facts. An undecided rule reports the part of its
+// expression that still depends on missing facts, and which facts those are.
+type PartialResult struct {
+	RuleID            string
+	RuleName          string
+	Decided           bool
+	Matched           bool
+	Output            any      // rule output converted to a JSON-compatible value, when decided
+	Residual          string   // CEL expression left to decide, when undecided
+	MissingAttributes []string // facts the outcome still depends on, e.g. "User.Age"
+	Error             error
+}
+
+// partialProgram lazily builds the partial evaluation program for a rule
+// Most rules are never partially evaluated, so the program is only built on
+// first use
+type partialProgram struct {
+	once    sync.Once
+	program cel.Program
+	guard   cel.Program // cost-limited program run first, if needed
+	err     error
+}
+
+// partialProgram returns the rule's program with partial evaluation enabled,
+// and the guard to run before it when the rule might exceed the cost limit
+// Residuals need the evaluation state, and a program that tracks state does
+// not enforce the cost limit, so the limit is enforced by the guard.
+func (c *compiledRule) partialProgram() (cel.Program, cel.Program, error) {
+	c.partial.once.Do(func() {
+		c.partial.program, c.partial.err = c.env.Program(c.checked,
+			cel.EvalOptions(cel.OptPartialEval, cel.OptTrackState),
+			cel.InterruptCheckFrequency(interruptCheckFrequency),
+		)
+		if c.partial.err != nil || c.cost.Max <= c.costLimit {
+			return
+		}
+		guardOptions := EngineOptions{CostLimit: c.costLimit}.guardOptions()
+		c.partial.guard, c.partial.err = c.env.Program(c.checked,
+			append(guardOptions, cel.EvalOptions(cel.OptPartialEval))...)
+	})
+	return c.partial.program, c.partial.guard, c.partial.err
+}
+
+// EvaluatePartial evaluates active rules against facts that may be missing
+// fields, reporting for each rule whether the outcome is already decided
+func (en *Engine) EvaluatePartial(facts map[string]any) ([]*PartialResult, error) {
+	return en.EvaluatePartialContext(context.Background(), facts, EvaluateOptions{})
+}
+
+// EvaluatePartialContext partially evaluates active rules in priority order,
+// applying the tag and rule set selectors of opts and the engine's time budgets
+// Every selected live rule is reported; the evaluation mode does not apply since
+// undecided rules cannot be counted as matches.
+// A fact is missing when a rule selects a field that is absent from the facts.
+// Rules referenced through rules.<name> are partially evaluated first; when
+// they are undecided their missing facts are reported for the dependent rule.
+func (en *Engine) EvaluatePartialContext(ctx context.Context, facts map[string]any, opts EvaluateOptions) ([]*PartialResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	snap, rules, now, err := en.liveRules()
+	if err != nil {
+		return nil, err
+	}
+
+	limits := snap.limits
+	evaluator := &partialEvaluator{
+		now:      now,
+		programs: snap.programs,
+		refs:     snap.refs,
+		results:  make(map[string]*PartialResult),
+		values:   make(map[string]ref.Val),
+	}
+
+	ctx, cancel := limits.requestContext(ctx)
+	defer cancel()
+
+	evaluator.ctx = ctx
+	evaluator.limits = limits
+	evaluator.facts, err = en.prepareFacts(ctx, snap, facts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shadow rules never affect responses, so they are not reported
+	rules, _ = splitShadowRules(opts.selectRules(rules))
+	results := make([]*PartialResult, 0, len(rules))
+	for _, rule := range rules {
+		results = append(results, evaluator.evaluate(rule))
+	}
+
+	return results, nil
+}
+
+// partialEvaluator partially evaluates rules for one request, memoizing
+// results so referenced rules are evaluated once
+type partialEvaluator struct {
+	ctx      context.Context
+	now      time.Time // references to rules outside their window at now are not run
+	limits   EvaluationLimits
+	facts    map[string]any
+	programs map[string]*compiledRule
+	refs     map[string]*Rule
+	results  map[string]*PartialResult // ruleID -> result
+	values   map[string]ref.Val        // ruleID -> decided output
+}
+
+// evaluate partially evaluates a rule
+func (p *partialEvaluator) evaluate(rule *Rule) *PartialResult {
+	if result, ok := p.results[rule.ID]; ok {
+		return result
+	}
+
+	result := &PartialResult{RuleID: rule.ID, RuleName: rule.Name}
+	p.results[rule.ID] = result
+
+	compiled := p.programs[rule.ID]
+	if compiled == nil {
+		result.Error = fmt.Errorf("rule %s is not compiled", rule.ID)
+		return result
+	}
+
+	// Referenced rules are bound when decided and unknown otherwise
+	bindings := make(map[string]any)
+	var unknowns []*cel.AttributePatternType
+	referenced := make(map[string][]string)
+	for _, name := range compiled.dependencies {
+		ref, ok := p.refs[name]
+		if !ok {
+			continue
+		}
+		if !ref.EffectiveAt(p.now) {
+			bindings[ruleRefPrefix+name] = outOfWindowValue(name, p.programs[ref.ID])
+			continue
+		}
+		dep := p.evaluate(ref)
+		if dep.Decided && dep.Error == nil {
+			bindings[ruleRefPrefix+name] = p.values[ref.ID]
+			continue
+		}
+		unknowns = append(unknowns, cel.AttributePattern(ruleRefPrefix+name))
+		referenced[ruleRefPrefix+name] = dep.MissingAttributes
+	}
+
+	for _, path := range missingPaths(compiled.checked, p.facts) {
+		pattern := cel.AttributePattern(path[0])
+		for _, field := range path[1:] {
+			pattern = pattern.QualString(field)
+		}
+		unknowns = append(unknowns, pattern)
+	}
+
+	program, guard, err := compiled.partialProgram()
+	if err != nil {
+		result.Error = fmt.Errorf("program creation error: %w", err)
+		return result
+	}
+
+	var vars interpreter.Activation
+	vars, err = interpreter.NewActivation(p.facts)
+	if err == nil && len(bindings) > 0 {
+		var bound interpreter.Activation
+		bound, err = interpreter.NewActivation(bindings)
+		if err == nil {
+			vars = interpreter.NewHierarchicalActivation(vars, bound)
+		}
+	}
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	partialVars, err := cel.PartialVars(vars, unknowns...)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	if err := p.ctx.Err(); err != nil {
+		result.Error = interruptedError(rule.ID, err)
+		return result
+	}
+	ruleCtx, cancel := p.limits.ruleContext(p.ctx)
+	defer cancel()
+
+	if guard != nil {
+		if _, _, err := guard.ContextEval(ruleCtx, partialVars); err != nil {
+			if ruleCtx.Err() != nil {
+				result.Error = interruptedError(rule.ID, ruleCtx.Err())
+			} else {
+				result.Error = err
+			}
+			return result
+		}
+	}
+
+	out, details, err := program.ContextEval(ruleCtx, partialVars)
+	if err != nil && ruleCtx.Err() != nil {
+		result.Error = interruptedError(rule.ID, ruleCtx.Err())
+		return result
+	}
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	if unknown, ok := out.(*types.Unknown); ok {
+		result.MissingAttributes = unknownAttributes(unknown, referenced)
+		if residual, err := compiled.env.ResidualAst(compiled.checked, details); err == nil {
+			result.Residual, _ = cel.AstToString(residual)
+		}
+		return result
+	}
+
+	if compiled.outputType != nil && !compiled.outputType.IsAssignableRuntimeType(out) {
+		result.Error = fmt.Errorf("rule output of type %s does not match declared output type %s",
+			out.Type().TypeName(), compiled.outputType)
+		return result
+	}
+	output, err := outputValue(out)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	result.Decided = true
+	result.Matched = outputMatched(out, compiled.outputType)
+	result.Output = output
+	if compiled.outputType == nil {
+		p.values[rule.ID] = types.Bool(result.Matched)
+	} else {
+		p.values[rule.ID] = out
+	}
+	return result
+}
+
+// unknownAttributes lists the attributes an unknown result depends on,
+// replacing references to undecided rules with the facts they are missing
+func unknownAttributes(unknown *types.Unknown, referenced map[string][]string) []string {
+	seen := make(map[string]bool)
+	for _, id := range unknown.IDs() {
+		trails, _ := unknown.GetAttributeTrails(id)
+		for _, trail := range trails {
+			if missing, ok := referenced[trail.Variable()]; ok {
+				for _, attr := range missing {
+					seen[attr] = true
+				}
+				continue
+			}
+			seen[trail.String()] = true
+		}
+	}
+
+	attrs := make([]string, 0, len(seen))
+	for attr := range seen {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// missingPaths returns the field paths an expression selects that are absent
+// from facts, e.g. ["User", "Age"]. References to other rules are excluded.
+func missingPaths(checked *cel.Ast, facts map[string]any) [][]string {
+	scoped := make(map[string]bool)
+	paths := make(map[string][]string)
+	collectPaths(checked.NativeRep().Expr(), false, scoped, paths)
+
+	keys := make([]string, 0, len(paths))
+	for key := range paths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var missing [][]string
+	for _, key := range keys {
+		path := paths[key]
+		if strings.HasPrefix(path[0], ruleRefPrefix) || scoped[path[0]] {
+			continue
+		}
+		if !hasFactPath(facts, path) {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+// collectPaths records the longest field path of every selection rooted at a
+// variable, keyed by its dotted form; comprehension variables are recorded in
+// scoped so they can be excluded. tested includes the field tested by has()
+// in its path.
+func collectPaths(e ast.Expr, tested bool, scoped map[string]bool, paths map[string][]string) {
+	switch e.Kind() {
+	case ast.IdentKind, ast.SelectKind:
+		if path, ok := selectPath(e, tested); ok {
+			paths[strings.Join(path, ".")] = path
+			return
+		}
+		if e.Kind() == ast.SelectKind {
+			collectPaths(e.AsSelect().Operand(), tested, scoped, paths)
+		}
+	case ast.CallKind:
+		call := e.AsCall()
+		if call.IsMemberFunction() {
+			collectPaths(call.Target(), tested, scoped, paths)
+		}
+		for _, arg := range call.Args() {
+			collectPaths(arg, tested, scoped, paths)
+		}
+	case ast.ListKind:
+		for _, elem := range e.AsList().Elements() {
+			collectPaths(elem, tested, scoped, paths)
+		}
+	case ast.MapKind:
+		for _, entry := range e.AsMap().Entries() {
+			collectPaths(entry.AsMapEntry().Key(), tested, scoped, paths)
+			collectPaths(entry.AsMapEntry().Value(), tested, scoped, paths)
+		}
+	case ast.StructKind:
+		for _, field := range e.AsStruct().Fields() {
+			collectPaths(field.AsStructField().Value(), tested, scoped, paths)
+		}
+	case ast.ComprehensionKind:
+		comp := e.AsComprehension()
+		scoped[comp.IterVar()] = true
+		if comp.HasIterVar2() {
+			scoped[comp.IterVar2()] = true
+		}
+		scoped[comp.AccuVar()] = true
+		collectPaths(comp.IterRange(), tested, scoped, paths)
+		collectPaths(comp.AccuInit(), tested, scoped, paths)
+		collectPaths(comp.LoopCondition(), tested, scoped, paths)
+		collectPaths(comp.LoopStep(), tested, scoped, paths)
+		collectPaths(comp.Result(), tested, scoped, paths)
+	}
+}
+
+// selectPath returns the variable and fields of a selection chain such as
+// User.Profile.Email; for has() tests only the tested object is returned
+// unless tested is set, since an absent field decides has() rather than
+// leaving it unknown
+func selectPath(e ast.Expr, tested bool) ([]string, bool) {
+	switch e.Kind() {
+	case ast.IdentKind:
+		return []string{e.AsIdent()}, true
+	case ast.SelectKind:
+		sel := e.AsSelect()
+		path, ok := selectPath(sel.Operand(), tested)
+		if !ok {
+			return nil, false
+		}
+		if sel.IsTestOnly() && !tested {
+			return path, true
+		}
+		return append(path, sel.FieldName()), true
+	}
+	return nil, false
+}
+
+// hasFactPath reports whether facts contain a value at path
+// Values that are not maps are assumed to contain any field below them
+func hasFactPath(facts map[string]any, path []string) bool {
+	var current any = facts
+	for _, field := range path {
+		fields, ok := current.(map[string]any)
+		if !ok {
+			return true
+		}
+		current, ok = fields[field]
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
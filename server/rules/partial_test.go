@@ -0,0 +1,175 @@
+package rules
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestEvaluatePartial verifies rules are decided from known facts where
+// possible and otherwise report what is still missing
+func TestEvaluatePartial(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	rules := []*Rule{
+		{ID: "adult-ca", Name: "Adult CA", Expression: `User.Age >= 18 && User.Country == "CA"`, Active: true},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		facts        map[string]any
+		wantDecided  bool
+		wantMatched  bool
+		wantMissing  []string
+		wantResidual string
+	}{
+		{
+			name:        "All facts known",
+			facts:       map[string]any{"User": map[string]any{"Age": 30, "Country": "CA"}},
+			wantDecided: true,
+			wantMatched: true,
+		},
+		{
+			name:        "Decided by known fact",
+			facts:       map[string]any{"User": map[string]any{"Age": 10}},
+			wantDecided: true,
+			wantMatched: false,
+		},
+		{
+			name:         "Needs one fact",
+			facts:        map[string]any{"User": map[string]any{"Age": 30}},
+			wantMissing:  []string{"User.Country"},
+			wantResidual: `User.Country == "CA"`,
+		},
+		{
+			name:         "Needs object",
+			facts:        map[string]any{},
+			wantMissing:  []string{"User.Age", "User.Country"},
+			wantResidual: `User.Age >= 18 && User.Country == "CA"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := engine.EvaluatePartial(tc.facts)
+			if err != nil {
+				t.Fatalf("EvaluatePartial() failed: %v", err)
+			}
+			result := results[0]
+			if result.Error != nil {
+				t.Fatalf("result error = %v", result.Error)
+			}
+			if result.Decided != tc.wantDecided || result.Matched != tc.wantMatched {
+				t.Errorf("Decided, Matched = %v, %v; want %v, %v", result.Decided, result.Matched, tc.wantDecided, tc.wantMatched)
+			}
+			if !reflect.DeepEqual(result.MissingAttributes, tc.wantMissing) {
+				t.Errorf("MissingAttributes = %v, want %v", result.MissingAttributes, tc.wantMissing)
+			}
+			if result.Residual != tc.wantResidual {
+				t.Errorf("Residual = %q, want %q", result.Residual, tc.wantResidual)
+			}
+		})
+	}
+}
+
+// TestEvaluatePartialMacros verifies has() and comprehensions are decided
+// without treating their variables as missing facts
+func TestEvaluatePartialMacros(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	rules := []*Rule{
+		{ID: "has-email", Name: "Has Email", Expression: `has(User.Email)`, Active: true, Priority: 20},
+		{ID: "tags", Name: "Tags", Expression: `User.Tags.all(t, t != "blocked")`, Active: true, Priority: 10},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	results, err := engine.EvaluatePartial(map[string]any{"User": map[string]any{"Tags": []any{"vip"}}})
+	if err != nil {
+		t.Fatalf("EvaluatePartial() failed: %v", err)
+	}
+	for _, result := range results {
+		if result.Error != nil || !result.Decided {
+			t.Errorf("%s: Decided = %v, Error = %v; want decided", result.RuleID, result.Decided, result.Error)
+		}
+	}
+	if results[0].Matched {
+		t.Error("has(User.Email) should be decided false when Email is absent")
+	}
+	if !results[1].Matched {
+		t.Error("all() should match")
+	}
+}
+
+// TestEvaluatePartialChaining verifies missing facts of an undecided
+// referenced rule are reported for the rules that depend on it
+func TestEvaluatePartialChaining(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	rules := []*Rule{
+		{ID: "country", Name: "isHighRiskCountry", Expression: `User.Country in ["XX", "YY"]`, Active: true, Priority: 20},
+		{ID: "fraud", Name: "Fraud", Expression: `Transaction.Amount > 1000.0 && rules.isHighRiskCountry`, Active: true, Priority: 10},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	results, err := engine.EvaluatePartial(map[string]any{"Transaction": map[string]any{"Amount": 5000.0}})
+	if err != nil {
+		t.Fatalf("EvaluatePartial() failed: %v", err)
+	}
+	fraud := results[1]
+	if fraud.Decided || !reflect.DeepEqual(fraud.MissingAttributes, []string{"User.Country"}) {
+		t.Errorf("fraud = %+v, want undecided missing [User.Country]", fraud)
+	}
+
+	results, err = engine.EvaluatePartial(map[string]any{
+		"User":        map[string]any{"Country": "XX"},
+		"Transaction": map[string]any{},
+	})
+	if err != nil {
+		t.Fatalf("EvaluatePartial() failed: %v", err)
+	}
+	fraud = results[1]
+	if fraud.Decided || !reflect.DeepEqual(fraud.MissingAttributes, []string{"Transaction.Amount"}) {
+		t.Errorf("fraud = %+v, want undecided missing [Transaction.Amount]", fraud)
+	}
+	if fraud.Residual != "Transaction.Amount > 1000.0" {
+		t.Errorf("Residual = %q, want the amount check only", fraud.Residual)
+	}
+}
+
+// TestEvaluatePartialCostLimit verifies partial evaluation aborts at the
+// engine's cost limit like full evaluation does
+func TestEvaluatePartialCostLimit(t *testing.T) {
+	rule := &Rule{ID: "r1", Name: "r1", Expression: `User.Tags.all(t, t != "") && User.Age > 18`, Active: true}
+	engine := newTestEngine(t, EngineConfig{Options: EngineOptions{CostLimit: 100}}, rule)
+
+	tags := make([]any, 100)
+	for i := range tags {
+		tags[i] = "tag"
+	}
+
+	results, err := engine.EvaluatePartial(map[string]any{"User": map[string]any{"Tags": tags}})
+	if err != nil {
+		t.Fatalf("EvaluatePartial() failed: %v", err)
+	}
+	if err := results[0].Error; err == nil || !strings.Contains(err.Error(), "cost limit") {
+		t.Errorf("result error = %v, want cost limit exceeded", err)
+	}
+
+	results, err = engine.EvaluatePartial(map[string]any{"User": map[string]any{"Tags": []any{"a"}}})
+	if err != nil {
+		t.Fatalf("EvaluatePartial() failed: %v", err)
+	}
+	if result := results[0]; result.Error != nil || result.Decided || result.Residual != "User.Age > 18" {
+		t.Errorf("result under the limit = %+v, want undecided on User.Age", result)
+	}
+}
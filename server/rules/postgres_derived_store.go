@@ -0,0 +1,155 @@
+package rules
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// PostgresDerivedFieldStore implements DerivedFieldStore backed by the derived_fields table
+type PostgresDerivedFieldStore struct {
+	db       *sql.DB
+	tenantID string
+}
+
+// NewPostgresDerivedFieldStore creates a new PostgreSQL-backed DerivedFieldStore for a specific tenant
+func NewPostgresDerivedFieldStore(db *sql.DB, tenantID string) *PostgresDerivedFieldStore {
+	return &PostgresDerivedFieldStore{
+		db:       db,
+		tenantID: tenantID,
+	}
+}
+
+// scanDerivedField reads a row of name, expression, dependencies, created_at
+func scanDerivedField(row rowScanner) (*DerivedField, error) {
+	var field DerivedField
+	var dependenciesJSON []byte
+	if err := row.Scan(&field.Name, &field.Expression, &dependenciesJSON, &field.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if len(dependenciesJSON) > 0 {
+		if err := json.Unmarshal(dependenciesJSON, &field.Dependencies); err != nil {
+			return nil, fmt.Errorf("invalid dependencies for derived field %s: %w", field.Name, err)
+		}
+	}
+
+	return &field, nil
+}
+
+// Add inserts a new derived field into the database
+func (s *PostgresDerivedFieldStore) Add(field *DerivedField) error {
+	dependenciesJSON, err := json.Marshal(field.Dependencies)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dependencies: %w", err)
+	}
+
+	err = s.db.QueryRow(`
+		INSERT INTO derived_fields (tenant_id, name, expression, dependencies, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (tenant_id, name) DO NOTHING
+		RETURNING created_at
+	`, s.tenantID, field.Name, field.Expression, dependenciesJSON).Scan(&field.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("derived field %s already exists", field.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert derived field: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves a derived field by name
+func (s *PostgresDerivedFieldStore) Get(name string) (*DerivedField, error) {
+	field, err := scanDerivedField(s.db.QueryRow(`
+		SELECT name, expression, dependencies, created_at
+		FROM derived_fields
+		WHERE tenant_id = $1 AND name = $2
+	`, s.tenantID, name))
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("derived field %s not found", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get derived field: %w", err)
+	}
+
+	return field, nil
+}
+
+// List returns all derived fields for the tenant ordered by name
+func (s *PostgresDerivedFieldStore) List() ([]*DerivedField, error) {
+	rows, err := s.db.Query(`
+		SELECT name, expression, dependencies, created_at
+		FROM derived_fields
+		WHERE tenant_id = $1
+		ORDER BY name ASC
+	`, s.tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list derived fields: %w", err)
+	}
+	defer rows.Close()
+
+	var fields []*DerivedField
+	for rows.Next() {
+		field, err := scanDerivedField(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan derived field: %w", err)
+		}
+		fields = append(fields, field)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating derived fields: %w", err)
+	}
+
+	return fields, nil
+}
+
+// Update modifies an existing derived field
+func (s *PostgresDerivedFieldStore) Update(field *DerivedField) error {
+	dependenciesJSON, err := json.Marshal(field.Dependencies)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dependencies: %w", err)
+	}
+
+	err = s.db.QueryRow(`
+		UPDATE derived_fields
+		SET expression = $1, dependencies = $2
+		WHERE tenant_id = $3 AND name = $4
+		RETURNING created_at
+	`, field.Expression, dependenciesJSON, s.tenantID, field.Name).Scan(&field.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("derived field %s not found", field.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update derived field: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a derived field from the database
+func (s *PostgresDerivedFieldStore) Delete(name string) error {
+	result, err := s.db.Exec(`
+		DELETE FROM derived_fields
+		WHERE tenant_id = $1 AND name = $2
+	`, s.tenantID, name)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete derived field: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("derived field %s not found", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package rules
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// PostgresReferenceListStore implements ReferenceListStore backed by the reference_lists table
+type PostgresReferenceListStore struct {
+	db       *sql.DB
+	tenantID string
+}
+
+// NewPostgresReferenceListStore creates a new PostgreSQL-backed ReferenceListStore for a specific tenant
+func NewPostgresReferenceListStore(db *sql.DB, tenantID string) *PostgresReferenceListStore {
+	return &PostgresReferenceListStore{
+		db:       db,
+		tenantID: tenantID,
+	}
+}
+
+// listItems is the JSON form of a list's members in the items column
+type listItems struct {
+	Strings []string       `json:"strings,omitempty"`
+	Numbers []float64      `json:"numbers,omitempty"`
+	Ranges  []NumericRange `json:"ranges,omitempty"`
+}
+
+// scanReferenceList reads a row of name, kind, items, created_at, updated_at
+func scanReferenceList(row rowScanner) (*ReferenceList, error) {
+	var list ReferenceList
+	var itemsJSON []byte
+	if err := row.Scan(&list.Name, &list.Kind, &itemsJSON, &list.CreatedAt, &list.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	var items listItems
+	if err := json.Unmarshal(itemsJSON, &items); err != nil {
+		return nil, fmt.Errorf("invalid items for list %s: %w", list.Name, err)
+	}
+	list.Strings = items.Strings
+	list.Numbers = items.Numbers
+	list.Ranges = items.Ranges
+
+	return &list, nil
+}
+
+// encodeListItems marshals the members of a list for the items column
+func encodeListItems(list *ReferenceList) ([]byte, error) {
+	itemsJSON, err := json.Marshal(listItems{
+		Strings: list.Strings,
+		Numbers: list.Numbers,
+		Ranges:  list.Ranges,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal list items: %w", err)
+	}
+	return itemsJSON, nil
+}
+
+// Add inserts a new list into the database
+func (s *PostgresReferenceListStore) Add(list *ReferenceList) error {
+	itemsJSON, err := encodeListItems(list)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.QueryRow(`
+		INSERT INTO reference_lists (tenant_id, name, kind, items, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (tenant_id, name) DO NOTHING
+		RETURNING created_at, updated_at
+	`, s.tenantID, list.Name, list.Kind, itemsJSON).Scan(&list.CreatedAt, &list.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("list %s already exists", list.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert list: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves a list by name
+func (s *PostgresReferenceListStore) Get(name string) (*ReferenceList, error) {
+	list, err := scanReferenceList(s.db.QueryRow(`
+		SELECT name, kind, items, created_at, updated_at
+		FROM reference_lists
+		WHERE tenant_id = $1 AND name = $2
+	`, s.tenantID, name))
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("list %s not found", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list: %w", err)
+	}
+
+	return list, nil
+}
+
+// List returns all lists for the tenant ordered by name
+func (s *PostgresReferenceListStore) List() ([]*ReferenceList, error) {
+	rows, err := s.db.Query(`
+		SELECT name, kind, items, created_at, updated_at
+		FROM reference_lists
+		WHERE tenant_id = $1
+		ORDER BY name ASC
+	`, s.tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lists: %w", err)
+	}
+	defer rows.Close()
+
+	var lists []*ReferenceList
+	for rows.Next() {
+		list, err := scanReferenceList(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan list: %w", err)
+		}
+		lists = append(lists, list)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lists: %w", err)
+	}
+
+	return lists, nil
+}
+
+// Update replaces the members of an existing list
+// The kind of a list never changes, so only its items are written
+func (s *PostgresReferenceListStore) Update(list *ReferenceList) error {
+	itemsJSON, err := encodeListItems(list)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.QueryRow(`
+		UPDATE reference_lists
+		SET items = $1, updated_at = NOW()
+		WHERE tenant_id = $2 AND name = $3
+		RETURNING created_at, updated_at
+	`, itemsJSON, s.tenantID, list.Name).Scan(&list.CreatedAt, &list.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("list %s not found", list.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update list: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a list from the database
+func (s *PostgresReferenceListStore) Delete(name string) error {
+	result, err := s.db.Exec(`
+		DELETE FROM reference_lists
+		WHERE tenant_id = $1 AND name = $2
+	`, s.tenantID, name)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete list: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("list %s not found", name)
+	}
+
+	return nil
+}
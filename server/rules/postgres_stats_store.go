@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PostgresRuleStatsStore implements RuleStatsStore backed by the rule_stats table
+type PostgresRuleStatsStore struct {
+	db       *sql.DB
+	tenantID string
+}
+
+// NewPostgresRuleStatsStore creates a new PostgreSQL-backed RuleStatsStore for a specific tenant
+func NewPostgresRuleStatsStore(db *sql.DB, tenantID string) *PostgresRuleStatsStore {
+	return &PostgresRuleStatsStore{
+		db:       db,
+		tenantID: tenantID,
+	}
+}
+
+// Add adds evaluation counts to the stored stats of each rule in one transaction
+// Counts for rules that no longer exist are dropped. Rows are written in rule
+// ID order so replicas adding at the same time cannot deadlock.
+func (s *PostgresRuleStatsStore) Add(stats []*RuleStats) error {
+	stats = append([]*RuleStats(nil), stats...)
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].RuleID < stats[j].RuleID
+	})
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, added := range stats {
+		var lastEvaluated sql.NullTime
+		if !added.LastEvaluated.IsZero() {
+			lastEvaluated = sql.NullTime{Time: added.LastEvaluated, Valid: true}
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO rule_stats (rule_id, tenant_id, evaluations, matches, errors, timed_out, skipped,
+				total_latency_ns, max_latency_ns, last_evaluated, updated_at)
+			SELECT id, tenant_id, $3, $4, $5, $6, $7, $8, $9, $10, NOW()
+			FROM rules
+			WHERE tenant_id = $1 AND id = $2
+			ON CONFLICT (rule_id) DO UPDATE SET
+				evaluations = rule_stats.evaluations + EXCLUDED.evaluations,
+				matches = rule_stats.matches + EXCLUDED.matches,
+				errors = rule_stats.errors + EXCLUDED.errors,
+				timed_out = rule_stats.timed_out + EXCLUDED.timed_out,
+				skipped = rule_stats.skipped + EXCLUDED.skipped,
+				total_latency_ns = rule_stats.total_latency_ns + EXCLUDED.total_latency_ns,
+				max_latency_ns = GREATEST(rule_stats.max_latency_ns, EXCLUDED.max_latency_ns),
+				last_evaluated = GREATEST(rule_stats.last_evaluated, EXCLUDED.last_evaluated),
+				updated_at = NOW()
+		`, s.tenantID, added.RuleID, added.Evaluations, added.Matches, added.Errors, added.TimedOut,
+			added.Skipped, added.TotalLatency.Nanoseconds(), added.MaxLatency.Nanoseconds(), lastEvaluated)
+		if err != nil {
+			return fmt.Errorf("failed to add stats of rule %s: %w", added.RuleID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rule stats: %w", err)
+	}
+	return nil
+}
+
+// List returns the stored stats of every rule ordered by rule ID, named after
+// the rule's current revision
+func (s *PostgresRuleStatsStore) List() ([]*RuleStats, error) {
+	rows, err := s.db.Query(`
+		SELECT s.rule_id, r.name, r.shadow, s.evaluations, s.matches, s.errors, s.timed_out, s.skipped,
+			s.total_latency_ns, s.max_latency_ns, s.last_evaluated
+		FROM rule_stats s
+		JOIN rules r ON r.id = s.rule_id
+		WHERE s.tenant_id = $1
+		ORDER BY s.rule_id
+	`, s.tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rule stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*RuleStats
+	for rows.Next() {
+		var rs RuleStats
+		var totalNanos, maxNanos int64
+		var lastEvaluated sql.NullTime
+		if err := rows.Scan(&rs.RuleID, &rs.RuleName, &rs.Shadow, &rs.Evaluations, &rs.Matches, &rs.Errors,
+			&rs.TimedOut, &rs.Skipped, &totalNanos, &maxNanos, &lastEvaluated); err != nil {
+			return nil, fmt.Errorf("failed to scan rule stats: %w", err)
+		}
+
+		rs.TotalLatency = time.Duration(totalNanos)
+		rs.MaxLatency = time.Duration(maxNanos)
+		if lastEvaluated.Valid {
+			rs.LastEvaluated = lastEvaluated.Time
+		}
+		if rs.Evaluations > 0 {
+			rs.AverageLatency = rs.TotalLatency / time.Duration(rs.Evaluations)
+		}
+		stats = append(stats, &rs)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rule stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// Delete removes the stats of a rule
+// Stats are also removed by cascade when their rule is deleted
+func (s *PostgresRuleStatsStore) Delete(ruleID string) error {
+	_, err := s.db.Exec(`
+		DELETE FROM rule_stats
+		WHERE tenant_id = $1 AND rule_id = $2
+	`, s.tenantID, ruleID)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete rule stats: %w", err)
+	}
+
+	return nil
+}
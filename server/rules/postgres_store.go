@@ -5,9 +5,28 @@ import (
 	"fmt"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// ruleColumns lists the rules table columns read by scanRule, in scan order
+const ruleColumns = `id, name, expression, active, shadow, priority, output_type, tags, rule_sets, effective_from,
+	effective_until, referenced_fields, version, created_at, updated_at`
+
+// ruleVersionColumns lists the rule_versions table columns read by scanRuleVersion, in scan order
+const ruleVersionColumns = `rule_id, version, name, expression, active, shadow, priority, output_type, tags, rule_sets,
+	effective_from, effective_until, created_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 // PostgresRuleStore implements RuleStore backed by PostgreSQL
 type PostgresRuleStore struct {
 	db       *sql.DB
@@ -22,6 +41,56 @@ func NewPostgresRuleStore(db *sql.DB, tenantID string) *PostgresRuleStore {
 	}
 }
 
+// scanRule reads a row selected with ruleColumns
+func scanRule(row rowScanner) (*Rule, error) {
+	var r Rule
+	err := row.Scan(
+		&r.ID,
+		&r.Name,
+		&r.Expression,
+		&r.Active,
+		&r.Shadow,
+		&r.Priority,
+		&r.OutputType,
+		pq.Array(&r.Tags),
+		pq.Array(&r.RuleSets),
+		&r.EffectiveFrom,
+		&r.EffectiveUntil,
+		pq.Array(&r.ReferencedFields),
+		&r.Version,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// scanRuleVersion reads a row selected with ruleVersionColumns
+func scanRuleVersion(row rowScanner) (*RuleVersion, error) {
+	var v RuleVersion
+	err := row.Scan(
+		&v.RuleID,
+		&v.Version,
+		&v.Name,
+		&v.Expression,
+		&v.Active,
+		&v.Shadow,
+		&v.Priority,
+		&v.OutputType,
+		pq.Array(&v.Tags),
+		pq.Array(&v.RuleSets),
+		&v.EffectiveFrom,
+		&v.EffectiveUntil,
+		&v.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
 // Add inserts a new rule into the database
 func (s *PostgresRuleStore) Add(rule *Rule) error {
 	// Check if rule already exists
@@ -36,34 +105,69 @@ func (s *PostgresRuleStore) Add(rule *Rule) error {
 		return fmt.Errorf("rule with ID %s already exists", rule.ID)
 	}
 
-	_, err = s.db.Exec(`
-		INSERT INTO rules (id, tenant_id, name, expression, active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`, rule.ID, s.tenantID, rule.Name, rule.Expression, rule.Active,
-		rule.CreatedAt, rule.UpdatedAt)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rule.Version = 1
+	_, err = tx.Exec(`
+		INSERT INTO rules (id, tenant_id, name, expression, active, shadow, priority, output_type, tags, rule_sets,
+			effective_from, effective_until, referenced_fields, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`, rule.ID, s.tenantID, rule.Name, rule.Expression, rule.Active, rule.Shadow, rule.Priority, rule.OutputType,
+		pq.Array(nonNil(rule.Tags)), pq.Array(nonNil(rule.RuleSets)), rule.EffectiveFrom, rule.EffectiveUntil,
+		pq.Array(nonNil(rule.ReferencedFields)), rule.Version, rule.CreatedAt, rule.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert rule: %w", err)
 	}
 
+	if err := s.insertVersion(tx, rule); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rule: %w", err)
+	}
+
+	return nil
+}
+
+// insertVersion records the current state of a rule as an immutable revision
+func (s *PostgresRuleStore) insertVersion(tx *sql.Tx, rule *Rule) error {
+	_, err := tx.Exec(`
+		INSERT INTO rule_versions (rule_id, tenant_id, version, name, expression, active, shadow, priority,
+			output_type, tags, rule_sets, effective_from, effective_until, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, rule.ID, s.tenantID, rule.Version, rule.Name, rule.Expression, rule.Active, rule.Shadow, rule.Priority,
+		rule.OutputType, pq.Array(nonNil(rule.Tags)), pq.Array(nonNil(rule.RuleSets)), rule.EffectiveFrom,
+		rule.EffectiveUntil, rule.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert rule version: %w", err)
+	}
+
 	return nil
 }
 
 // Get retrieves a rule by ID
 func (s *PostgresRuleStore) Get(id string) (*Rule, error) {
-	var rule Rule
-	err := s.db.QueryRow(`
-		SELECT id, name, expression, active, created_at, updated_at
+	return s.get(s.db, id)
+}
+
+// GetInTx retrieves a rule by ID as part of a transaction
+func (s *PostgresRuleStore) GetInTx(tx *sql.Tx, id string) (*Rule, error) {
+	return s.get(tx, id)
+}
+
+func (s *PostgresRuleStore) get(q querier, id string) (*Rule, error) {
+	rule, err := scanRule(q.QueryRow(`
+		SELECT `+ruleColumns+`
 		FROM rules
 		WHERE id = $1 AND tenant_id = $2
-	`, id, s.tenantID).Scan(
-		&rule.ID,
-		&rule.Name,
-		&rule.Expression,
-		&rule.Active,
-		&rule.CreatedAt,
-		&rule.UpdatedAt,
-	)
+	`, id, s.tenantID))
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("rule %s not found", id)
@@ -72,30 +176,89 @@ func (s *PostgresRuleStore) Get(id string) (*Rule, error) {
 		return nil, fmt.Errorf("failed to get rule: %w", err)
 	}
 
-	return &rule, nil
+	return rule, nil
 }
 
 // ListActive returns all active rules for the tenant
+// Rules are ordered by priority (highest first), then by creation time and ID
 func (s *PostgresRuleStore) ListActive() ([]*Rule, error) {
-	rows, err := s.db.Query(`
-		SELECT id, name, expression, active, created_at, updated_at
+	return s.listActive(s.db)
+}
+
+// ListActiveInTx returns all active rules for the tenant as part of a
+// transaction, seeing the transaction's own changes
+func (s *PostgresRuleStore) ListActiveInTx(tx *sql.Tx) ([]*Rule, error) {
+	return s.listActive(tx)
+}
+
+func (s *PostgresRuleStore) listActive(q querier) ([]*Rule, error) {
+	rulesList, err := s.queryRules(q, `
+		SELECT `+ruleColumns+`
 		FROM rules
 		WHERE tenant_id = $1 AND active = true
-		ORDER BY created_at ASC
+		ORDER BY priority DESC, created_at ASC, id ASC
 	`, s.tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list active rules: %w", err)
 	}
+
+	return rulesList, nil
+}
+
+// List returns every rule for the tenant, active or not, newest first
+func (s *PostgresRuleStore) List() ([]*Rule, error) {
+	rulesList, err := s.queryRules(s.db, `
+		SELECT `+ruleColumns+`
+		FROM rules
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+	`, s.tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rules: %w", err)
+	}
+
+	return rulesList, nil
+}
+
+// ListByTag returns every rule for the tenant carrying the given tag, active or not
+// Rules are ordered by priority (highest first), then by creation time and ID
+func (s *PostgresRuleStore) ListByTag(tag string) ([]*Rule, error) {
+	rulesList, err := s.queryRules(s.db, `
+		SELECT `+ruleColumns+`
+		FROM rules
+		WHERE tenant_id = $1 AND tags @> ARRAY[$2]::TEXT[]
+		ORDER BY priority DESC, created_at ASC, id ASC
+	`, s.tenantID, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rules by tag: %w", err)
+	}
+
+	return rulesList, nil
+}
+
+// nonNil returns an empty slice for nil so NOT NULL array columns get '{}'
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// queryRules runs a query selecting ruleColumns and scans every row
+func (s *PostgresRuleStore) queryRules(q querier, query string, args ...any) ([]*Rule, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var rulesList []*Rule
 	for rows.Next() {
-		var r Rule
-		if err := rows.Scan(&r.ID, &r.Name, &r.Expression, &r.Active,
-			&r.CreatedAt, &r.UpdatedAt); err != nil {
+		r, err := scanRule(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan rule: %w", err)
 		}
-		rulesList = append(rulesList, &r)
+		rulesList = append(rulesList, r)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -105,36 +268,53 @@ func (s *PostgresRuleStore) ListActive() ([]*Rule, error) {
 	return rulesList, nil
 }
 
-// Update modifies an existing rule
+// Update modifies an existing rule and records the new revision
 func (s *PostgresRuleStore) Update(rule *Rule) error {
-	// Check if rule exists
-	_, err := s.Get(rule.ID)
+	tx, err := s.db.Begin()
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.UpdateInTx(tx, rule); err != nil {
 		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rule update: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateInTx updates an existing rule and records its new version as part of
+// a transaction the caller commits
+func (s *PostgresRuleStore) UpdateInTx(tx *sql.Tx, rule *Rule) error {
 	// Update the timestamp
 	rule.UpdatedAt = time.Now()
 
-	result, err := s.db.Exec(`
+	err := tx.QueryRow(`
 		UPDATE rules
-		SET name = $1, expression = $2, active = $3, updated_at = $4
-		WHERE id = $5 AND tenant_id = $6
-	`, rule.Name, rule.Expression, rule.Active, rule.UpdatedAt, rule.ID, s.tenantID)
+		SET name = $1, expression = $2, active = $3, shadow = $4, priority = $5, output_type = $6,
+			tags = $7, rule_sets = $8, effective_from = $9, effective_until = $10, referenced_fields = $11,
+			updated_at = $12, version = version + 1
+		WHERE id = $13 AND tenant_id = $14
+		RETURNING version, created_at
+	`, rule.Name, rule.Expression, rule.Active, rule.Shadow, rule.Priority, rule.OutputType,
+		pq.Array(nonNil(rule.Tags)), pq.Array(nonNil(rule.RuleSets)), rule.EffectiveFrom, rule.EffectiveUntil,
+		pq.Array(nonNil(rule.ReferencedFields)), rule.UpdatedAt, rule.ID, s.tenantID).Scan(
+		&rule.Version,
+		&rule.CreatedAt,
+	)
 
-	if err != nil {
-		return fmt.Errorf("failed to update rule: %w", err)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("rule %s not found", rule.ID)
 	}
-
-	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("rule %s not found", rule.ID)
+		return fmt.Errorf("failed to update rule: %w", err)
 	}
 
-	return nil
+	return s.insertVersion(tx, rule)
 }
 
 // Delete removes a rule from the database
@@ -158,3 +338,54 @@ func (s *PostgresRuleStore) Delete(id string) error {
 
 	return nil
 }
+
+// ListVersions returns all revisions of a rule, newest first
+func (s *PostgresRuleStore) ListVersions(id string) ([]*RuleVersion, error) {
+	rows, err := s.db.Query(`
+		SELECT `+ruleVersionColumns+`
+		FROM rule_versions
+		WHERE rule_id = $1 AND tenant_id = $2
+		ORDER BY version DESC
+	`, id, s.tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rule versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*RuleVersion
+	for rows.Next() {
+		v, err := scanRuleVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rule version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rule versions: %w", err)
+	}
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("rule %s not found", id)
+	}
+
+	return versions, nil
+}
+
+// GetVersion retrieves a single revision of a rule
+func (s *PostgresRuleStore) GetVersion(id string, version int) (*RuleVersion, error) {
+	v, err := scanRuleVersion(s.db.QueryRow(`
+		SELECT `+ruleVersionColumns+`
+		FROM rule_versions
+		WHERE rule_id = $1 AND tenant_id = $2 AND version = $3
+	`, id, s.tenantID, version))
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("version %d of rule %s not found", version, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule version: %w", err)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,208 @@
+package rules
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// PostgresRuleTestStore implements RuleTestStore backed by the rule_test_cases table
+type PostgresRuleTestStore struct {
+	db       *sql.DB
+	tenantID string
+}
+
+// NewPostgresRuleTestStore creates a new PostgreSQL-backed RuleTestStore for a specific tenant
+func NewPostgresRuleTestStore(db *sql.DB, tenantID string) *PostgresRuleTestStore {
+	return &PostgresRuleTestStore{
+		db:       db,
+		tenantID: tenantID,
+	}
+}
+
+// ruleTestColumns is the column list read by scanRuleTestCase
+const ruleTestColumns = `id, rule_id, name, facts, expected_matched, expected_output, created_at, updated_at`
+
+// scanRuleTestCase reads a row of ruleTestColumns
+func scanRuleTestCase(row rowScanner) (*RuleTestCase, error) {
+	var tc RuleTestCase
+	var factsJSON, outputJSON []byte
+	var expectedMatched sql.NullBool
+	if err := row.Scan(&tc.ID, &tc.RuleID, &tc.Name, &factsJSON, &expectedMatched, &outputJSON,
+		&tc.CreatedAt, &tc.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(factsJSON, &tc.Facts); err != nil {
+		return nil, fmt.Errorf("invalid facts for test case %s: %w", tc.ID, err)
+	}
+	if expectedMatched.Valid {
+		tc.ExpectedMatched = &expectedMatched.Bool
+	}
+	if len(outputJSON) > 0 {
+		if err := json.Unmarshal(outputJSON, &tc.ExpectedOutput); err != nil {
+			return nil, fmt.Errorf("invalid expected output for test case %s: %w", tc.ID, err)
+		}
+	}
+
+	return &tc, nil
+}
+
+// encodeRuleTestCase marshals the JSON columns of a test case
+// The expected output is NULL when it is not checked
+func encodeRuleTestCase(tc *RuleTestCase) (facts []byte, output []byte, err error) {
+	facts, err = json.Marshal(tc.Facts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal facts: %w", err)
+	}
+	if tc.ExpectedOutput != nil {
+		output, err = json.Marshal(tc.ExpectedOutput)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal expected output: %w", err)
+		}
+	}
+	return facts, output, nil
+}
+
+// Add inserts a new test case into the database
+func (s *PostgresRuleTestStore) Add(tc *RuleTestCase) error {
+	factsJSON, outputJSON, err := encodeRuleTestCase(tc)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.QueryRow(`
+		INSERT INTO rule_test_cases (id, tenant_id, rule_id, name, facts, expected_matched, expected_output, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`, tc.ID, s.tenantID, tc.RuleID, tc.Name, factsJSON, tc.ExpectedMatched, outputJSON).Scan(&tc.CreatedAt, &tc.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert test case: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves a test case by ID
+func (s *PostgresRuleTestStore) Get(id string) (*RuleTestCase, error) {
+	tc, err := scanRuleTestCase(s.db.QueryRow(`
+		SELECT `+ruleTestColumns+`
+		FROM rule_test_cases
+		WHERE tenant_id = $1 AND id = $2
+	`, s.tenantID, id))
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("test case %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test case: %w", err)
+	}
+
+	return tc, nil
+}
+
+// ListByRule returns a rule's test cases ordered by creation time
+func (s *PostgresRuleTestStore) ListByRule(ruleID string) ([]*RuleTestCase, error) {
+	return s.queryRuleTests(`
+		SELECT `+ruleTestColumns+`
+		FROM rule_test_cases
+		WHERE tenant_id = $1 AND rule_id = $2
+		ORDER BY created_at ASC, id ASC
+	`, s.tenantID, ruleID)
+}
+
+// List returns all of the tenant's test cases ordered by rule, then creation time
+func (s *PostgresRuleTestStore) List() ([]*RuleTestCase, error) {
+	return s.queryRuleTests(`
+		SELECT `+ruleTestColumns+`
+		FROM rule_test_cases
+		WHERE tenant_id = $1
+		ORDER BY rule_id ASC, created_at ASC, id ASC
+	`, s.tenantID)
+}
+
+// queryRuleTests runs a query returning ruleTestColumns rows
+func (s *PostgresRuleTestStore) queryRuleTests(query string, args ...any) ([]*RuleTestCase, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list test cases: %w", err)
+	}
+	defer rows.Close()
+
+	var cases []*RuleTestCase
+	for rows.Next() {
+		tc, err := scanRuleTestCase(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan test case: %w", err)
+		}
+		cases = append(cases, tc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating test cases: %w", err)
+	}
+
+	return cases, nil
+}
+
+// Update modifies an existing test case
+func (s *PostgresRuleTestStore) Update(tc *RuleTestCase) error {
+	factsJSON, outputJSON, err := encodeRuleTestCase(tc)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.QueryRow(`
+		UPDATE rule_test_cases
+		SET name = $1, facts = $2, expected_matched = $3, expected_output = $4, updated_at = NOW()
+		WHERE tenant_id = $5 AND id = $6
+		RETURNING created_at, updated_at
+	`, tc.Name, factsJSON, tc.ExpectedMatched, outputJSON, s.tenantID, tc.ID).Scan(&tc.CreatedAt, &tc.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("test case %s not found", tc.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update test case: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a test case from the database
+func (s *PostgresRuleTestStore) Delete(id string) error {
+	result, err := s.db.Exec(`
+		DELETE FROM rule_test_cases
+		WHERE tenant_id = $1 AND id = $2
+	`, s.tenantID, id)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete test case: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("test case %s not found", id)
+	}
+
+	return nil
+}
+
+// DeleteByRule removes every test case of a rule
+// Test cases are also removed by cascade when their rule is deleted
+func (s *PostgresRuleTestStore) DeleteByRule(ruleID string) error {
+	_, err := s.db.Exec(`
+		DELETE FROM rule_test_cases
+		WHERE tenant_id = $1 AND rule_id = $2
+	`, s.tenantID, ruleID)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete test cases: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package rules
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// RuleStatus describes whether a rule is in effect at a given time
+type RuleStatus string
+
+const (
+	// RuleStatusLive is an active rule inside its effective window
+	RuleStatusLive RuleStatus = "live"
+
+	// RuleStatusScheduled is an active rule whose window has not opened yet
+	RuleStatusScheduled RuleStatus = "scheduled"
+
+	// RuleStatusExpired is an active rule whose window has closed
+	RuleStatusExpired RuleStatus = "expired"
+
+	// RuleStatusInactive is a rule that is switched off
+	RuleStatusInactive RuleStatus = "inactive"
+)
+
+// EffectiveAt reports whether now falls inside the rule's effective window
+// The window includes EffectiveFrom and excludes EffectiveUntil
+func (r *Rule) EffectiveAt(now time.Time) bool {
+	if r.EffectiveFrom != nil && now.Before(*r.EffectiveFrom) {
+		return false
+	}
+	if r.EffectiveUntil != nil && !now.Before(*r.EffectiveUntil) {
+		return false
+	}
+	return true
+}
+
+// StatusAt returns the status of the rule at now
+func (r *Rule) StatusAt(now time.Time) RuleStatus {
+	switch {
+	case !r.Active:
+		return RuleStatusInactive
+	case r.EffectiveFrom != nil && now.Before(*r.EffectiveFrom):
+		return RuleStatusScheduled
+	case r.EffectiveUntil != nil && !now.Before(*r.EffectiveUntil):
+		return RuleStatusExpired
+	default:
+		return RuleStatusLive
+	}
+}
+
+// validateSchedule checks that a rule's effective window is not empty
+func validateSchedule(r *Rule) error {
+	if r.EffectiveFrom != nil && r.EffectiveUntil != nil && !r.EffectiveUntil.After(*r.EffectiveFrom) {
+		return fmt.Errorf("effective until (%s) must be after effective from (%s)",
+			r.EffectiveUntil.Format(time.RFC3339), r.EffectiveFrom.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// effectiveRules filters rules to those in effect at now, preserving order
+func effectiveRules(rules []*Rule, now time.Time) []*Rule {
+	effective := rules[:0:0]
+	for _, rule := range rules {
+		if rule.EffectiveAt(now) {
+			effective = append(effective, rule)
+		}
+	}
+	return effective
+}
+
+// liveRules returns the current snapshot, its active rules in effect on the
+// engine's clock and the time they were checked at, which references between
+// rules are resolved at too
+// The snapshot holds every active rule, so rules come into and go out of
+// effect without a new snapshot being published
+func (en *Engine) liveRules() (*ruleSnapshot, []*Rule, time.Time, error) {
+	snap, err := en.currentSnapshot()
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	now := en.clock()
+	return snap, effectiveRules(snap.rules, now), now, nil
+}
+
+// outOfWindowValue is what a reference to a rule outside its effective window
+// evaluates to: false, as the rule did not match, for rules without a declared
+// output type or declaring bool; rules with any other output type have no
+// output, so the reference is an error
+func outOfWindowValue(name string, compiled *compiledRule) ref.Val {
+	if compiled == nil || compiled.outputType == nil || compiled.outputType.IsExactType(cel.BoolType) {
+		return types.False
+	}
+	return types.NewErr("referenced rule %s is not in effect", name)
+}
+
+// equalTimes reports whether two optional times are both unset or the same instant
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
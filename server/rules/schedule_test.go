@@ -0,0 +1,217 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable engine clock
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// timePtr returns a pointer to t, for effective windows
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// TestEffectiveWindows verifies EvaluateAll only runs rules inside their
+// effective window, without the rules cache being invalidated
+func TestEffectiveWindows(t *testing.T) {
+	start := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start.Add(-time.Hour)}
+	engine := newTestEngine(t, EngineConfig{Clock: clock.Now})
+
+	rules := []*Rule{
+		{ID: "always", Name: "always", Expression: `true`, Active: true},
+		{ID: "promo", Name: "blackFriday", Expression: `true`, Active: true, EffectiveFrom: timePtr(start), EffectiveUntil: timePtr(end)},
+		{ID: "legacy", Name: "legacy", Expression: `true`, Active: true, EffectiveUntil: timePtr(start)},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	testCases := []struct {
+		name string
+		now  time.Time
+		want []string
+	}{
+		{"Before window", start.Add(-time.Hour), []string{"always", "legacy"}},
+		{"Window opens", start, []string{"always", "promo"}},
+		{"Inside window", start.Add(48 * time.Hour), []string{"always", "promo"}},
+		{"Window closes", end, []string{"always"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock.now = tc.now
+
+			results, err := engine.EvaluateAll(map[string]any{})
+			if err != nil {
+				t.Fatalf("EvaluateAll() failed: %v", err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.RuleID)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("EvaluateAll() ran %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("EvaluateAll() ran %v, want %v", got, tc.want)
+				}
+			}
+
+			batch, err := engine.EvaluateBatch([]BatchItem{{Facts: map[string]any{}}}, EvaluateOptions{})
+			if err != nil {
+				t.Fatalf("EvaluateBatch() failed: %v", err)
+			}
+			if len(batch[0].Results) != len(tc.want) {
+				t.Errorf("EvaluateBatch() ran %d rules, want %d", len(batch[0].Results), len(tc.want))
+			}
+		})
+	}
+}
+
+// TestEffectiveWindowsByID verifies rules requested by ID are only run inside
+// their effective window
+func TestEffectiveWindowsByID(t *testing.T) {
+	now := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+	engine := newTestEngine(t, EngineConfig{Clock: func() time.Time { return now }})
+
+	rules := []*Rule{
+		{ID: "live", Name: "live", Expression: `true`, Active: true},
+		{ID: "scheduled", Name: "scheduled", Expression: `true`, Active: true, EffectiveFrom: timePtr(now.Add(time.Hour))},
+		{ID: "expired", Name: "expired", Expression: `true`, Active: true, EffectiveUntil: timePtr(now)},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+
+	for _, ruleID := range []string{"scheduled", "expired"} {
+		if _, err := engine.Evaluate(ruleID, map[string]any{}); !errors.Is(err, ErrRuleNotActive) {
+			t.Errorf("Evaluate(%s) error = %v, want ErrRuleNotActive", ruleID, err)
+		}
+	}
+	if result, err := engine.Evaluate("live", map[string]any{}); err != nil || !result.Matched {
+		t.Errorf("Evaluate(live) = %+v, %v; want matched", result, err)
+	}
+
+	results, err := engine.EvaluateRulesContext(context.Background(), []string{"scheduled", "live", "expired"}, map[string]any{}, EvaluateOptions{})
+	if err != nil {
+		t.Fatalf("EvaluateRulesContext() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].RuleID != "live" {
+		t.Errorf("EvaluateRulesContext() = %v, want only the live rule", results)
+	}
+}
+
+// TestEffectiveWindowsReferences verifies a reference to a rule outside its
+// effective window is not matched, or an error for a rule with an output,
+// without running the rule
+func TestEffectiveWindowsReferences(t *testing.T) {
+	start := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start.Add(-time.Hour)}
+	engine := newTestEngine(t, EngineConfig{Clock: clock.Now})
+
+	rules := []*Rule{
+		{ID: "promo", Name: "promo", Expression: `Transaction.Amount > 100.0`, Active: true, EffectiveFrom: timePtr(start)},
+		{ID: "discount", Name: "discount", Expression: `{"percent": 10}`, OutputType: "map", Active: true, EffectiveFrom: timePtr(start)},
+		{ID: "promoted", Name: "promoted", Expression: `rules.promo`, Active: true},
+		{ID: "discounted", Name: "discounted", Expression: `rules.discount.percent > 0`, Active: true},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+	facts := map[string]any{"Transaction": map[string]any{"Amount": 500.0}}
+
+	// Before the window opens
+	if result, err := engine.Evaluate("promoted", facts); err != nil || result.Matched {
+		t.Errorf("Evaluate(promoted) = %+v, %v; want not matched", result, err)
+	}
+	if _, err := engine.Evaluate("discounted", facts); err == nil || !strings.Contains(err.Error(), "not in effect") {
+		t.Errorf("Evaluate(discounted) error = %v, want referenced rule not in effect", err)
+	}
+	results, err := engine.EvaluatePartial(facts)
+	if err != nil {
+		t.Fatalf("EvaluatePartial() failed: %v", err)
+	}
+	for _, result := range results {
+		if result.RuleID == "promoted" && (!result.Decided || result.Matched) {
+			t.Errorf("EvaluatePartial() promoted = %+v, want decided and not matched", result)
+		}
+	}
+
+	clock.now = start
+	for _, ruleID := range []string{"promoted", "discounted"} {
+		if result, err := engine.Evaluate(ruleID, facts); err != nil || !result.Matched {
+			t.Errorf("Evaluate(%s) inside the window = %+v, %v; want matched", ruleID, result, err)
+		}
+	}
+}
+
+// TestRuleStatus verifies a rule's status at different times
+func TestRuleStatus(t *testing.T) {
+	start := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	rule := &Rule{Active: true, EffectiveFrom: &start, EffectiveUntil: &end}
+
+	testCases := []struct {
+		name string
+		now  time.Time
+		want RuleStatus
+	}{
+		{"Scheduled", start.Add(-time.Second), RuleStatusScheduled},
+		{"Live at start", start, RuleStatusLive},
+		{"Expired at end", end, RuleStatusExpired},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := rule.StatusAt(tc.now); got != tc.want {
+				t.Errorf("StatusAt() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	inactive := &Rule{Active: false, EffectiveFrom: &start}
+	if got := inactive.StatusAt(start.Add(-time.Hour)); got != RuleStatusInactive {
+		t.Errorf("StatusAt() = %s, want %s", got, RuleStatusInactive)
+	}
+}
+
+// TestEffectiveWindowValidation verifies empty windows are rejected
+func TestEffectiveWindowValidation(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{})
+	start := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+
+	err := engine.AddRule(&Rule{ID: "r", Name: "r", Expression: `true`, Active: true,
+		EffectiveFrom: timePtr(start), EffectiveUntil: timePtr(start)})
+	if err == nil {
+		t.Error("AddRule() should reject a window that ends when it starts")
+	}
+
+	if err := engine.AddRule(&Rule{ID: "r", Name: "r", Expression: `true`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	err = engine.UpdateRule(&Rule{ID: "r", Name: "r", Expression: `true`, Active: true,
+		EffectiveFrom: timePtr(start), EffectiveUntil: timePtr(start.Add(-time.Hour))})
+	if err == nil {
+		t.Error("UpdateRule() should reject a window that ends before it starts")
+	}
+}
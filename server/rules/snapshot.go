@@ -0,0 +1,136 @@
+package rules
+
+import (
+	"time"
+
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// ruleSnapshot is an immutable view of everything evaluation reads: the
+// active rules, the programs compiled for them, derived fields, reference
+// lists and evaluation settings
+// Changes copy the current snapshot, change the copy and publish it, so an
+// evaluation that loads a snapshot once sees a single consistent ruleset
+// version without taking a lock. Once published, nothing a snapshot points
+// to is modified; maps and slices are replaced instead.
+type ruleSnapshot struct {
+	version     uint64
+	rules       []*Rule                  // active rules in store order
+	active      map[string]*Rule         // ruleID -> active rule, for evaluating rules by ID
+	loaded      bool                     // whether rules are loaded; false after a failed reload
+	loadedAt    time.Time                // when rules were loaded, checked against the cache TTL
+	programs    map[string]*compiledRule // ruleID -> compiled program
+	legacy      map[string]bool          // ruleIDs compiled against fallbackEnv
+	refs        map[string]*Rule         // referenceable rule name -> active rule
+	chained     bool                     // whether any compiled rule references another
+	derived     []*compiledDerivedField  // derived fields in dependency order
+	lists       map[string]ref.Val       // lists.<name> -> list value
+	limits      EvaluationLimits
+	concurrency int // workers used by EvaluateAll; 0 or 1 is sequential
+}
+
+// clone returns a copy of s sharing its maps and slices, to be changed and
+// published as the next version
+func (s *ruleSnapshot) clone() *ruleSnapshot {
+	next := *s
+	next.version++
+	return &next
+}
+
+// setRules replaces the active rules
+func (s *ruleSnapshot) setRules(rules []*Rule) {
+	s.rules = rules
+	s.active = make(map[string]*Rule, len(rules))
+	for _, rule := range rules {
+		s.active[rule.ID] = rule
+	}
+	s.loaded = true
+	s.loadedAt = time.Now()
+}
+
+// unloadRules drops the active rules so the next evaluation reloads them
+func (s *ruleSnapshot) unloadRules() {
+	s.rules = nil
+	s.active = nil
+	s.loaded = false
+}
+
+// setPrograms replaces the compiled programs and records whether any rule
+// references another
+func (s *ruleSnapshot) setPrograms(programs map[string]*compiledRule, legacy map[string]bool) {
+	s.programs = programs
+	s.legacy = legacy
+	s.chained = false
+	for _, compiled := range programs {
+		if len(compiled.dependencies) > 0 {
+			s.chained = true
+			return
+		}
+	}
+}
+
+// copyPrograms returns copies of the compiled programs and the legacy set
+// that a change can modify before publishing them
+func (s *ruleSnapshot) copyPrograms() (map[string]*compiledRule, map[string]bool) {
+	programs := make(map[string]*compiledRule, len(s.programs)+1)
+	for ruleID, compiled := range s.programs {
+		programs[ruleID] = compiled
+	}
+	legacy := make(map[string]bool, len(s.legacy))
+	for ruleID := range s.legacy {
+		legacy[ruleID] = true
+	}
+	return programs, legacy
+}
+
+// stale reports whether the active rules must be loaded from the store
+func (s *ruleSnapshot) stale(ttl time.Duration, now time.Time) bool {
+	return !s.loaded || (ttl > 0 && now.Sub(s.loadedAt) > ttl)
+}
+
+// publish applies change to a copy of the current snapshot and makes the copy
+// current, returning it
+// Publishing is serialized by mu so no change is lost; change may also update
+// the fields mu guards. Readers load the current snapshot without locking.
+func (en *Engine) publish(change func(next *ruleSnapshot)) *ruleSnapshot {
+	en.mu.Lock()
+	defer en.mu.Unlock()
+
+	next := en.snapshot.Load().clone()
+	change(next)
+	en.snapshot.Store(next)
+	return next
+}
+
+// currentSnapshot returns the current snapshot for an evaluation
+// Rules that are not loaded, after a failed reload, are loaded and compiled
+// first. Rules older than the cache TTL are rebuilt in the background while
+// the current snapshot keeps being served, so evaluation does not wait on the
+// store.
+func (en *Engine) currentSnapshot() (*ruleSnapshot, error) {
+	snap := en.snapshot.Load()
+	if !snap.loaded {
+		if err := en.CompileAllRules(); err != nil {
+			return nil, err
+		}
+		return en.snapshot.Load(), nil
+	}
+
+	if snap.stale(en.options.CacheTTL, time.Now()) {
+		en.refreshRules()
+	}
+	return snap, nil
+}
+
+// refreshRules rebuilds the snapshot from the store in the background unless
+// a rebuild is already running
+// A failed rebuild leaves the snapshot stale, so the next evaluation retries.
+func (en *Engine) refreshRules() {
+	if !en.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer en.refreshing.Store(false)
+		_ = en.CompileAllRules()
+	}()
+}
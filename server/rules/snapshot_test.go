@@ -0,0 +1,240 @@
+package rules
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// versionedRule returns a rule whose output is its own name, so a result
+// whose output differs from its rule name mixes two versions of the rule
+func versionedRule(version int) *Rule {
+	name := fmt.Sprintf("versioned_%d", version)
+	return &Rule{
+		ID:         "versioned",
+		Name:       name,
+		Expression: fmt.Sprintf("%q", name),
+		OutputType: "string",
+		Active:     true,
+	}
+}
+
+// TestSnapshotConsistentUnderChanges verifies evaluations running while rules
+// are updated, added and deleted only ever see one version of the ruleset
+func TestSnapshotConsistentUnderChanges(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, parallelRules(20, "")...)
+	if err := engine.AddRule(versionedRule(0)); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer stop.Store(true)
+		for i := 1; i <= 200; i++ {
+			if err := engine.UpdateRule(versionedRule(i)); err != nil {
+				t.Errorf("UpdateRule() failed: %v", err)
+				return
+			}
+			extra := &Rule{ID: "extra", Name: "extra", Expression: `User.Age > 0`, Active: true}
+			if err := engine.AddRule(extra); err != nil {
+				t.Errorf("AddRule() failed: %v", err)
+				return
+			}
+			if err := engine.DeleteRule("extra"); err != nil {
+				t.Errorf("DeleteRule() failed: %v", err)
+				return
+			}
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				results, err := engine.EvaluateAll(facts)
+				if err != nil {
+					t.Errorf("EvaluateAll() failed: %v", err)
+					return
+				}
+				for _, result := range results {
+					if result.Error != nil {
+						t.Errorf("rule %s: %v", result.RuleID, result.Error)
+						return
+					}
+					if result.RuleID == "versioned" && result.Output != result.RuleName {
+						t.Errorf("rule %s evaluated as %v, want its own version", result.RuleName, result.Output)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// TestSnapshotReloadCompilesRules verifies rules dropped after a failed
+// reload are loaded together with their programs by the next evaluation
+func TestSnapshotReloadCompilesRules(t *testing.T) {
+	engine, err := NewEngine(NewInMemoryRuleStore())
+	if err != nil {
+		t.Fatalf("NewEngine() failed: %v", err)
+	}
+
+	engine.publish(func(next *ruleSnapshot) {
+		next.unloadRules()
+	})
+	rule := &Rule{ID: "newer", Name: "newer", Expression: `true`, Active: true}
+	if err := engine.store.Add(rule); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	results, err := engine.EvaluateAll(map[string]any{})
+	if err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Error != nil || !results[0].Matched {
+		t.Errorf("EvaluateAll() = %v, want the reloaded rule compiled and matched", results)
+	}
+}
+
+// lockedRuleState reproduces how evaluation read engine state before
+// snapshots: the cached rule list and each rule's program behind RWMutexes
+type lockedRuleState struct {
+	cacheMu  sync.RWMutex
+	rules    []*Rule
+	mu       sync.RWMutex
+	programs map[string]*compiledRule
+}
+
+func (s *lockedRuleState) read() int {
+	s.cacheMu.RLock()
+	rules := s.rules
+	s.cacheMu.RUnlock()
+
+	found := 0
+	for _, rule := range rules {
+		s.mu.RLock()
+		if s.programs[rule.ID] != nil {
+			found++
+		}
+		s.mu.RUnlock()
+	}
+	return found
+}
+
+func (s *lockedRuleState) write(ruleID string, compiled *compiledRule) {
+	s.mu.Lock()
+	s.programs[ruleID] = compiled
+	s.mu.Unlock()
+
+	s.cacheMu.Lock()
+	s.rules = append([]*Rule(nil), s.rules...)
+	s.cacheMu.Unlock()
+}
+
+// benchmarkRuleState runs read on every benchmark goroutine while write runs
+// continuously in the background
+func benchmarkRuleState(b *testing.B, read func() int, write func(i int)) {
+	var stop atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; !stop.Load(); i++ {
+			write(i)
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if read() == 0 {
+				b.Error("no programs found")
+			}
+		}
+	})
+	b.StopTimer()
+
+	stop.Store(true)
+	<-done
+}
+
+// The state reads of one evaluation of 1000 rules under a constant stream of
+// rule changes: per-rule RWMutex reads against a single atomic snapshot load
+func BenchmarkRuleStateRead_Locked(b *testing.B) {
+	engine := newTestEngine(b, EngineConfig{}, parallelRules(1000, "")...)
+	snap := engine.snapshot.Load()
+	state := &lockedRuleState{rules: snap.rules}
+	state.programs, _ = snap.copyPrograms()
+
+	benchmarkRuleState(b, state.read, func(i int) {
+		rule := snap.rules[i%len(snap.rules)]
+		state.write(rule.ID, snap.programs[rule.ID])
+	})
+}
+
+func BenchmarkRuleStateRead_Snapshot(b *testing.B) {
+	engine := newTestEngine(b, EngineConfig{}, parallelRules(1000, "")...)
+	read := func() int {
+		snap := engine.snapshot.Load()
+		found := 0
+		for _, rule := range snap.rules {
+			if snap.programs[rule.ID] != nil {
+				found++
+			}
+		}
+		return found
+	}
+
+	benchmarkRuleState(b, read, func(i int) {
+		engine.publish(func(next *ruleSnapshot) {
+			rule := next.rules[i%len(next.rules)]
+			programs, legacy := next.copyPrograms()
+			programs[rule.ID] = next.programs[rule.ID]
+			next.setPrograms(programs, legacy)
+			next.setRules(append([]*Rule(nil), next.rules...))
+		})
+	})
+}
+
+// BenchmarkEvaluateAll_Contended evaluates 100 rules from every benchmark
+// goroutine while another goroutine keeps updating a rule
+func BenchmarkEvaluateAll_Contended(b *testing.B) {
+	engine := newTestEngine(b, EngineConfig{}, parallelRules(100, "")...)
+	if err := engine.AddRule(versionedRule(0)); err != nil {
+		b.Fatalf("AddRule() failed: %v", err)
+	}
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+
+	var stop atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; !stop.Load(); i++ {
+			if err := engine.UpdateRule(versionedRule(i)); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := engine.EvaluateAll(facts); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.StopTimer()
+
+	stop.Store(true)
+	<-done
+}
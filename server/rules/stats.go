@@ -0,0 +1,374 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// statsFlushInterval is how often evaluations write the stats they
+	// recorded to the engine's stats store
+	statsFlushInterval = 10 * time.Second
+
+	// maxShadowEvaluations bounds the requests whose shadow rules run in the
+	// background at once, so shadow rules cannot pile up under load
+	maxShadowEvaluations = 64
+)
+
+// RuleStats summarizes how a rule has evaluated in EvaluateAll and batch
+// evaluation
+// Stats are kept for live and shadow rules alike, so a shadow rule can be
+// compared with the live rule it is meant to replace
+type RuleStats struct {
+	RuleID         string
+	RuleName       string
+	Shadow         bool
+	Evaluations    int64
+	Matches        int64
+	Errors         int64 // includes rules that were cut off
+	TimedOut       int64
+	Skipped        int64 // shadow evaluations skipped because too many were running
+	TotalLatency   time.Duration
+	AverageLatency time.Duration
+	MaxLatency     time.Duration
+	LastEvaluated  time.Time
+}
+
+// merge adds the counts of other to s
+func (s *RuleStats) merge(other *RuleStats) {
+	s.Evaluations += other.Evaluations
+	s.Matches += other.Matches
+	s.Errors += other.Errors
+	s.TimedOut += other.TimedOut
+	s.Skipped += other.Skipped
+	s.TotalLatency += other.TotalLatency
+	s.MaxLatency = max(s.MaxLatency, other.MaxLatency)
+	if other.LastEvaluated.After(s.LastEvaluated) {
+		s.LastEvaluated = other.LastEvaluated
+	}
+	s.AverageLatency = 0
+	if s.Evaluations > 0 {
+		s.AverageLatency = s.TotalLatency / time.Duration(s.Evaluations)
+	}
+}
+
+// ruleCounters accumulates the stats of one rule since they were last flushed
+// Counters are updated atomically so parallel workers never contend on a lock
+type ruleCounters struct {
+	rule          atomic.Pointer[Rule] // most recently evaluated revision
+	evaluations   atomic.Int64
+	matches       atomic.Int64
+	errors        atomic.Int64
+	timedOut      atomic.Int64
+	skipped       atomic.Int64
+	totalNanos    atomic.Int64
+	maxNanos      atomic.Int64
+	lastEvaluated atomic.Int64 // unix nanoseconds
+}
+
+// load returns the counts accumulated since the last flush, resetting them
+// when reset is set
+func (c *ruleCounters) load(ruleID string, reset bool) *RuleStats {
+	read := func(v *atomic.Int64) int64 {
+		if reset {
+			return v.Swap(0)
+		}
+		return v.Load()
+	}
+
+	rule := c.rule.Load()
+	s := &RuleStats{
+		RuleID:       ruleID,
+		RuleName:     rule.Name,
+		Shadow:       rule.Shadow,
+		Evaluations:  read(&c.evaluations),
+		Matches:      read(&c.matches),
+		Errors:       read(&c.errors),
+		TimedOut:     read(&c.timedOut),
+		Skipped:      read(&c.skipped),
+		TotalLatency: time.Duration(read(&c.totalNanos)),
+		MaxLatency:   time.Duration(read(&c.maxNanos)),
+	}
+	if last := c.lastEvaluated.Load(); last != 0 {
+		s.LastEvaluated = time.Unix(0, last)
+	}
+	if s.Evaluations > 0 {
+		s.AverageLatency = s.TotalLatency / time.Duration(s.Evaluations)
+	}
+	return s
+}
+
+// ruleStatsRecorder counts the evaluations of every rule in memory and
+// periodically adds the counts to a RuleStatsStore
+// An engine that replaces another shares its recorder, see InheritStats, so
+// no counts are lost when a tenant's engine is rebuilt.
+type ruleStatsRecorder struct {
+	store     RuleStatsStore
+	counters  sync.Map // ruleID -> *ruleCounters
+	flushMu   sync.Mutex
+	flushing  atomic.Bool
+	lastFlush atomic.Int64 // unix nanoseconds
+}
+
+// newRuleStatsRecorder creates a recorder that flushes to store
+func newRuleStatsRecorder(store RuleStatsStore) *ruleStatsRecorder {
+	r := &ruleStatsRecorder{store: store}
+	r.lastFlush.Store(time.Now().UnixNano())
+	return r
+}
+
+// countersFor returns the counters of rule, storing its latest revision
+func (r *ruleStatsRecorder) countersFor(rule *Rule) *ruleCounters {
+	value, ok := r.counters.Load(rule.ID)
+	if !ok {
+		value, _ = r.counters.LoadOrStore(rule.ID, &ruleCounters{})
+	}
+	c := value.(*ruleCounters)
+	c.rule.Store(rule)
+	return c
+}
+
+// record adds one evaluation of rule to its stats
+func (r *ruleStatsRecorder) record(rule *Rule, result *EvaluationResult, latency time.Duration) {
+	c := r.countersFor(rule)
+
+	c.evaluations.Add(1)
+	if result.Matched {
+		c.matches.Add(1)
+	}
+	if result.Error != nil {
+		c.errors.Add(1)
+	}
+	if result.TimedOut {
+		c.timedOut.Add(1)
+	}
+
+	nanos := latency.Nanoseconds()
+	c.totalNanos.Add(nanos)
+	for {
+		max := c.maxNanos.Load()
+		if nanos <= max || c.maxNanos.CompareAndSwap(max, nanos) {
+			break
+		}
+	}
+	c.lastEvaluated.Store(time.Now().UnixNano())
+
+	r.flushIfDue()
+}
+
+// skip counts a shadow evaluation of rule that was skipped
+func (r *ruleStatsRecorder) skip(rule *Rule) {
+	r.countersFor(rule).skipped.Add(1)
+	r.flushIfDue()
+}
+
+// restore adds counts that failed to flush back to the counters
+func (r *ruleStatsRecorder) restore(s *RuleStats) {
+	value, _ := r.counters.LoadOrStore(s.RuleID, &ruleCounters{})
+	c := value.(*ruleCounters)
+	c.rule.CompareAndSwap(nil, &Rule{ID: s.RuleID, Name: s.RuleName, Shadow: s.Shadow})
+	c.evaluations.Add(s.Evaluations)
+	c.matches.Add(s.Matches)
+	c.errors.Add(s.Errors)
+	c.timedOut.Add(s.TimedOut)
+	c.skipped.Add(s.Skipped)
+	c.totalNanos.Add(s.TotalLatency.Nanoseconds())
+	for {
+		max := c.maxNanos.Load()
+		if s.MaxLatency.Nanoseconds() <= max || c.maxNanos.CompareAndSwap(max, s.MaxLatency.Nanoseconds()) {
+			break
+		}
+	}
+	if s.LastEvaluated.IsZero() {
+		return
+	}
+	for {
+		last := c.lastEvaluated.Load()
+		if s.LastEvaluated.UnixNano() <= last || c.lastEvaluated.CompareAndSwap(last, s.LastEvaluated.UnixNano()) {
+			break
+		}
+	}
+}
+
+// forget drops the stats of a deleted rule
+func (r *ruleStatsRecorder) forget(ruleID string) error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.counters.Delete(ruleID)
+	return r.store.Delete(ruleID)
+}
+
+// flushIfDue flushes in the background once statsFlushInterval has passed
+// since the last flush, so evaluations never wait on the store
+func (r *ruleStatsRecorder) flushIfDue() {
+	if time.Since(time.Unix(0, r.lastFlush.Load())) < statsFlushInterval {
+		return
+	}
+	if !r.flushing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer r.flushing.Store(false)
+		// A failed flush keeps its counts for the next one
+		_ = r.flush()
+	}()
+}
+
+// flush adds the counts recorded since the last flush to the store
+func (r *ruleStatsRecorder) flush() error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.lastFlush.Store(time.Now().UnixNano())
+
+	var pending []*RuleStats
+	r.counters.Range(func(key, value any) bool {
+		s := value.(*ruleCounters).load(key.(string), true)
+		if s.Evaluations > 0 || s.Skipped > 0 {
+			pending = append(pending, s)
+		}
+		return true
+	})
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err := r.store.Add(pending); err != nil {
+		for _, s := range pending {
+			r.restore(s)
+		}
+		return fmt.Errorf("failed to store rule stats: %w", err)
+	}
+	return nil
+}
+
+// snapshot returns the stored stats of every rule plus the counts recorded
+// since the last flush, ordered by rule name
+func (r *ruleStatsRecorder) snapshot() ([]*RuleStats, error) {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	stored, err := r.store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rule stats: %w", err)
+	}
+
+	byRule := make(map[string]*RuleStats, len(stored))
+	stats := make([]*RuleStats, 0, len(stored))
+	for _, s := range stored {
+		merged := *s
+		byRule[s.RuleID] = &merged
+		stats = append(stats, &merged)
+	}
+
+	r.counters.Range(func(key, value any) bool {
+		local := value.(*ruleCounters).load(key.(string), false)
+		if s, ok := byRule[local.RuleID]; ok {
+			// The recorder has the rule's latest revision
+			s.RuleName = local.RuleName
+			s.Shadow = local.Shadow
+			s.merge(local)
+			return true
+		}
+		stats = append(stats, local)
+		return true
+	})
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].RuleName != stats[j].RuleName {
+			return stats[i].RuleName < stats[j].RuleName
+		}
+		return stats[i].RuleID < stats[j].RuleID
+	})
+	return stats, nil
+}
+
+// RuleStats returns the evaluation stats of every rule EvaluateAll has run
+// They combine the stats in the engine's stats store, which outlive the
+// engine and add up across the engines sharing the store, with the counts
+// this engine has not flushed yet.
+func (en *Engine) RuleStats() ([]*RuleStats, error) {
+	return en.stats.snapshot()
+}
+
+// FlushStats writes the rule stats recorded since the last flush to the
+// engine's stats store
+// Evaluations flush in the background every few seconds; FlushStats is for
+// when the engine is about to stop, e.g. on shutdown.
+func (en *Engine) FlushStats() error {
+	return en.stats.flush()
+}
+
+// InheritStats makes the engine record rule stats together with previous,
+// the engine it replaces, so evaluations still running on previous and
+// counts it has not flushed are not lost
+// Both engines must use the same stats store. Call it before the engine
+// evaluates anything.
+func (en *Engine) InheritStats(previous *Engine) {
+	en.stats = previous.stats
+}
+
+// evaluateRecorded evaluates a rule through chain, when rules reference each
+// other, and records the result in the engine's stats
+func (en *Engine) evaluateRecorded(ctx context.Context, chain *ruleChain, rule *Rule, compiled *compiledRule, facts map[string]any, limits EvaluationLimits) *EvaluationResult {
+	start := time.Now()
+	var result *EvaluationResult
+	if chain != nil {
+		result = chain.evaluate(rule, compiled)
+	} else {
+		result = evaluateCompiled(ctx, rule, compiled, facts, limits)
+	}
+	en.stats.record(rule, result, time.Since(start))
+	return result
+}
+
+// evaluateShadow evaluates shadow rules in the background for their stats
+// only, so they add no latency to the request that triggered them
+// They run with the engine's request budget, detached from the request's
+// context, against the facts the live rules saw. When maxShadowEvaluations
+// requests' shadow rules are already running the rules are skipped, and
+// counted as skipped in their stats.
+func (en *Engine) evaluateShadow(ctx context.Context, snap *ruleSnapshot, now time.Time, rules []*Rule, facts map[string]any) {
+	select {
+	case en.shadowSlots <- struct{}{}:
+	default:
+		for _, rule := range rules {
+			en.stats.skip(rule)
+		}
+		return
+	}
+
+	en.shadowRunning.Add(1)
+	go func() {
+		defer en.shadowRunning.Done()
+		defer func() { <-en.shadowSlots }()
+
+		ctx, cancel := snap.limits.requestContext(context.WithoutCancel(ctx))
+		defer cancel()
+
+		chain := snap.newRuleChain(ctx, facts, now)
+		evaluateRulesUnreported(snap, rules, func(rule *Rule, compiled *compiledRule) *EvaluationResult {
+			return en.evaluateRecorded(ctx, chain, rule, compiled, facts, snap.limits)
+		})
+	}()
+}
+
+// splitShadowRules separates shadow rules from live rules, preserving order
+func splitShadowRules(rules []*Rule) (live, shadow []*Rule) {
+	for _, rule := range rules {
+		if rule.Shadow {
+			shadow = append(shadow, rule)
+			continue
+		}
+		live = append(live, rule)
+	}
+	if shadow == nil {
+		return rules, nil
+	}
+	return live, shadow
+}
@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"sort"
+	"sync"
+)
+
+// RuleStatsStore persists rule evaluation stats, so they outlive engine
+// rebuilds and restarts and add up across the engines sharing the store
+type RuleStatsStore interface {
+	// Add adds evaluation counts to the stored stats of each rule
+	Add(stats []*RuleStats) error
+
+	// List returns the stored stats of every rule
+	List() ([]*RuleStats, error)
+
+	// Delete removes the stats of a rule
+	Delete(ruleID string) error
+}
+
+// InMemoryRuleStatsStore implements RuleStatsStore using an in-memory map
+type InMemoryRuleStatsStore struct {
+	stats map[string]*RuleStats
+	mu    sync.RWMutex
+}
+
+// NewInMemoryRuleStatsStore creates a new in-memory rule stats store
+func NewInMemoryRuleStatsStore() *InMemoryRuleStatsStore {
+	return &InMemoryRuleStatsStore{
+		stats: make(map[string]*RuleStats),
+	}
+}
+
+// Add adds evaluation counts to the stored stats of each rule
+func (s *InMemoryRuleStatsStore) Add(stats []*RuleStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, added := range stats {
+		stored, exists := s.stats[added.RuleID]
+		if !exists {
+			stored = &RuleStats{RuleID: added.RuleID}
+			s.stats[added.RuleID] = stored
+		}
+		stored.RuleName = added.RuleName
+		stored.Shadow = added.Shadow
+		stored.merge(added)
+	}
+	return nil
+}
+
+// List returns the stored stats of every rule ordered by rule ID
+func (s *InMemoryRuleStatsStore) List() ([]*RuleStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make([]*RuleStats, 0, len(s.stats))
+	for _, stored := range s.stats {
+		copied := *stored
+		stats = append(stats, &copied)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].RuleID < stats[j].RuleID
+	})
+	return stats, nil
+}
+
+// Delete removes the stats of a rule; rules without stats are ignored
+func (s *InMemoryRuleStatsStore) Delete(ruleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.stats, ruleID)
+	return nil
+}
@@ -0,0 +1,240 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ruleStatsByID returns the engine's rule stats keyed by rule ID
+func ruleStatsByID(t *testing.T, engine *Engine) map[string]*RuleStats {
+	t.Helper()
+
+	all, err := engine.RuleStats()
+	if err != nil {
+		t.Fatalf("RuleStats() failed: %v", err)
+	}
+	stats := make(map[string]*RuleStats, len(all))
+	for _, s := range all {
+		stats[s.RuleID] = s
+	}
+	return stats
+}
+
+// TestShadowRules verifies shadow rules are evaluated and recorded in the
+// stats without appearing in results
+func TestShadowRules(t *testing.T) {
+	for _, concurrency := range []int{0, 4} {
+		engine, _ := NewEngine(NewInMemoryRuleStore())
+		if err := engine.SetConcurrency(concurrency); err != nil {
+			t.Fatalf("SetConcurrency() failed: %v", err)
+		}
+
+		rules := []*Rule{
+			{ID: "live", Name: "liveFraud", Expression: `Transaction.Amount > 1000.0`, Active: true, Priority: 10},
+			{ID: "shadow", Name: "newFraud", Expression: `Transaction.Amount > 500.0`, Active: true, Shadow: true, Priority: 20},
+			{ID: "other", Name: "other", Expression: `true`, Active: true},
+		}
+		for _, rule := range rules {
+			if err := engine.AddRule(rule); err != nil {
+				t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+			}
+		}
+
+		for _, amount := range []float64{2000.0, 700.0, 100.0} {
+			facts := map[string]any{"Transaction": map[string]any{"Amount": amount}}
+			results, err := engine.EvaluateAllWithOptions(facts, EvaluateOptions{Mode: EvaluationModeFirstMatch})
+			if err != nil {
+				t.Fatalf("EvaluateAllWithOptions() failed: %v", err)
+			}
+			for _, result := range results {
+				if result.RuleID == "shadow" {
+					t.Errorf("concurrency %d: shadow rule reported in results", concurrency)
+				}
+			}
+		}
+
+		engine.shadowRunning.Wait()
+		stats := ruleStatsByID(t, engine)
+
+		// The shadow rule runs on every request, even after first-match stops
+		shadow := stats["shadow"]
+		if shadow == nil || !shadow.Shadow || shadow.Evaluations != 3 || shadow.Matches != 2 || shadow.Errors != 0 {
+			t.Errorf("concurrency %d: shadow stats = %+v, want 3 evaluations, 2 matches", concurrency, shadow)
+		}
+		live := stats["live"]
+		if live == nil || live.Shadow || live.Evaluations != 3 || live.Matches != 1 {
+			t.Errorf("concurrency %d: live stats = %+v, want 3 evaluations, 1 match", concurrency, live)
+		}
+		if shadow != nil && shadow.MaxLatency < shadow.AverageLatency {
+			t.Errorf("concurrency %d: max latency %v below average %v", concurrency, shadow.MaxLatency, shadow.AverageLatency)
+		}
+
+		if err := engine.DeleteRule("shadow"); err != nil {
+			t.Fatalf("DeleteRule() failed: %v", err)
+		}
+		if _, ok := ruleStatsByID(t, engine)["shadow"]; ok {
+			t.Errorf("concurrency %d: stats kept for deleted rule", concurrency)
+		}
+	}
+}
+
+// TestShadowRulesErrors verifies shadow rule errors are counted
+func TestShadowRulesErrors(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.AddRule(&Rule{ID: "shadow", Name: "broken", Expression: `User.Missing > 1`, Active: true, Shadow: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	results, err := engine.EvaluateAll(map[string]any{"User": map[string]any{}})
+	if err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("EvaluateAll() returned %d results, want 0", len(results))
+	}
+
+	engine.shadowRunning.Wait()
+	stats, err := engine.RuleStats()
+	if err != nil {
+		t.Fatalf("RuleStats() failed: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Errors != 1 {
+		t.Errorf("RuleStats() = %+v, want 1 error", stats)
+	}
+}
+
+// TestShadowRulesNotReferenceable verifies live rules cannot depend on shadow rules
+func TestShadowRulesNotReferenceable(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.AddRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age >= 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	if err := engine.AddRule(&Rule{ID: "check", Name: "Check", Expression: `rules.isAdult`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	if err := engine.UpdateRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age >= 18`, Active: true, Shadow: true}); err == nil {
+		t.Error("UpdateRule() should reject shadowing a rule other rules reference")
+	}
+	if err := engine.AddRule(&Rule{ID: "s", Name: "s", Expression: `rules.Check`, Active: true, Shadow: true}); err != nil {
+		t.Errorf("AddRule() failed for a shadow rule referencing a live rule: %v", err)
+	}
+
+	results, err := engine.EvaluatePartial(map[string]any{})
+	if err != nil {
+		t.Fatalf("EvaluatePartial() failed: %v", err)
+	}
+	for _, result := range results {
+		if result.RuleID == "s" {
+			t.Error("EvaluatePartial() reported a shadow rule")
+		}
+	}
+}
+
+// TestShadowRulesInBackground verifies shadow rules do not hold up the
+// request, and are skipped and counted once too many are running
+func TestShadowRulesInBackground(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.AddRule(&Rule{ID: "shadow", Name: "slow", Expression: `true`, Active: true, Shadow: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	// Fill every slot, as if shadow rules were still running for other requests
+	for i := 0; i < cap(engine.shadowSlots); i++ {
+		engine.shadowSlots <- struct{}{}
+	}
+	if _, err := engine.EvaluateAll(map[string]any{}); err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+	stats := ruleStatsByID(t, engine)["shadow"]
+	if stats == nil || stats.Skipped != 1 || stats.Evaluations != 0 {
+		t.Errorf("shadow stats = %+v, want 1 skipped", stats)
+	}
+
+	for i := 0; i < cap(engine.shadowSlots); i++ {
+		<-engine.shadowSlots
+	}
+	if _, err := engine.EvaluateAll(map[string]any{}); err != nil {
+		t.Fatalf("EvaluateAll() failed: %v", err)
+	}
+	engine.shadowRunning.Wait()
+	stats = ruleStatsByID(t, engine)["shadow"]
+	if stats == nil || stats.Skipped != 1 || stats.Evaluations != 1 {
+		t.Errorf("shadow stats = %+v, want 1 skipped and 1 evaluation", stats)
+	}
+}
+
+// TestRuleStatsPersisted verifies stats are flushed to the stats store, add
+// up across engines sharing it, and carry over to an engine that inherits
+// them
+func TestRuleStatsPersisted(t *testing.T) {
+	env, err := cel.NewEnv()
+	if err != nil {
+		t.Fatalf("cel.NewEnv() failed: %v", err)
+	}
+	store := NewInMemoryRuleStore()
+	statsStore := NewInMemoryRuleStatsStore()
+	newEngine := func() *Engine {
+		engine, err := NewEngineWithConfig(EngineConfig{Env: env, Store: store, StatsStore: statsStore})
+		if err != nil {
+			t.Fatalf("NewEngineWithConfig() failed: %v", err)
+		}
+		return engine
+	}
+
+	first := newEngine()
+	if err := first.AddRule(&Rule{ID: "r1", Name: "r1", Expression: `true`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	evaluate := func(engine *Engine) {
+		t.Helper()
+		if _, err := engine.EvaluateAll(map[string]any{}); err != nil {
+			t.Fatalf("EvaluateAll() failed: %v", err)
+		}
+	}
+
+	// Unflushed counts are reported, and kept by an engine that inherits them
+	evaluate(first)
+	rebuilt := newEngine()
+	rebuilt.InheritStats(first)
+	evaluate(first)
+	evaluate(rebuilt)
+	if stats := ruleStatsByID(t, rebuilt)["r1"]; stats == nil || stats.Evaluations != 3 {
+		t.Errorf("inherited stats = %+v, want 3 evaluations", stats)
+	}
+
+	// Flushed counts add up with those of other engines over the same store
+	if err := rebuilt.FlushStats(); err != nil {
+		t.Fatalf("FlushStats() failed: %v", err)
+	}
+	other := newEngine()
+	evaluate(other)
+	if stats := ruleStatsByID(t, other)["r1"]; stats == nil || stats.Evaluations != 4 || stats.RuleName != "r1" {
+		t.Errorf("stats = %+v, want 4 evaluations", stats)
+	}
+	if err := other.FlushStats(); err != nil {
+		t.Fatalf("FlushStats() failed: %v", err)
+	}
+	if stats := ruleStatsByID(t, newEngine())["r1"]; stats == nil || stats.Evaluations != 4 {
+		t.Errorf("stats after restart = %+v, want 4 evaluations", stats)
+	}
+}
+
+// TestRuleStatsRestore verifies counts that failed to flush are restored with
+// the time the rule was last evaluated
+func TestRuleStatsRestore(t *testing.T) {
+	recorder := newRuleStatsRecorder(NewInMemoryRuleStatsStore())
+	last := time.Date(2024, 11, 29, 12, 0, 0, 0, time.UTC)
+	recorder.restore(&RuleStats{RuleID: "r1", RuleName: "r1", Evaluations: 2, LastEvaluated: last})
+	recorder.restore(&RuleStats{RuleID: "r1", RuleName: "r1", Evaluations: 1, LastEvaluated: last.Add(-time.Hour)})
+
+	all, err := recorder.snapshot()
+	if err != nil {
+		t.Fatalf("snapshot() failed: %v", err)
+	}
+	if len(all) != 1 || all[0].Evaluations != 3 || !all[0].LastEvaluated.Equal(last) {
+		t.Errorf("snapshot() = %+v, want 3 evaluations last at %v", all, last)
+	}
+}
@@ -2,6 +2,8 @@ package rules
 
 import (
     "fmt"
+    "slices"
+    "sort"
     "sync"
     "time"
 )
@@ -18,25 +20,36 @@ type RuleStore interface {
     // List all active rules
     ListActive() ([]*Rule, error)
 
+    // List all rules, active or not, carrying the given tag
+    ListByTag(tag string) ([]*Rule, error)
+
     // Update an existing rule
     Update(rule *Rule) error
 
     // Delete a rule
     Delete(id string) error
+
+    // List all revisions of a rule, newest first
+    ListVersions(id string) ([]*RuleVersion, error)
+
+    // Get a single revision of a rule
+    GetVersion(id string, version int) (*RuleVersion, error)
 }
 
 // InMemoryRuleStore implements RuleStore using an in-memory map
 // Satisfies REQ-STORE-003: In-memory RuleStore implementation
 // Satisfies REQ-CONCUR-001: Thread-safe with RWMutex
 type InMemoryRuleStore struct {
-    rules map[string]*Rule
-    mu    sync.RWMutex
+    rules    map[string]*Rule
+    versions map[string][]*RuleVersion // ruleID -> revisions, oldest first
+    mu       sync.RWMutex
 }
 
 // NewInMemoryRuleStore creates a new in-memory rule store
 func NewInMemoryRuleStore() *InMemoryRuleStore {
     return &InMemoryRuleStore{
-        rules: make(map[string]*Rule),
+        rules:    make(map[string]*Rule),
+        versions: make(map[string][]*RuleVersion),
     }
 }
 
@@ -54,7 +67,9 @@ func (s *InMemoryRuleStore) Add(rule *Rule) error {
     now := time.Now()
     rule.CreatedAt = now
     rule.UpdatedAt = now
+    rule.Version = 1
     s.rules[rule.ID] = rule
+    s.versions[rule.ID] = []*RuleVersion{newRuleVersion(rule)}
     return nil
 }
 
@@ -73,6 +88,7 @@ func (s *InMemoryRuleStore) Get(id string) (*Rule, error) {
 
 // ListActive returns all active rules
 // Satisfies REQ-STORE-007: Filters to return only active rules
+// Rules are ordered by priority (highest first), then by creation time
 func (s *InMemoryRuleStore) ListActive() ([]*Rule, error) {
     s.mu.RLock()
     defer s.mu.RUnlock()
@@ -83,9 +99,40 @@ func (s *InMemoryRuleStore) ListActive() ([]*Rule, error) {
             active = append(active, rule)
         }
     }
+    sortByPriority(active)
     return active, nil
 }
 
+// ListByTag returns all rules carrying the given tag, active or not
+// Rules are ordered by priority (highest first), then by creation time
+func (s *InMemoryRuleStore) ListByTag(tag string) ([]*Rule, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var tagged []*Rule
+    for _, rule := range s.rules {
+        if slices.Contains(rule.Tags, tag) {
+            tagged = append(tagged, rule)
+        }
+    }
+    sortByPriority(tagged)
+    return tagged, nil
+}
+
+// sortByPriority orders rules by priority descending, then creation time and ID ascending
+// This matches the ordering of PostgresRuleStore.ListActive and ListByTag
+func sortByPriority(rules []*Rule) {
+    sort.Slice(rules, func(i, j int) bool {
+        if rules[i].Priority != rules[j].Priority {
+            return rules[i].Priority > rules[j].Priority
+        }
+        if !rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+            return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+        }
+        return rules[i].ID < rules[j].ID
+    })
+}
+
 // Update updates an existing rule
 // Satisfies REQ-STORE-006: Updates UpdatedAt timestamp, preserves CreatedAt
 func (s *InMemoryRuleStore) Update(rule *Rule) error {
@@ -100,7 +147,9 @@ func (s *InMemoryRuleStore) Update(rule *Rule) error {
     // Preserve original CreatedAt timestamp
     rule.CreatedAt = existing.CreatedAt
     rule.UpdatedAt = time.Now()
+    rule.Version = existing.Version + 1
     s.rules[rule.ID] = rule
+    s.versions[rule.ID] = append(s.versions[rule.ID], newRuleVersion(rule))
     return nil
 }
 
@@ -114,6 +163,44 @@ func (s *InMemoryRuleStore) Delete(id string) error {
     }
 
     delete(s.rules, id)
+    delete(s.versions, id)
     return nil
 }
 
+// ListVersions returns all revisions of a rule, newest first
+func (s *InMemoryRuleStore) ListVersions(id string) ([]*RuleVersion, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    history, exists := s.versions[id]
+    if !exists {
+        return nil, fmt.Errorf("rule with ID %s not found", id)
+    }
+
+    versions := make([]*RuleVersion, 0, len(history))
+    for i := len(history) - 1; i >= 0; i-- {
+        v := *history[i]
+        versions = append(versions, &v)
+    }
+    return versions, nil
+}
+
+// GetVersion retrieves a single revision of a rule
+func (s *InMemoryRuleStore) GetVersion(id string, version int) (*RuleVersion, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    history, exists := s.versions[id]
+    if !exists {
+        return nil, fmt.Errorf("rule with ID %s not found", id)
+    }
+
+    for _, v := range history {
+        if v.Version == version {
+            snapshot := *v
+            return &snapshot, nil
+        }
+    }
+    return nil, fmt.Errorf("version %d of rule %s not found", version, id)
+}
+
@@ -0,0 +1,78 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+)
+
+// taggedRules returns rules spread across tags and rule sets
+func taggedRules() []*Rule {
+	return []*Rule{
+		{ID: "kyc-age", Name: "KYC Age", Expression: `User.Age >= 18`, Active: true, Priority: 30,
+			Tags: []string{"kyc"}, RuleSets: []string{"onboarding"}},
+		{ID: "kyc-checkout", Name: "KYC Checkout", Expression: `User.Age >= 21`, Active: true, Priority: 20,
+			Tags: []string{"kyc", "fraud"}, RuleSets: []string{"checkout", "onboarding"}},
+		{ID: "fraud-amount", Name: "Fraud Amount", Expression: `Transaction.Amount > 1000`, Active: true, Priority: 10,
+			Tags: []string{"fraud"}, RuleSets: []string{"checkout"}},
+		{ID: "untagged", Name: "Untagged", Expression: `true`, Active: true},
+		{ID: "inactive-kyc", Name: "Inactive KYC", Expression: `true`, Active: false, Tags: []string{"kyc"}},
+	}
+}
+
+// TestEvaluateAllSelectors verifies tag and rule set selectors restrict which rules run
+func TestEvaluateAllSelectors(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, taggedRules()...)
+	facts := map[string]any{
+		"User":        map[string]any{"Age": 30},
+		"Transaction": map[string]any{"Amount": 5000},
+	}
+
+	testCases := []struct {
+		name string
+		opts EvaluateOptions
+		want []string
+	}{
+		{"No selectors", EvaluateOptions{}, []string{"kyc-age", "kyc-checkout", "fraud-amount", "untagged"}},
+		{"Single tag", EvaluateOptions{Tags: []string{"kyc"}}, []string{"kyc-age", "kyc-checkout"}},
+		{"Any of tags", EvaluateOptions{Tags: []string{"kyc", "fraud"}}, []string{"kyc-age", "kyc-checkout", "fraud-amount"}},
+		{"Rule set", EvaluateOptions{RuleSet: "checkout"}, []string{"kyc-checkout", "fraud-amount"}},
+		{"Tag and rule set", EvaluateOptions{Tags: []string{"kyc"}, RuleSet: "checkout"}, []string{"kyc-checkout"}},
+		{"Unknown tag", EvaluateOptions{Tags: []string{"none"}}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := engine.EvaluateAllWithOptions(facts, tc.opts)
+			if err != nil {
+				t.Fatalf("EvaluateAllWithOptions() failed: %v", err)
+			}
+
+			var got []string
+			for _, result := range results {
+				got = append(got, result.RuleID)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("evaluated %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestInMemoryRuleStoreListByTag verifies rules are listed by tag, including inactive rules
+func TestInMemoryRuleStoreListByTag(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{}, taggedRules()...)
+
+	rules, err := engine.store.ListByTag("kyc")
+	if err != nil {
+		t.Fatalf("ListByTag() failed: %v", err)
+	}
+
+	var got []string
+	for _, rule := range rules {
+		got = append(got, rule.ID)
+	}
+	want := []string{"kyc-age", "kyc-checkout", "inactive-kyc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListByTag() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RuleTestStore manages rule test case persistence and retrieval
+type RuleTestStore interface {
+	// Add a new test case
+	Add(tc *RuleTestCase) error
+
+	// Get a test case by ID
+	Get(id string) (*RuleTestCase, error)
+
+	// ListByRule returns the test cases of one rule
+	ListByRule(ruleID string) ([]*RuleTestCase, error)
+
+	// List returns every test case
+	List() ([]*RuleTestCase, error)
+
+	// Update an existing test case
+	Update(tc *RuleTestCase) error
+
+	// Delete a test case
+	Delete(id string) error
+
+	// DeleteByRule removes every test case of a rule
+	DeleteByRule(ruleID string) error
+}
+
+// InMemoryRuleTestStore implements RuleTestStore using an in-memory map
+type InMemoryRuleTestStore struct {
+	cases map[string]*RuleTestCase
+	mu    sync.RWMutex
+}
+
+// NewInMemoryRuleTestStore creates a new in-memory rule test store
+func NewInMemoryRuleTestStore() *InMemoryRuleTestStore {
+	return &InMemoryRuleTestStore{
+		cases: make(map[string]*RuleTestCase),
+	}
+}
+
+// Add adds a new test case to the store
+func (s *InMemoryRuleTestStore) Add(tc *RuleTestCase) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.cases[tc.ID]; exists {
+		return fmt.Errorf("test case %s already exists", tc.ID)
+	}
+
+	tc.CreatedAt = time.Now()
+	tc.UpdatedAt = tc.CreatedAt
+	s.cases[tc.ID] = tc
+	return nil
+}
+
+// Get retrieves a test case by ID
+func (s *InMemoryRuleTestStore) Get(id string) (*RuleTestCase, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tc, exists := s.cases[id]
+	if !exists {
+		return nil, fmt.Errorf("test case %s not found", id)
+	}
+	return tc, nil
+}
+
+// ListByRule returns a rule's test cases ordered by creation time
+func (s *InMemoryRuleTestStore) ListByRule(ruleID string) ([]*RuleTestCase, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var cases []*RuleTestCase
+	for _, tc := range s.cases {
+		if tc.RuleID == ruleID {
+			cases = append(cases, tc)
+		}
+	}
+	sortTestCases(cases)
+	return cases, nil
+}
+
+// List returns all test cases ordered by rule, then creation time
+func (s *InMemoryRuleTestStore) List() ([]*RuleTestCase, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cases := make([]*RuleTestCase, 0, len(s.cases))
+	for _, tc := range s.cases {
+		cases = append(cases, tc)
+	}
+	sortTestCases(cases)
+	return cases, nil
+}
+
+// Update updates an existing test case, preserving CreatedAt
+func (s *InMemoryRuleTestStore) Update(tc *RuleTestCase) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.cases[tc.ID]
+	if !exists {
+		return fmt.Errorf("test case %s not found", tc.ID)
+	}
+
+	tc.CreatedAt = existing.CreatedAt
+	tc.UpdatedAt = time.Now()
+	s.cases[tc.ID] = tc
+	return nil
+}
+
+// Delete removes a test case from the store
+func (s *InMemoryRuleTestStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.cases[id]; !exists {
+		return fmt.Errorf("test case %s not found", id)
+	}
+
+	delete(s.cases, id)
+	return nil
+}
+
+// DeleteByRule removes every test case of a rule
+func (s *InMemoryRuleTestStore) DeleteByRule(ruleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, tc := range s.cases {
+		if tc.RuleID == ruleID {
+			delete(s.cases, id)
+		}
+	}
+	return nil
+}
+
+// sortTestCases orders test cases by rule, then creation time, then ID
+func sortTestCases(cases []*RuleTestCase) {
+	sort.Slice(cases, func(i, j int) bool {
+		if cases[i].RuleID != cases[j].RuleID {
+			return cases[i].RuleID < cases[j].RuleID
+		}
+		if !cases[i].CreatedAt.Equal(cases[j].CreatedAt) {
+			return cases[i].CreatedAt.Before(cases[j].CreatedAt)
+		}
+		return cases[i].ID < cases[j].ID
+	})
+}
@@ -0,0 +1,341 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// TestCaseResult is the outcome of running one rule test case
+type TestCaseResult struct {
+	TestCaseID string
+	RuleID     string
+	Name       string
+	Passed     bool
+	Matched    bool
+	Output     any
+	Error      string // evaluation error, if the rule failed to evaluate
+	Failure    string // why the case failed, e.g. "expected Matched true, got false"
+}
+
+// TestSuiteResult summarizes a run of a tenant's rule test cases
+type TestSuiteResult struct {
+	Total   int
+	Passed  int
+	Failed  int
+	Results []*TestCaseResult
+}
+
+// TestFailureError is returned when a change fails rule test cases
+// Failures lists only the cases that failed
+type TestFailureError struct {
+	RuleID   string
+	Failures []*TestCaseResult
+}
+
+// Error summarizes the failed cases
+func (e *TestFailureError) Error() string {
+	parts := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		label := failure.Name
+		if failure.RuleID != e.RuleID {
+			label = fmt.Sprintf("%s (rule %s)", failure.Name, failure.RuleID)
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", label, failure.Failure))
+	}
+	return fmt.Sprintf("rule %s failed %d test case(s): %s", e.RuleID, len(e.Failures), strings.Join(parts, "; "))
+}
+
+// validateTestCase checks a test case is complete
+func validateTestCase(tc *RuleTestCase) error {
+	if tc.ID == "" {
+		return fmt.Errorf("test case ID is required")
+	}
+	if tc.RuleID == "" {
+		return fmt.Errorf("test case rule ID is required")
+	}
+	if tc.Name == "" {
+		return fmt.Errorf("test case name is required")
+	}
+	if tc.Facts == nil {
+		return fmt.Errorf("test case facts are required")
+	}
+	if tc.ExpectedMatched == nil && tc.ExpectedOutput == nil {
+		return fmt.Errorf("test case must set an expected matched value or output")
+	}
+	return nil
+}
+
+// testState is the compiled state test cases run against
+type testState struct {
+	env      *cel.Env // used to compile inactive rules, which have no cached program
+	refs     map[string]*Rule
+	programs map[string]*compiledRule
+	derived  []*compiledDerivedField
+	lists    map[string]ref.Val
+	limits   EvaluationLimits
+	options  EngineOptions
+	convert  FactConverter
+}
+
+// currentTestState snapshots the engine's compiled state
+func (en *Engine) currentTestState() *testState {
+	en.mu.RLock()
+	env := en.ruleEnv
+	en.mu.RUnlock()
+
+	snap := en.snapshot.Load()
+	programs, _ := snap.copyPrograms()
+	return &testState{
+		env:      env,
+		refs:     snap.refs,
+		programs: programs,
+		derived:  snap.derived,
+		lists:    snap.lists,
+		limits:   snap.limits,
+		options:  en.options,
+		convert:  en.convertFacts,
+	}
+}
+
+// withPlan overlays a planned rule change on the state
+func (s *testState) withPlan(plan *rulePlan) *testState {
+	for ruleID, compiled := range plan.programs {
+		s.programs[ruleID] = compiled
+	}
+	s.env = plan.env
+	s.refs = plan.refs
+	return s
+}
+
+// run evaluates a rule against one test case and compares the outcome
+func (s *testState) run(rule *Rule, tc *RuleTestCase) *TestCaseResult {
+	result := &TestCaseResult{TestCaseID: tc.ID, RuleID: rule.ID, Name: tc.Name}
+
+	compiled := s.programs[rule.ID]
+	if compiled == nil {
+		var err error
+		compiled, err = compileProgram(s.env, rule.Expression, rule.OutputType, s.options)
+		if err != nil {
+			result.Error = err.Error()
+			result.Failure = "rule does not compile: " + err.Error()
+			return result
+		}
+	}
+
+	ctx, cancel := s.limits.requestContext(context.Background())
+	defer cancel()
+
+	facts, err := convertFacts(s.convert, tc.Facts)
+	if err != nil {
+		result.Error = err.Error()
+		result.Failure = "evaluation failed: " + err.Error()
+		return result
+	}
+	facts = computeDerivedFields(ctx, s.derived, bindLists(s.lists, facts))
+	var evaluated *EvaluationResult
+	if chain := buildRuleChain(ctx, facts, s.limits, s.refs, s.programs, nil); chain != nil {
+		evaluated = chain.evaluate(rule, compiled)
+	} else {
+		evaluated = evaluateCompiled(ctx, rule, compiled, facts, s.limits)
+	}
+
+	result.Matched = evaluated.Matched
+	result.Output = evaluated.Output
+	switch {
+	case evaluated.Error != nil:
+		result.Error = evaluated.Error.Error()
+		result.Failure = "evaluation failed: " + evaluated.Error.Error()
+	case tc.ExpectedMatched != nil && *tc.ExpectedMatched != evaluated.Matched:
+		result.Failure = fmt.Sprintf("expected Matched %v, got %v", *tc.ExpectedMatched, evaluated.Matched)
+	case tc.ExpectedOutput != nil && !jsonEqual(tc.ExpectedOutput, evaluated.Output):
+		result.Failure = fmt.Sprintf("expected output %s, got %s", jsonString(tc.ExpectedOutput), jsonString(evaluated.Output))
+	default:
+		result.Passed = true
+	}
+
+	return result
+}
+
+// dependents returns the active rules that reference rule, directly or
+// through other rules
+func (s *testState) dependents(rule *Rule) []*Rule {
+	referencedBy := make(map[string][]*Rule)
+	for _, ref := range s.refs {
+		compiled := s.programs[ref.ID]
+		if compiled == nil {
+			continue
+		}
+		for _, dep := range compiled.dependencies {
+			referencedBy[dep] = append(referencedBy[dep], ref)
+		}
+	}
+
+	if ref, ok := s.refs[rule.Name]; !ok || ref.ID != rule.ID {
+		return nil
+	}
+
+	var dependents []*Rule
+	seen := map[string]bool{rule.ID: true}
+	queue := []string{rule.Name}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, dependent := range referencedBy[name] {
+			if seen[dependent.ID] {
+				continue
+			}
+			seen[dependent.ID] = true
+			dependents = append(dependents, dependent)
+			queue = append(queue, dependent.Name)
+		}
+	}
+	return dependents
+}
+
+// checkTests runs the test cases of a changed rule, and of the rules that
+// depend on it, against a planned change
+// Callers must hold writeMu
+func (en *Engine) checkTests(plan *rulePlan, changed *Rule) error {
+	state := en.currentTestState().withPlan(plan)
+
+	var failures []*TestCaseResult
+	for _, rule := range append([]*Rule{changed}, state.dependents(changed)...) {
+		cases, err := en.tests.ListByRule(rule.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load test cases for rule %s: %w", rule.ID, err)
+		}
+		for _, tc := range cases {
+			if result := state.run(rule, tc); !result.Passed {
+				failures = append(failures, result)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return &TestFailureError{RuleID: changed.ID, Failures: failures}
+	}
+	return nil
+}
+
+// TestCases returns a rule's test cases
+func (en *Engine) TestCases(ruleID string) ([]*RuleTestCase, error) {
+	return en.tests.ListByRule(ruleID)
+}
+
+// AddTestCase stores a new test case for a rule
+// The case must pass against the current rule, so the stored suite always passes
+func (en *Engine) AddTestCase(tc *RuleTestCase) error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	if err := en.verifyTestCase(tc); err != nil {
+		return err
+	}
+	return en.tests.Add(tc)
+}
+
+// UpdateTestCase replaces an existing test case
+// The updated case must pass against the current rule
+func (en *Engine) UpdateTestCase(tc *RuleTestCase) error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	existing, err := en.tests.Get(tc.ID)
+	if err != nil {
+		return err
+	}
+	if existing.RuleID != tc.RuleID {
+		return fmt.Errorf("test case %s does not belong to rule %s", tc.ID, tc.RuleID)
+	}
+
+	if err := en.verifyTestCase(tc); err != nil {
+		return err
+	}
+	return en.tests.Update(tc)
+}
+
+// DeleteTestCase removes a test case
+func (en *Engine) DeleteTestCase(id string) error {
+	en = en.lockWrites()
+	defer en.writeMu.Unlock()
+
+	return en.tests.Delete(id)
+}
+
+// verifyTestCase validates a test case and runs it against its current rule
+func (en *Engine) verifyTestCase(tc *RuleTestCase) error {
+	if err := validateTestCase(tc); err != nil {
+		return err
+	}
+
+	rule, err := en.store.Get(tc.RuleID)
+	if err != nil {
+		return err
+	}
+
+	if result := en.currentTestState().run(rule, tc); !result.Passed {
+		return &TestFailureError{RuleID: rule.ID, Failures: []*TestCaseResult{result}}
+	}
+	return nil
+}
+
+// RunTests runs every stored test case against the current rules
+func (en *Engine) RunTests() (*TestSuiteResult, error) {
+	cases, err := en.tests.List()
+	if err != nil {
+		return nil, err
+	}
+
+	state := en.currentTestState()
+	suite := &TestSuiteResult{Results: make([]*TestCaseResult, 0, len(cases))}
+	rules := make(map[string]*Rule)
+	for _, tc := range cases {
+		rule, ok := rules[tc.RuleID]
+		if !ok {
+			rule, err = en.store.Get(tc.RuleID)
+			if err != nil {
+				return nil, err
+			}
+			rules[tc.RuleID] = rule
+		}
+
+		result := state.run(rule, tc)
+		suite.Results = append(suite.Results, result)
+		suite.Total++
+		if result.Passed {
+			suite.Passed++
+		} else {
+			suite.Failed++
+		}
+	}
+
+	return suite, nil
+}
+
+// jsonEqual compares two values by their JSON encodings, so numbers compare
+// equal whatever their Go type
+func jsonEqual(a, b any) bool {
+	var left, right any
+	if err := json.Unmarshal([]byte(jsonString(a)), &left); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(jsonString(b)), &right); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// jsonString renders a value as JSON for comparisons and failure messages
+func jsonString(v any) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,143 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+)
+
+// boolPtr returns a pointer to b, for test case expectations
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// TestRuleTestCasesGateUpdates verifies rule changes that break stored test
+// cases are rejected with a report of the failures
+func TestRuleTestCasesGateUpdates(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.AddRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age >= 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	cases := []*RuleTestCase{
+		{ID: "t1", RuleID: "adult", Name: "adult", Facts: map[string]any{"User": map[string]any{"Age": 30}}, ExpectedMatched: boolPtr(true)},
+		{ID: "t2", RuleID: "adult", Name: "minor", Facts: map[string]any{"User": map[string]any{"Age": 12}}, ExpectedMatched: boolPtr(false)},
+	}
+	for _, tc := range cases {
+		if err := engine.AddTestCase(tc); err != nil {
+			t.Fatalf("AddTestCase(%s) failed: %v", tc.ID, err)
+		}
+	}
+
+	// A case that does not pass against the current rule is not stored
+	err := engine.AddTestCase(&RuleTestCase{ID: "t3", RuleID: "adult", Name: "wrong",
+		Facts: map[string]any{"User": map[string]any{"Age": 5}}, ExpectedMatched: boolPtr(true)})
+	var failure *TestFailureError
+	if !errors.As(err, &failure) {
+		t.Fatalf("AddTestCase() error = %v, want TestFailureError", err)
+	}
+
+	err = engine.UpdateRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age >= 10`, Active: true})
+	if !errors.As(err, &failure) {
+		t.Fatalf("UpdateRule() error = %v, want TestFailureError", err)
+	}
+	if len(failure.Failures) != 1 || failure.Failures[0].TestCaseID != "t2" {
+		t.Errorf("UpdateRule() failures = %+v, want only t2", failure.Failures)
+	}
+
+	// The rejected update leaves the original rule in place
+	result, _ := engine.Evaluate("adult", map[string]any{"User": map[string]any{"Age": 12}})
+	if result.Matched {
+		t.Error("rejected update should not change the compiled rule")
+	}
+
+	if err := engine.UpdateRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age >= 16`, Active: true}); err != nil {
+		t.Errorf("UpdateRule() failed for a change passing its tests: %v", err)
+	}
+
+	suite, err := engine.RunTests()
+	if err != nil {
+		t.Fatalf("RunTests() failed: %v", err)
+	}
+	if suite.Total != 2 || suite.Passed != 2 || suite.Failed != 0 {
+		t.Errorf("RunTests() = %d total, %d passed, %d failed; want 2, 2, 0", suite.Total, suite.Passed, suite.Failed)
+	}
+
+	if err := engine.DeleteRule("adult"); err != nil {
+		t.Fatalf("DeleteRule() failed: %v", err)
+	}
+	if remaining, _ := engine.TestCases("adult"); len(remaining) != 0 {
+		t.Errorf("DeleteRule() left %d test cases, want 0", len(remaining))
+	}
+}
+
+// TestRuleTestCasesOutput verifies expected outputs compare by value
+func TestRuleTestCasesOutput(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.AddRule(&Rule{ID: "score", Name: "riskScore", Expression: `Transaction.Amount > 1000.0 ? 80 : 10`, Active: true, OutputType: "int"}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	// Outputs decoded from JSON are float64; they still equal the int output
+	facts := map[string]any{"Transaction": map[string]any{"Amount": 5000.0}}
+	if err := engine.AddTestCase(&RuleTestCase{ID: "t1", RuleID: "score", Name: "high", Facts: facts, ExpectedOutput: float64(80)}); err != nil {
+		t.Fatalf("AddTestCase() failed: %v", err)
+	}
+
+	err := engine.UpdateRule(&Rule{ID: "score", Name: "riskScore", Expression: `Transaction.Amount > 1000.0 ? 90 : 10`, Active: true, OutputType: "int"})
+	var failure *TestFailureError
+	if !errors.As(err, &failure) || failure.Failures[0].Failure != "expected output 80, got 90" {
+		t.Errorf("UpdateRule() error = %v, want output mismatch", err)
+	}
+}
+
+// TestRuleTestCasesDependents verifies a change to a referenced rule runs the
+// test cases of the rules depending on it
+func TestRuleTestCasesDependents(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	rules := []*Rule{
+		{ID: "adult", Name: "isAdult", Expression: `User.Age >= 18`, Active: true},
+		{ID: "check", Name: "Check", Expression: `rules.isAdult && User.Age < 65`, Active: true},
+	}
+	for _, rule := range rules {
+		if err := engine.AddRule(rule); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.ID, err)
+		}
+	}
+	if err := engine.AddTestCase(&RuleTestCase{ID: "t1", RuleID: "check", Name: "working age",
+		Facts: map[string]any{"User": map[string]any{"Age": 19}}, ExpectedMatched: boolPtr(true)}); err != nil {
+		t.Fatalf("AddTestCase() failed: %v", err)
+	}
+
+	err := engine.UpdateRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age >= 21`, Active: true})
+	var failure *TestFailureError
+	if !errors.As(err, &failure) || len(failure.Failures) != 1 || failure.Failures[0].RuleID != "check" {
+		t.Errorf("UpdateRule() error = %v, want failure of the dependent rule's test case", err)
+	}
+}
+
+// TestRuleTestCasesValidation verifies incomplete test cases are rejected
+func TestRuleTestCasesValidation(t *testing.T) {
+	engine, _ := NewEngine(NewInMemoryRuleStore())
+	if err := engine.AddRule(&Rule{ID: "adult", Name: "isAdult", Expression: `User.Age >= 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+
+	facts := map[string]any{"User": map[string]any{"Age": 30}}
+	testCases := []struct {
+		name string
+		tc   *RuleTestCase
+	}{
+		{"Missing name", &RuleTestCase{ID: "t", RuleID: "adult", Facts: facts, ExpectedMatched: boolPtr(true)}},
+		{"Missing facts", &RuleTestCase{ID: "t", RuleID: "adult", Name: "n", ExpectedMatched: boolPtr(true)}},
+		{"No expectation", &RuleTestCase{ID: "t", RuleID: "adult", Name: "n", Facts: facts}},
+		{"Unknown rule", &RuleTestCase{ID: "t", RuleID: "missing", Name: "n", Facts: facts, ExpectedMatched: boolPtr(true)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := engine.AddTestCase(tc.tc); err == nil {
+				t.Error("AddTestCase() should reject the test case")
+			}
+		})
+	}
+}
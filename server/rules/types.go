@@ -5,28 +5,103 @@ import "time"
 // Rule represents a single evaluation rule
 // Satisfies REQ-STORE-002: Rule SHALL contain all required fields
 type Rule struct {
-    ID         string
-    Name       string
-    Expression string
-    Active     bool
-    CreatedAt  time.Time
-    UpdatedAt  time.Time
+	ID         string
+	Name       string
+	Expression string
+	Active     bool
+	Shadow     bool     // evaluated by EvaluateAll for stats only; never reported or referenceable
+	Priority   int      // higher priorities are evaluated first
+	OutputType string   // optional declared output type, e.g. "bool", "map", "float64"
+	Tags       []string // labels used to select rules for evaluation, e.g. "kyc"
+	RuleSets   []string // named rule sets the rule belongs to, e.g. "checkout"
+	Version    int      // current revision number, incremented on every update
+
+	// ReferencedFields are the variable and field paths the expression reads,
+	// e.g. "User.Age", recorded by the engine when the rule is added or updated
+	ReferencedFields []string
+
+	// EffectiveFrom and EffectiveUntil bound when an active rule is evaluated
+	// by EvaluateAll; nil leaves that side of the window open
+	EffectiveFrom  *time.Time // inclusive
+	EffectiveUntil *time.Time // exclusive
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RuleVersion is an immutable snapshot of a rule at a given revision
+type RuleVersion struct {
+	RuleID         string
+	Version        int
+	Name           string
+	Expression     string
+	Active         bool
+	Shadow         bool
+	Priority       int
+	OutputType     string
+	Tags           []string
+	RuleSets       []string
+	EffectiveFrom  *time.Time
+	EffectiveUntil *time.Time
+	CreatedAt      time.Time
 }
 
 // EvaluationResult contains the outcome of evaluating a rule
 // Satisfies REQ-EVAL-004: EvaluationResult SHALL contain all required fields
 type EvaluationResult struct {
-    RuleID   string
-    RuleName string
-    Matched  bool
-    Error    error
-    Trace    any // CEL evaluation trace (optional)
+	RuleID   string
+	RuleName string
+	Matched  bool
+	Output   any // rule output converted to a JSON-compatible value
+	Error    error
+	TimedOut bool // true when the rule was cut off by a deadline or cancellation
+	Trace    any  // CEL evaluation trace (optional)
+
+	// Explanation breaks the result down by sub-expression; only set when
+	// requested with EvaluateOptions.Explain
+	Explanation *Explanation
 }
 
-// DerivedField represents a computed field (for future code generation)
+// RuleTestCase is a stored example of how a rule should evaluate
+// Test cases run whenever their rule changes, and a change that fails any of
+// them is rejected. At least one of ExpectedMatched and ExpectedOutput is set.
+type RuleTestCase struct {
+	ID              string
+	RuleID          string
+	Name            string
+	Facts           map[string]any
+	ExpectedMatched *bool // nil when only the output is checked
+	ExpectedOutput  any   // nil when only Matched is checked; compared as JSON
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// ReferenceList is a named list of values maintained by a tenant
+// Rules reference it as lists.<Name>, e.g. `Transaction.Country in lists.sanctionedCountries`.
+// Only the member field matching Kind is set.
+type ReferenceList struct {
+	Name      string
+	Kind      ListKind
+	Strings   []string       // members of a strings list
+	Numbers   []float64      // members of a numbers list
+	Ranges    []NumericRange // inclusive ranges of a ranges list
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NumericRange is an inclusive range of numbers
+type NumericRange struct {
+	Min float64
+	Max float64
+}
+
+// DerivedField represents a computed field
+// Derived fields are evaluated against the incoming facts before rules run and
+// are exposed to rule expressions as top-level variables named after the field
 // Satisfies REQ-DERIVED-002: DerivedField SHALL contain required fields
 type DerivedField struct {
-    Name       string
-    Expression string // CEL expression for computing the field
+	Name         string
+	Expression   string   // CEL expression for computing the field
+	Dependencies []string // other derived fields referenced by Expression
+	CreatedAt    time.Time
 }
-
@@ -0,0 +1,117 @@
+package rules
+
+import (
+	"fmt"
+	"slices"
+)
+
+// RuleVersionDiff describes the changes between two revisions of a rule
+type RuleVersionDiff struct {
+	RuleID      string
+	FromVersion int
+	ToVersion   int
+	Changes     []FieldChange // empty when both revisions are identical
+}
+
+// FieldChange describes a single rule field that differs between two revisions
+type FieldChange struct {
+	Field string
+	From  any
+	To    any
+}
+
+// newRuleVersion snapshots the current state of a rule as a revision
+func newRuleVersion(rule *Rule) *RuleVersion {
+	return &RuleVersion{
+		RuleID:         rule.ID,
+		Version:        rule.Version,
+		Name:           rule.Name,
+		Expression:     rule.Expression,
+		Active:         rule.Active,
+		Shadow:         rule.Shadow,
+		Priority:       rule.Priority,
+		OutputType:     rule.OutputType,
+		Tags:           slices.Clone(rule.Tags),
+		RuleSets:       slices.Clone(rule.RuleSets),
+		EffectiveFrom:  rule.EffectiveFrom,
+		EffectiveUntil: rule.EffectiveUntil,
+		CreatedAt:      rule.UpdatedAt,
+	}
+}
+
+// DiffRuleVersions compares two revisions of the same rule field by field
+func DiffRuleVersions(from, to *RuleVersion) (*RuleVersionDiff, error) {
+	if from.RuleID != to.RuleID {
+		return nil, fmt.Errorf("cannot diff versions of different rules %s and %s", from.RuleID, to.RuleID)
+	}
+
+	diff := &RuleVersionDiff{
+		RuleID:      from.RuleID,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Changes:     []FieldChange{},
+	}
+
+	if from.Name != to.Name {
+		diff.Changes = append(diff.Changes, FieldChange{Field: "Name", From: from.Name, To: to.Name})
+	}
+	if from.Expression != to.Expression {
+		diff.Changes = append(diff.Changes, FieldChange{Field: "Expression", From: from.Expression, To: to.Expression})
+	}
+	if from.Active != to.Active {
+		diff.Changes = append(diff.Changes, FieldChange{Field: "Active", From: from.Active, To: to.Active})
+	}
+	if from.Shadow != to.Shadow {
+		diff.Changes = append(diff.Changes, FieldChange{Field: "Shadow", From: from.Shadow, To: to.Shadow})
+	}
+	if from.Priority != to.Priority {
+		diff.Changes = append(diff.Changes, FieldChange{Field: "Priority", From: from.Priority, To: to.Priority})
+	}
+	if from.OutputType != to.OutputType {
+		diff.Changes = append(diff.Changes, FieldChange{Field: "OutputType", From: from.OutputType, To: to.OutputType})
+	}
+	if !slices.Equal(from.Tags, to.Tags) {
+		diff.Changes = append(diff.Changes, FieldChange{Field: "Tags", From: from.Tags, To: to.Tags})
+	}
+	if !slices.Equal(from.RuleSets, to.RuleSets) {
+		diff.Changes = append(diff.Changes, FieldChange{Field: "RuleSets", From: from.RuleSets, To: to.RuleSets})
+	}
+	if !equalTimes(from.EffectiveFrom, to.EffectiveFrom) {
+		diff.Changes = append(diff.Changes, FieldChange{Field: "EffectiveFrom", From: from.EffectiveFrom, To: to.EffectiveFrom})
+	}
+	if !equalTimes(from.EffectiveUntil, to.EffectiveUntil) {
+		diff.Changes = append(diff.Changes, FieldChange{Field: "EffectiveUntil", From: from.EffectiveUntil, To: to.EffectiveUntil})
+	}
+
+	return diff, nil
+}
+
+// RollbackRule restores a rule to the content of an earlier revision
+// The restored content is recompiled through UpdateRule and recorded as a new
+// revision, so history is never rewritten
+func (en *Engine) RollbackRule(ruleID string, version int) (*Rule, error) {
+	target, err := en.store.GetVersion(ruleID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &Rule{
+		ID:             ruleID,
+		Name:           target.Name,
+		Expression:     target.Expression,
+		Active:         target.Active,
+		Shadow:         target.Shadow,
+		Priority:       target.Priority,
+		OutputType:     target.OutputType,
+		Tags:           target.Tags,
+		RuleSets:       target.RuleSets,
+		EffectiveFrom:  target.EffectiveFrom,
+		EffectiveUntil: target.EffectiveUntil,
+	}
+
+	if err := en.UpdateRule(rule); err != nil {
+		return nil, fmt.Errorf("rollback to version %d failed: %w", version, err)
+	}
+
+	return rule, nil
+}
@@ -0,0 +1,144 @@
+package rules
+
+import "testing"
+
+// TestInMemoryRuleStoreVersions verifies every add and update is recorded as a revision
+func TestInMemoryRuleStoreVersions(t *testing.T) {
+	store := NewInMemoryRuleStore()
+
+	rule := &Rule{ID: "rule-1", Name: "Adult", Expression: `User.Age >= 18`, Active: true}
+	if err := store.Add(rule); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if rule.Version != 1 {
+		t.Errorf("Rule.Version after Add() = %d, want 1", rule.Version)
+	}
+
+	updated := &Rule{ID: "rule-1", Name: "Adult", Expression: `User.Age >= 21`, Active: true}
+	if err := store.Update(updated); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("Rule.Version after Update() = %d, want 2", updated.Version)
+	}
+
+	versions, err := store.ListVersions("rule-1")
+	if err != nil {
+		t.Fatalf("ListVersions() failed: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("ListVersions() returned %d versions, want 2", len(versions))
+	}
+	if versions[0].Version != 2 || versions[1].Version != 1 {
+		t.Errorf("ListVersions() should be newest first, got versions %d, %d", versions[0].Version, versions[1].Version)
+	}
+
+	v1, err := store.GetVersion("rule-1", 1)
+	if err != nil {
+		t.Fatalf("GetVersion() failed: %v", err)
+	}
+	if v1.Expression != `User.Age >= 18` {
+		t.Errorf("Version 1 expression = %q, want original expression", v1.Expression)
+	}
+
+	if _, err := store.GetVersion("rule-1", 3); err == nil {
+		t.Error("GetVersion() should return error for non-existent version")
+	}
+	if _, err := store.ListVersions("missing"); err == nil {
+		t.Error("ListVersions() should return error for non-existent rule")
+	}
+
+	if err := store.Delete("rule-1"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := store.ListVersions("rule-1"); err == nil {
+		t.Error("ListVersions() should return error after rule is deleted")
+	}
+}
+
+// TestDiffRuleVersions verifies field-level differences between revisions
+func TestDiffRuleVersions(t *testing.T) {
+	from := &RuleVersion{RuleID: "rule-1", Version: 1, Name: "Adult", Expression: `User.Age >= 18`, Active: true}
+	to := &RuleVersion{RuleID: "rule-1", Version: 2, Name: "Adult", Expression: `User.Age >= 21`, Active: false}
+
+	diff, err := DiffRuleVersions(from, to)
+	if err != nil {
+		t.Fatalf("DiffRuleVersions() failed: %v", err)
+	}
+
+	if diff.FromVersion != 1 || diff.ToVersion != 2 {
+		t.Errorf("Diff versions = %d..%d, want 1..2", diff.FromVersion, diff.ToVersion)
+	}
+	if len(diff.Changes) != 2 {
+		t.Fatalf("Diff has %d changes, want 2", len(diff.Changes))
+	}
+	if diff.Changes[0].Field != "Expression" || diff.Changes[0].To != `User.Age >= 21` {
+		t.Errorf("Unexpected expression change: %+v", diff.Changes[0])
+	}
+	if diff.Changes[1].Field != "Active" || diff.Changes[1].To != false {
+		t.Errorf("Unexpected active change: %+v", diff.Changes[1])
+	}
+
+	same, err := DiffRuleVersions(from, from)
+	if err != nil {
+		t.Fatalf("DiffRuleVersions() failed: %v", err)
+	}
+	if len(same.Changes) != 0 {
+		t.Errorf("Diff of identical versions has %d changes, want 0", len(same.Changes))
+	}
+
+	other := &RuleVersion{RuleID: "rule-2", Version: 1}
+	if _, err := DiffRuleVersions(from, other); err == nil {
+		t.Error("DiffRuleVersions() should reject versions of different rules")
+	}
+}
+
+// TestEngineRollbackRule verifies rollback recompiles the old expression and records a new revision
+func TestEngineRollbackRule(t *testing.T) {
+	store := NewInMemoryRuleStore()
+	engine, _ := NewEngine(store)
+
+	if err := engine.AddRule(&Rule{ID: "rule-1", Name: "Adult", Expression: `User.Age >= 18`, Active: true}); err != nil {
+		t.Fatalf("AddRule() failed: %v", err)
+	}
+	if err := engine.UpdateRule(&Rule{ID: "rule-1", Name: "Adult", Expression: `User.Age >= 21`, Active: true}); err != nil {
+		t.Fatalf("UpdateRule() failed: %v", err)
+	}
+
+	facts := map[string]any{
+		"User": map[string]any{"Age": 19},
+	}
+
+	result, err := engine.Evaluate("rule-1", facts)
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if result.Matched {
+		t.Fatal("Expected updated rule not to match age 19")
+	}
+
+	rule, err := engine.RollbackRule("rule-1", 1)
+	if err != nil {
+		t.Fatalf("RollbackRule() failed: %v", err)
+	}
+	if rule.Version != 3 {
+		t.Errorf("Rolled back rule version = %d, want 3", rule.Version)
+	}
+
+	result, err = engine.Evaluate("rule-1", facts)
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if !result.Matched {
+		t.Error("Expected rolled back rule to match age 19")
+	}
+
+	versions, _ := store.ListVersions("rule-1")
+	if len(versions) != 3 {
+		t.Errorf("Expected 3 revisions after rollback, got %d", len(versions))
+	}
+
+	if _, err := engine.RollbackRule("rule-1", 10); err == nil {
+		t.Error("RollbackRule() should return error for non-existent version")
+	}
+}